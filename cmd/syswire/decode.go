@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// detectOrder lists the order auto-detection tries the built-in payload
+// kinds.  Types with fixed-size headers come first since they are the least
+// likely to match by accident.  Any other registered kinds are tried last.
+var detectOrder = []wire.PayloadKind{
+	wire.KindNEVMBlock,
+	wire.KindMint,
+	wire.KindNEVMDisconnect,
+	wire.KindBurnToEthereum,
+	wire.KindAllocation,
+	wire.KindAsset,
+}
+
+// detectKinds returns every registered kind in auto-detection order.
+func detectKinds() []wire.PayloadKind {
+	kinds := append([]wire.PayloadKind(nil), detectOrder...)
+	for _, kind := range wire.PayloadKinds() {
+		known := false
+		for _, k := range detectOrder {
+			if k == kind {
+				known = true
+				break
+			}
+		}
+		if !known {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+func lookupKind(name string) (wire.Payload, error) {
+	p, err := wire.NewPayload(wire.PayloadKind(name))
+	if err != nil {
+		kinds := wire.PayloadKinds()
+		names := make([]string, len(kinds))
+		for i, k := range kinds {
+			names[i] = string(k)
+		}
+		return nil, fmt.Errorf("unknown type %q (want one of %s)", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+func runDecode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	kind := fs.String("type", "", "payload type; auto-detected when empty")
+	format := fs.String("format", "auto", "input encoding: auto, hex, base64 or raw")
+	reencode := fs.Bool("reencode", false, "check that re-serializing reproduces the input")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args(), stdin, *format)
+	if err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		name, p, err := decodePayload(in.data, *kind)
+		if err != nil {
+			return fmt.Errorf("%s: %v", in.name, err)
+		}
+		if *kind == "" {
+			fmt.Fprintf(stderr, "%s: detected type %s\n", in.name, name)
+		}
+		if *reencode {
+			var buf bytes.Buffer
+			if err := p.Serialize(&buf); err != nil {
+				return fmt.Errorf("%s: re-encode: %v", in.name, err)
+			}
+			if !bytes.Equal(buf.Bytes(), in.data) {
+				return fmt.Errorf("%s: re-encoded payload differs from input:\n got %x\nwant %x",
+					in.name, buf.Bytes(), in.data)
+			}
+			fmt.Fprintf(stderr, "%s: re-encode round trip ok\n", in.name)
+		}
+		out, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(stdout, "%s\n", out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodePayload decodes data as the named kind, or tries every kind when
+// kind is empty.  The payload must be consumed exactly.
+func decodePayload(data []byte, kind string) (string, wire.Payload, error) {
+	if kind != "" {
+		p, err := lookupKind(kind)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := decodeExact(p, data); err != nil {
+			return "", nil, err
+		}
+		return kind, p, nil
+	}
+	for _, k := range detectKinds() {
+		p, err := wire.NewPayload(k)
+		if err != nil {
+			continue
+		}
+		if decodeExact(p, data) == nil {
+			return string(k), p, nil
+		}
+	}
+	return "", nil, fmt.Errorf("payload does not decode as any known type")
+}
+
+func decodeExact(p wire.Payload, data []byte) error {
+	r := bytes.NewReader(data)
+	if err := p.Deserialize(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d trailing bytes after payload", r.Len())
+	}
+	return nil
+}
+
+type input struct {
+	name string
+	data []byte
+}
+
+// readInputs resolves the command line arguments, or stdin when there are
+// none, into raw payload bytes.
+func readInputs(args []string, stdin io.Reader, format string) ([]input, error) {
+	if len(args) == 0 {
+		raw, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		data, err := decodeInput(raw, format)
+		if err != nil {
+			return nil, fmt.Errorf("stdin: %v", err)
+		}
+		return []input{{name: "stdin", data: data}}, nil
+	}
+	inputs := make([]input, 0, len(args))
+	for i, arg := range args {
+		name, raw := fmt.Sprintf("arg %d", i+1), []byte(arg)
+		if fi, err := os.Stat(arg); err == nil && fi.Mode().IsRegular() {
+			if raw, err = os.ReadFile(arg); err != nil {
+				return nil, err
+			}
+			name = arg
+		}
+		data, err := decodeInput(raw, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		inputs = append(inputs, input{name: name, data: data})
+	}
+	return inputs, nil
+}
+
+// decodeInput converts raw input into payload bytes according to format.  In
+// auto mode, text that is valid hex is treated as hex, then base64 is tried,
+// and anything else is used as raw bytes.
+func decodeInput(raw []byte, format string) ([]byte, error) {
+	text := strings.Join(strings.Fields(string(raw)), "")
+	text = strings.TrimPrefix(text, "0x")
+	switch format {
+	case "hex":
+		return hex.DecodeString(text)
+	case "base64":
+		return base64.StdEncoding.DecodeString(text)
+	case "raw":
+		return raw, nil
+	case "auto":
+		if b, err := hex.DecodeString(text); err == nil {
+			return b, nil
+		}
+		if b, err := base64.StdEncoding.DecodeString(text); err == nil {
+			return b, nil
+		}
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type vectorFile struct {
+	Kind    string `json:"kind"`
+	Vectors []struct {
+		Name string `json:"name"`
+		Hex  string `json:"hex"`
+	} `json:"vectors"`
+}
+
+// TestDecodeGoldenVectors decodes every golden vector with an explicit type
+// and with -reencode.
+func TestDecodeGoldenVectors(t *testing.T) {
+	paths, err := filepath.Glob("../../syscoin/wire/testdata/vectors/v1/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no golden vectors found: %v", err)
+	}
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file vectorFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range file.Vectors {
+			var stdout, stderr bytes.Buffer
+			args := []string{"decode", "-type", file.Kind, "-reencode", v.Hex}
+			if err := run(args, nil, &stdout, &stderr); err != nil {
+				t.Errorf("%s/%s: %v", file.Kind, v.Name, err)
+				continue
+			}
+			if !json.Valid(stdout.Bytes()) {
+				t.Errorf("%s/%s: output is not JSON: %s", file.Kind, v.Name, stdout.Bytes())
+			}
+		}
+	}
+}
+
+func TestDecodeAutoDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		kind   string
+	}{
+		{"allocation hex", "auto", "0186c340010009", "allocation"},
+		{"allocation base64", "auto", "AYbDQAEACQ==", "allocation"},
+		{"asset explicit base64", "base64", "BFNZU1gI", "asset"},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"decode", "-format", test.format}, strings.NewReader(test.input), &stdout, &stderr)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if want := "detected type " + test.kind; !strings.Contains(stderr.String(), want) {
+			t.Errorf("%s: stderr %q does not contain %q", test.name, stderr.String(), want)
+		}
+	}
+}
+
+func TestDecodeRejectsTrailingBytes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"decode", "-type", "allocation", "0186c34001000900"}, nil, &stdout, &stderr)
+	if err == nil {
+		t.Fatal("expected an error for trailing bytes")
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+func runEncode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("encode", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: syswire encode <type> [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return fmt.Errorf("encode needs a type and at most one input file")
+	}
+	p, err := lookupKind(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var raw []byte
+	if fs.NArg() == 2 {
+		raw, err = os.ReadFile(fs.Arg(1))
+	} else {
+		raw, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := unmarshalPayload(raw, p); err != nil {
+		return err
+	}
+	if err := validatePayload(p); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return err
+	}
+	// Decoding the result back catches any limit the field checks missed
+	// and guarantees the output is something syswire decode accepts.
+	check, _ := wire.NewPayload(p.Kind())
+	if err := decodeExact(check, buf.Bytes()); err != nil {
+		return fmt.Errorf("encoded payload does not decode: %v", err)
+	}
+	_, err = fmt.Fprintln(stdout, hex.EncodeToString(buf.Bytes()))
+	return err
+}
+
+// unmarshalPayload decodes JSON into p, rejecting unknown fields.  Byte
+// fields accept either base64, as printed by syswire decode, or hex with a
+// "hex:" prefix, which is easier to write by hand.  The prefix cannot occur
+// in base64, so the two forms never collide.
+func unmarshalPayload(raw []byte, p wire.Payload) error {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	doc, err := hexToBase64(doc, reflect.TypeOf(p).Elem(), "")
+	if err != nil {
+		return err
+	}
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(normalized))
+	dec.DisallowUnknownFields()
+	return dec.Decode(p)
+}
+
+// hexPrefix marks a JSON byte field written as hex rather than base64.
+const hexPrefix = "hex:"
+
+var byteSliceType = reflect.TypeOf([]byte(nil))
+
+// hexToBase64 walks a generic JSON document alongside the Go type it will be
+// decoded into and rewrites "hex:" strings in byte fields to base64.
+func hexToBase64(doc interface{}, t reflect.Type, path string) (interface{}, error) {
+	switch {
+	case t == byteSliceType:
+		s, ok := doc.(string)
+		if !ok || !strings.HasPrefix(s, hexPrefix) {
+			return doc, nil
+		}
+		b, err := hex.DecodeString(s[len(hexPrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+
+	case t.Kind() == reflect.Slice:
+		list, ok := doc.([]interface{})
+		if !ok {
+			return doc, nil
+		}
+		for i := range list {
+			v, err := hexToBase64(list[i], t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+
+	case t.Kind() == reflect.Struct:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return doc, nil
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := f.Name
+			if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" {
+				name = tag
+			}
+			for key, v := range obj {
+				if !strings.EqualFold(key, name) {
+					continue
+				}
+				v, err := hexToBase64(v, f.Type, strings.TrimPrefix(path+"."+name, "."))
+				if err != nil {
+					return nil, err
+				}
+				obj[key] = v
+			}
+		}
+		return obj, nil
+	}
+	return doc, nil
+}
+
+// validatePayload checks the field sizes and ranges that Serialize does not
+// enforce itself.
+func validatePayload(p wire.Payload) error {
+	switch v := p.(type) {
+	case *wire.AssetAllocationType:
+		return validateAllocation(v, "VoutAssets")
+	case *wire.MintSyscoinType:
+		if err := validateAllocation(&v.Allocation, "Allocation.VoutAssets"); err != nil {
+			return err
+		}
+		for _, f := range []struct {
+			name string
+			b    []byte
+		}{
+			{"TxHash", v.TxHash}, {"BlockHash", v.BlockHash},
+			{"TxRoot", v.TxRoot}, {"ReceiptRoot", v.ReceiptRoot},
+		} {
+			if err := checkHash(f.name, f.b); err != nil {
+				return err
+			}
+		}
+		for _, f := range []struct {
+			name string
+			b    []byte
+		}{
+			{"TxParentNodes", v.TxParentNodes}, {"TxPath", v.TxPath},
+			{"ReceiptParentNodes", v.ReceiptParentNodes},
+		} {
+			if err := checkMaxLen(f.name, f.b, wire.MAX_RLP_SIZE); err != nil {
+				return err
+			}
+		}
+	case *wire.SyscoinBurnToEthereumType:
+		if err := validateAllocation(&v.Allocation, "Allocation.VoutAssets"); err != nil {
+			return err
+		}
+		return checkMaxLen("ethAddress", v.EthAddress, wire.MAX_GUID_LENGTH)
+	case *wire.AssetType:
+		return checkMaxLen("Symbol", v.Symbol, wire.MAX_GUID_LENGTH)
+	case *wire.NEVMBlockWire:
+		for _, f := range []struct {
+			name string
+			b    []byte
+		}{
+			{"NEVMBlockHash", v.NEVMBlockHash}, {"TxRoot", v.TxRoot},
+			{"ReceiptRoot", v.ReceiptRoot}, {"SYSBlockHash", v.SYSBlockHash},
+		} {
+			if err := checkHash(f.name, f.b); err != nil {
+				return err
+			}
+		}
+		if err := checkMaxLen("NEVMBlockData", v.NEVMBlockData, wire.MAX_NEVM_BLOCK_SIZE); err != nil {
+			return err
+		}
+		for i, vh := range v.VersionHashes {
+			if err := checkMaxLen(fmt.Sprintf("VersionHashes[%d]", i), vh, wire.HASH_SIZE); err != nil {
+				return err
+			}
+		}
+		return validateDiff(&v.Diff)
+	case *wire.NEVMDisconnectBlockWire:
+		if err := checkHash("SYSBlockHash", v.SYSBlockHash); err != nil {
+			return err
+		}
+		return validateDiff(&v.Diff)
+	}
+	return nil
+}
+
+func validateAllocation(a *wire.AssetAllocationType, path string) error {
+	for i, out := range a.VoutAssets {
+		for j, value := range out.Values {
+			if value.ValueSat < 0 {
+				return fmt.Errorf("%s[%d].Values[%d].ValueSat: negative amount %d",
+					path, i, j, value.ValueSat)
+			}
+		}
+	}
+	return nil
+}
+
+func validateDiff(d *wire.NEVMAddressDiff) error {
+	for i, e := range d.AddedMNNEVM {
+		if err := checkMaxLen(fmt.Sprintf("Diff.AddedMNNEVM[%d].Address", i), e.Address, wire.HASH_SIZE); err != nil {
+			return err
+		}
+	}
+	for i, e := range d.UpdatedMNNEVM {
+		if err := checkMaxLen(fmt.Sprintf("Diff.UpdatedMNNEVM[%d].OldAddress", i), e.OldAddress, wire.HASH_SIZE); err != nil {
+			return err
+		}
+		if err := checkMaxLen(fmt.Sprintf("Diff.UpdatedMNNEVM[%d].NewAddress", i), e.NewAddress, wire.HASH_SIZE); err != nil {
+			return err
+		}
+	}
+	for i, e := range d.RemovedMNNEVM {
+		if err := checkMaxLen(fmt.Sprintf("Diff.RemovedMNNEVM[%d].Address", i), e.Address, wire.HASH_SIZE); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkHash(name string, b []byte) error {
+	if len(b) != wire.HASH_SIZE {
+		return fmt.Errorf("%s: must be %d bytes, got %d", name, wire.HASH_SIZE, len(b))
+	}
+	return nil
+}
+
+func checkMaxLen(name string, b []byte, max int) error {
+	if len(b) > max {
+		return fmt.Errorf("%s: at most %d bytes allowed, got %d", name, max, len(b))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		input string
+		want  string
+	}{
+		{
+			name:  "allocation",
+			kind:  "allocation",
+			input: `{"VoutAssets":[{"AssetGuid":123456,"Values":[{"N":0,"ValueSat":100000000}]}]}`,
+			want:  "0186c340010009",
+		},
+		{
+			name:  "burn with hex address",
+			kind:  "burn-eth",
+			input: `{"Allocation":{"VoutAssets":[]},"ethAddress":"hex:5a5a"}`,
+			want:  "00025a5a",
+		},
+		{
+			name:  "asset with base64 symbol",
+			kind:  "asset",
+			input: `{"Symbol":"U1lTWA==","Precision":8}`,
+			want:  "045359535808",
+		},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"encode", test.kind}, strings.NewReader(test.input), &stdout, &stderr)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := strings.TrimSpace(stdout.String()); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEncodeValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		input string
+	}{
+		{"unknown field", "allocation", `{"VoutAssets":[],"Extra":1}`},
+		{"negative amount", "allocation", `{"VoutAssets":[{"AssetGuid":1,"Values":[{"N":0,"ValueSat":-1}]}]}`},
+		{"short hash", "nevmdisconnect", `{"SYSBlockHash":"hex:00","Diff":{}}`},
+		{"long eth address", "burn-eth", `{"ethAddress":"hex:` + strings.Repeat("00", 21) + `"}`},
+		{"bad hex", "asset", `{"Symbol":"hex:zz"}`},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"encode", test.kind}, strings.NewReader(test.input), &stdout, &stderr)
+		if err == nil {
+			t.Errorf("%s: expected an error, got output %s", test.name, stdout.String())
+		}
+	}
+}
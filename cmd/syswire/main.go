@@ -0,0 +1,60 @@
+// Command syswire decodes Syscoin payloads such as asset allocation OP_RETURN
+// data and NEVM ZMQ frames into JSON, and encodes JSON back into canonical
+// payload hex.
+//
+// Usage:
+//
+//	syswire decode [-type kind] [-format auto|hex|base64|raw] [-reencode] [input ...]
+//	syswire encode <kind> [file]
+//
+// Each input is either a hex or base64 string or the path of a file holding
+// the payload.  With no inputs the payload is read from stdin.  Supported
+// kinds are allocation, mint, burn-eth, asset, nevmblock and nevmdisconnect;
+// when -type is omitted every kind is tried and the first that consumes the
+// whole payload is used.
+//
+// encode reads JSON in the shape decode prints, from the file or stdin,
+// validates it and prints the serialized payload as hex.  Byte fields may be
+// given as base64 or as hex with a "hex:" prefix.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: syswire <command> [flags] [args]
+
+commands:
+  decode    decode a payload into JSON
+  encode    encode JSON into payload hex
+
+run "syswire <command> -h" for command flags
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "syswire: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("missing command")
+	}
+	switch args[0] {
+	case "decode":
+		return runDecode(args[1:], stdin, stdout, stderr)
+	case "encode":
+		return runEncode(args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
@@ -2,10 +2,20 @@ module github.com/syscoin/syscoinwire
 
 go 1.22
 
-require github.com/btcsuite/btcd v0.24.2
+require (
+	github.com/btcsuite/btcd v0.24.2
+	github.com/btcsuite/btcd/btcec/v2 v2.1.3
+	github.com/btcsuite/btcd/btcutil v1.1.5
+	github.com/btcsuite/btcd/btcutil/psbt v1.1.8
+	github.com/btcsuite/btcd/chaincfg/chainhash v1.1.0
+	github.com/kilic/bls12-381 v0.1.0
+	go.etcd.io/bbolt v1.3.11
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+)
 
 require (
-	github.com/btcsuite/btcd/chaincfg/chainhash v1.1.0 // indirect
-	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9 // indirect
-	golang.org/x/sys v0.0.0-20200814200057-3d37ad5750ed // indirect
+	github.com/btcsuite/btclog v0.0.0-20170628155309-84c8d2346e9f // indirect
+	github.com/decred/dcrd/crypto/blake256 v1.0.0 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+	golang.org/x/sys v0.4.0 // indirect
 )
@@ -0,0 +1,126 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// DecodeAddress decodes a Syscoin address for the network described by
+// params: a base58 P2PKH or P2SH address, or a bech32 P2WPKH or P2WSH or
+// bech32m P2TR address.  Addresses of other networks are rejected.
+func DecodeAddress(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	prefix := params.Bech32HRPSegwit + "1"
+	if len(addr) > len(prefix) && strings.EqualFold(addr[:len(prefix)], prefix) {
+		return decodeSegWitAddress(addr, params)
+	}
+
+	decoded, netID, err := base58.CheckDecode(addr)
+	if err != nil {
+		return nil, fmt.Errorf("address %q: %v", addr, err)
+	}
+	if len(decoded) != 20 {
+		return nil, fmt.Errorf("address %q has a %d-byte payload", addr, len(decoded))
+	}
+	switch netID {
+	case params.PubKeyHashAddrID:
+		return btcutil.NewAddressPubKeyHash(decoded, params)
+	case params.ScriptHashAddrID:
+		return btcutil.NewAddressScriptHashFromHash(decoded, params)
+	}
+	return nil, fmt.Errorf("address %q is not a %s address", addr, params.Name)
+}
+
+func decodeSegWitAddress(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	hrp, data, version, err := bech32.DecodeGeneric(addr)
+	if err != nil {
+		return nil, fmt.Errorf("address %q: %v", addr, err)
+	}
+	if hrp != params.Bech32HRPSegwit {
+		return nil, fmt.Errorf("address %q is not a %s address", addr, params.Name)
+	}
+	if len(data) < 1 {
+		return nil, fmt.Errorf("address %q has no witness version", addr)
+	}
+	witnessVer := data[0]
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("address %q: %v", addr, err)
+	}
+
+	// BIP 350: version 0 programs use bech32, later versions bech32m.
+	if (witnessVer == 0) != (version == bech32.Version0) {
+		return nil, fmt.Errorf("address %q uses the wrong checksum for witness version %d", addr, witnessVer)
+	}
+	switch {
+	case witnessVer == 0 && len(program) == 20:
+		return btcutil.NewAddressWitnessPubKeyHash(program, params)
+	case witnessVer == 0 && len(program) == 32:
+		return btcutil.NewAddressWitnessScriptHash(program, params)
+	case witnessVer == 1 && len(program) == 32:
+		return btcutil.NewAddressTaproot(program, params)
+	}
+	return nil, fmt.Errorf("address %q has an unsupported witness version %d program of %d bytes",
+		addr, witnessVer, len(program))
+}
+
+// PayToAddrScript returns the output script paying addr, which is decoded
+// with DecodeAddress.
+func PayToAddrScript(addr string, params *chaincfg.Params) ([]byte, error) {
+	a, err := DecodeAddress(addr, params)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(a)
+}
+
+// ErrNoAddress is returned by ScriptAddress for scripts that do not pay a
+// single address, such as OP_RETURN outputs and bare multisig.
+var ErrNoAddress = errors.New("script does not pay a single address")
+
+// ScriptAddress returns the address pkScript pays: P2PKH, P2SH, P2WPKH,
+// P2WSH or P2TR.  Bare public key outputs map to their P2PKH address.
+func ScriptAddress(pkScript []byte, params *chaincfg.Params) (string, error) {
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, params)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) != 1 || class == txscript.MultiSigTy {
+		return "", ErrNoAddress
+	}
+	if pk, ok := addrs[0].(*btcutil.AddressPubKey); ok {
+		return pk.AddressPubKeyHash().EncodeAddress(), nil
+	}
+	return addrs[0].EncodeAddress(), nil
+}
+
+// AddressFunc returns a function mapping output scripts to their addresses,
+// or to the empty string for scripts without one.  It suits
+// indexer.Config.AddressOf.
+func AddressFunc(params *chaincfg.Params) func(pkScript []byte) string {
+	return func(pkScript []byte) string {
+		addr, err := ScriptAddress(pkScript, params)
+		if err != nil {
+			return ""
+		}
+		return addr
+	}
+}
+
+// PayToScriptFunc returns PayToAddrScript bound to params, in the form
+// governance.CheckSuperblockCoinbase expects.
+func PayToScriptFunc(params *chaincfg.Params) func(addr string) ([]byte, error) {
+	return func(addr string) ([]byte, error) {
+		return PayToAddrScript(addr, params)
+	}
+}
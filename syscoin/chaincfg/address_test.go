@@ -0,0 +1,108 @@
+package chaincfg
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+func TestAddressRoundTrip(t *testing.T) {
+	hash20 := bytes.Repeat([]byte{0x11}, 20)
+	hash32 := bytes.Repeat([]byte{0x22}, 32)
+	nets := []struct {
+		params       *chaincfg.Params
+		p2pkh, p2sh  string // leading characters
+		segwitPrefix string
+	}{
+		{&MainNetParams, "S", "3", "sys1"},
+		{&TestNetParams, "T", "2", "tsys1"},
+		{&RegressionNetParams, "T", "2", "scrt1"},
+	}
+	for _, net := range nets {
+		p := net.params
+		var addrs []btcutil.Address
+		add := func(a btcutil.Address, err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrs = append(addrs, a)
+		}
+		add(btcutil.NewAddressPubKeyHash(hash20, p))
+		add(btcutil.NewAddressScriptHashFromHash(hash20, p))
+		add(btcutil.NewAddressWitnessPubKeyHash(hash20, p))
+		add(btcutil.NewAddressWitnessScriptHash(hash32, p))
+		add(btcutil.NewAddressTaproot(hash32, p))
+
+		prefixes := []string{net.p2pkh, net.p2sh, net.segwitPrefix + "q", net.segwitPrefix + "q", net.segwitPrefix + "p"}
+		for i, a := range addrs {
+			s := a.EncodeAddress()
+			if !strings.HasPrefix(s, prefixes[i]) {
+				t.Errorf("%s: %s does not start with %s", p.Name, s, prefixes[i])
+			}
+			decoded, err := DecodeAddress(s, p)
+			if err != nil {
+				t.Fatalf("%s: DecodeAddress(%s): %v", p.Name, s, err)
+			}
+			if decoded.String() != s || !bytes.Equal(decoded.ScriptAddress(), a.ScriptAddress()) {
+				t.Errorf("%s: DecodeAddress(%s) = %v", p.Name, s, decoded)
+			}
+			if i >= 2 {
+				if _, err := DecodeAddress(strings.ToUpper(s), p); err != nil {
+					t.Errorf("%s: upper-case %s rejected: %v", p.Name, s, err)
+				}
+			}
+			script, err := PayToAddrScript(s, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := ScriptAddress(script, p); err != nil || got != s {
+				t.Errorf("%s: ScriptAddress = %s, %v, want %s", p.Name, got, err, s)
+			}
+			if got := AddressFunc(p)(script); got != s {
+				t.Errorf("%s: AddressFunc = %s, want %s", p.Name, got, s)
+			}
+		}
+	}
+
+	// Mainnet and testnet addresses are not interchangeable.
+	main, _ := btcutil.NewAddressPubKeyHash(hash20, &MainNetParams)
+	if _, err := DecodeAddress(main.EncodeAddress(), &TestNetParams); err == nil {
+		t.Error("mainnet P2PKH address accepted on testnet")
+	}
+	tsys, _ := btcutil.NewAddressWitnessPubKeyHash(hash20, &TestNetParams)
+	if _, err := DecodeAddress(tsys.EncodeAddress(), &RegressionNetParams); err == nil {
+		t.Error("testnet bech32 address accepted on regtest")
+	}
+	if _, err := DecodeAddress("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", &MainNetParams); err == nil {
+		t.Error("Bitcoin address accepted on Syscoin mainnet")
+	}
+}
+
+func TestDecodeAddressChecksumVariant(t *testing.T) {
+	// A version 1 program with a bech32 rather than bech32m checksum is
+	// invalid under BIP 350, and so is version 0 with bech32m.
+	prog, _ := bech32.ConvertBits(bytes.Repeat([]byte{0x22}, 32), 8, 5, true)
+	v1, _ := bech32.Encode("sys", append([]byte{1}, prog...))
+	v0m, _ := bech32.EncodeM("sys", append([]byte{0}, prog...))
+	for _, s := range []string{v1, v0m} {
+		if _, err := DecodeAddress(s, &MainNetParams); err == nil {
+			t.Errorf("DecodeAddress(%s) accepted", s)
+		}
+	}
+}
+
+func TestScriptAddressWithoutAddress(t *testing.T) {
+	script, _ := txscript.NullDataScript([]byte("payload"))
+	if _, err := ScriptAddress(script, &MainNetParams); !errors.Is(err, ErrNoAddress) {
+		t.Errorf("got %v, want ErrNoAddress", err)
+	}
+	if got := AddressFunc(&MainNetParams)(script); got != "" {
+		t.Errorf("AddressFunc = %q", got)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package chaincfg defines the address parameters of the Syscoin networks
+// and encodes and decodes Syscoin addresses.
+//
+// The parameters are btcd chaincfg.Params values so that btcutil's address
+// types and txscript's script helpers work with them, but only the fields
+// describing the network magic, address encodings and key versions are set.
+// They are deliberately not registered with btcd's chaincfg: Syscoin's
+// regtest shares Bitcoin regtest's magic, and registration is global.  Use
+// DecodeAddress from this package instead of btcutil.DecodeAddress, which
+// only accepts registered bech32 prefixes.
+package chaincfg
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// MainNetParams are the address parameters of Syscoin mainnet.
+var MainNetParams = chaincfg.Params{
+	Name:             "mainnet",
+	Net:              wire.SyscoinMainNet,
+	Bech32HRPSegwit:  "sys",
+	PubKeyHashAddrID: 63,                              // starts with S
+	ScriptHashAddrID: 5,                               // starts with 3
+	PrivateKeyID:     128,                             // WIF starts with 5, K or L
+	HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4}, // xprv
+	HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e}, // xpub
+	HDCoinType:       57,
+}
+
+// TestNetParams are the address parameters of Syscoin testnet.
+var TestNetParams = chaincfg.Params{
+	Name:             "testnet",
+	Net:              wire.SyscoinTestNet,
+	Bech32HRPSegwit:  "tsys",
+	PubKeyHashAddrID: 65,                              // starts with T
+	ScriptHashAddrID: 196,                             // starts with 2
+	PrivateKeyID:     239,                             // WIF starts with 9 or c
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+	HDCoinType:       1,
+}
+
+// RegressionNetParams are the address parameters of Syscoin regtest.
+var RegressionNetParams = chaincfg.Params{
+	Name:             "regtest",
+	Net:              wire.SyscoinRegTest,
+	Bech32HRPSegwit:  "scrt",
+	PubKeyHashAddrID: 65,
+	ScriptHashAddrID: 196,
+	PrivateKeyID:     239,
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+	HDCoinType:       1,
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package evo
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// MasternodeListDiff describes how one masternode list differs from an
+// earlier one.
+type MasternodeListDiff struct {
+	BaseBlockHash chainhash.Hash
+	BlockHash     chainhash.Hash
+	Added         []*Masternode
+	Updated       []*Masternode
+	Removed       []chainhash.Hash
+}
+
+// Diff returns the changes that turn l into to.  Entries are sorted by
+// ProTxHash.
+func (l *MasternodeList) Diff(to *MasternodeList) *MasternodeListDiff {
+	d := &MasternodeListDiff{BaseBlockHash: l.BlockHash, BlockHash: to.BlockHash}
+	for _, mn := range to.Masternodes() {
+		old, ok := l.mns[mn.ProTxHash]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, mn)
+		case !reflect.DeepEqual(old.copy(), mn):
+			d.Updated = append(d.Updated, mn)
+		}
+	}
+	for h := range l.mns {
+		if _, ok := to.mns[h]; !ok {
+			d.Removed = append(d.Removed, h)
+		}
+	}
+	sort.Slice(d.Removed, func(i, j int) bool {
+		return bytes.Compare(d.Removed[i][:], d.Removed[j][:]) < 0
+	})
+	return d
+}
+
+// NEVMAddresses returns the NEVM address of every masternode that has one,
+// mapped to its collateral height.
+func (l *MasternodeList) NEVMAddresses() map[string]uint32 {
+	addrs := make(map[string]uint32)
+	for _, mn := range l.mns {
+		if len(mn.NEVMAddress) > 0 {
+			addrs[string(mn.NEVMAddress)] = uint32(mn.CollateralHeight)
+		}
+	}
+	return addrs
+}
+
+// BuildNEVMDiff returns the NEVM address diff syscoind reports to the NEVM
+// client when the masternode list changes from l to to.  A masternode whose
+// address changes is an update; gaining or losing an address, including by
+// registration or removal, is an addition or removal.
+func (l *MasternodeList) BuildNEVMDiff(to *MasternodeList) wire.NEVMAddressDiff {
+	var d wire.NEVMAddressDiff
+	for _, mn := range to.Masternodes() {
+		if len(mn.NEVMAddress) == 0 {
+			continue
+		}
+		var oldAddr []byte
+		if old, ok := l.mns[mn.ProTxHash]; ok {
+			oldAddr = old.NEVMAddress
+		}
+		switch {
+		case len(oldAddr) == 0:
+			d.AddedMNNEVM = append(d.AddedMNNEVM, wire.NEVMAddressEntry{
+				Address:          mn.NEVMAddress,
+				CollateralHeight: uint32(mn.CollateralHeight),
+			})
+		case !bytes.Equal(oldAddr, mn.NEVMAddress):
+			d.UpdatedMNNEVM = append(d.UpdatedMNNEVM, wire.NEVMAddressUpdateEntry{
+				OldAddress:       append([]byte(nil), oldAddr...),
+				NewAddress:       mn.NEVMAddress,
+				CollateralHeight: uint32(mn.CollateralHeight),
+			})
+		}
+	}
+	for _, old := range l.Masternodes() {
+		if len(old.NEVMAddress) == 0 {
+			continue
+		}
+		if mn, ok := to.mns[old.ProTxHash]; !ok || len(mn.NEVMAddress) == 0 {
+			d.RemovedMNNEVM = append(d.RemovedMNNEVM, wire.NEVMRemoveEntry{Address: old.NEVMAddress})
+		}
+	}
+	return d
+}
+
+// CheckNEVMDiff verifies that diff, as carried by an NEVMBlockWire or
+// NEVMDisconnectBlockWire, matches the change from l to to.  Entries are
+// compared without regard to order.  Collateral heights are only compared
+// when this list knows them.
+func (l *MasternodeList) CheckNEVMDiff(to *MasternodeList, diff *wire.NEVMAddressDiff) error {
+	want := l.BuildNEVMDiff(to)
+
+	if err := compareEntries("added", keysOfAdded(want.AddedMNNEVM), keysOfAdded(diff.AddedMNNEVM)); err != nil {
+		return err
+	}
+	if err := compareEntries("updated", keysOfUpdated(want.UpdatedMNNEVM), keysOfUpdated(diff.UpdatedMNNEVM)); err != nil {
+		return err
+	}
+	removedWant := make(map[string]uint32, len(want.RemovedMNNEVM))
+	for _, e := range want.RemovedMNNEVM {
+		removedWant[string(e.Address)] = 0
+	}
+	removedGot := make(map[string]uint32, len(diff.RemovedMNNEVM))
+	for _, e := range diff.RemovedMNNEVM {
+		removedGot[string(e.Address)] = 0
+	}
+	return compareEntries("removed", removedWant, removedGot)
+}
+
+func keysOfAdded(entries []wire.NEVMAddressEntry) map[string]uint32 {
+	m := make(map[string]uint32, len(entries))
+	for _, e := range entries {
+		m[string(e.Address)] = e.CollateralHeight
+	}
+	return m
+}
+
+func keysOfUpdated(entries []wire.NEVMAddressUpdateEntry) map[string]uint32 {
+	m := make(map[string]uint32, len(entries))
+	for _, e := range entries {
+		m[string(e.OldAddress)+"->"+string(e.NewAddress)] = e.CollateralHeight
+	}
+	return m
+}
+
+// compareEntries reports the first difference between the expected and the
+// reported entries.  A zero expected height matches any reported height.
+func compareEntries(section string, want, got map[string]uint32) error {
+	for key, height := range want {
+		gotHeight, ok := got[key]
+		if !ok {
+			return fmt.Errorf("NEVM diff is missing %s address %x", section, key)
+		}
+		if height != 0 && gotHeight != height {
+			return fmt.Errorf("NEVM diff %s address %x has collateral height %d, want %d",
+				section, key, gotHeight, height)
+		}
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			return fmt.Errorf("NEVM diff has unexpected %s address %x", section, key)
+		}
+	}
+	return nil
+}
+
+// ApplyNEVMBlock applies block like ApplyBlock and then cross-checks the
+// NEVM address diff syscoind attached to the block's NEVMBlockWire.  The
+// list is only advanced when both succeed.
+func (l *MasternodeList) ApplyNEVMBlock(block *btcwire.MsgBlock, nevm *wire.NEVMBlockWire) error {
+	blockHash := block.BlockHash()
+	if !bytes.Equal(nevm.SYSBlockHash, blockHash[:]) {
+		return fmt.Errorf("NEVM block is for Syscoin block %x, not %v", nevm.SYSBlockHash, blockHash)
+	}
+	next := l.Snapshot()
+	if err := next.ApplyBlock(block); err != nil {
+		return err
+	}
+	if err := l.CheckNEVMDiff(next, &nevm.Diff); err != nil {
+		return fmt.Errorf("block %v: %v", blockHash, err)
+	}
+	*l = *next
+	return nil
+}
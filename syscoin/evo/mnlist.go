@@ -0,0 +1,423 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package evo builds Syscoin's deterministic masternode list from raw blocks.
+//
+// The list follows registrations, updates, revocations and collateral
+// spends.  Proof-of-service penalties for the members a quorum commitment
+// marks invalid need the quorum's member set, which is computed from the
+// list at the quorum's base block; ApplyBlock derives them when the list's
+// QuorumMembers is set.
+package evo
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// Masternode is the state of one deterministic masternode.
+type Masternode struct {
+	ProTxHash          chainhash.Hash
+	CollateralOutpoint btcwire.OutPoint
+	CollateralHeight   int32
+	RegisteredHeight   int32
+
+	// ConfirmedHash is the hash of the block at which the registration
+	// reached the list's MinConfirmations.  Until then it is zero and the
+	// masternode is not a quorum candidate.
+	ConfirmedHash chainhash.Hash
+
+	Addr                 wire.NetService
+	KeyIDOwner           [wire.KEY_ID_SIZE]byte
+	PubKeyOperator       [wire.BLS_PUBKEY_SIZE]byte
+	KeyIDVoting          [wire.KEY_ID_SIZE]byte
+	OperatorReward       uint16
+	ScriptPayout         []byte
+	ScriptOperatorPayout []byte
+	NEVMAddress          []byte
+
+	PoSePenalty       int32
+	PoSeRevivedHeight int32
+	PoSeBanHeight     int32
+	RevocationReason  uint16
+}
+
+// IsPoSeBanned reports whether the masternode is banned by proof of service.
+func (mn *Masternode) IsPoSeBanned() bool {
+	return mn.PoSeBanHeight != -1
+}
+
+func (mn *Masternode) copy() *Masternode {
+	c := *mn
+	c.Addr.IP = append([]byte(nil), mn.Addr.IP...)
+	c.ScriptPayout = append([]byte(nil), mn.ScriptPayout...)
+	c.ScriptOperatorPayout = append([]byte(nil), mn.ScriptOperatorPayout...)
+	c.NEVMAddress = append([]byte(nil), mn.NEVMAddress...)
+	return &c
+}
+
+// resetOperatorFields clears everything the operator controls, as syscoind
+// does when the operator key changes or is revoked.
+func (mn *Masternode) resetOperatorFields() {
+	mn.PubKeyOperator = [wire.BLS_PUBKEY_SIZE]byte{}
+	mn.Addr = wire.NetService{}
+	mn.ScriptOperatorPayout = nil
+	mn.NEVMAddress = nil
+	mn.RevocationReason = 0
+}
+
+func (mn *Masternode) banIfNotBanned(height int32) {
+	if !mn.IsPoSeBanned() {
+		mn.PoSeBanHeight = height
+	}
+}
+
+// CollateralHeightFunc returns the height at which an external collateral
+// outpoint was confirmed.  It is consulted for ProRegTx transactions whose
+// collateral is not created by the registration itself.
+type CollateralHeightFunc func(op btcwire.OutPoint) (int32, bool)
+
+// QuorumMembersFunc returns the members of the quorum of the given type and
+// hash in quorum order, typically by calling CalculateQuorum on the list at
+// the quorum's base block.
+type QuorumMembersFunc func(llmqType uint8, quorumHash chainhash.Hash) ([]chainhash.Hash, bool)
+
+// MASTERNODE_MIN_CONFIRMATIONS is the number of blocks syscoind waits after
+// a registration before the masternode is confirmed on mainnet and testnet.
+// Regtest uses 1.
+const MASTERNODE_MIN_CONFIRMATIONS = 15
+
+// MasternodeList is the deterministic masternode list as of one block.  It
+// is built by applying blocks in order with ApplyBlock.
+type MasternodeList struct {
+	BlockHash chainhash.Hash
+	Height    int32
+
+	// CollateralHeight, when set, resolves the confirmation height of
+	// external collaterals.  Without it those masternodes report a
+	// CollateralHeight of 0.
+	CollateralHeight CollateralHeightFunc
+
+	// QuorumMembers, when set, resolves the members of the quorums whose
+	// commitments a block mines, and each member a commitment marks
+	// invalid is punished as syscoind does.  Without it commitments are
+	// ignored and PoSe scores only change through PoSePunish, the
+	// per-block decay and ProUpServTx revivals.
+	QuorumMembers QuorumMembersFunc
+
+	// MinConfirmations is the number of blocks after its registration at
+	// which a masternode is confirmed.
+	MinConfirmations int32
+
+	mns          map[chainhash.Hash]*Masternode
+	byCollateral map[btcwire.OutPoint]chainhash.Hash
+}
+
+// NewMasternodeList returns an empty list positioned before the block with
+// the given height, typically the DIP3 activation height.
+func NewMasternodeList(startHeight int32) *MasternodeList {
+	return &MasternodeList{
+		Height:           startHeight - 1,
+		MinConfirmations: MASTERNODE_MIN_CONFIRMATIONS,
+		mns:              make(map[chainhash.Hash]*Masternode),
+		byCollateral:     make(map[btcwire.OutPoint]chainhash.Hash),
+	}
+}
+
+// Count returns the number of masternodes in the list, including banned
+// ones.
+func (l *MasternodeList) Count() int {
+	return len(l.mns)
+}
+
+// ValidCount returns the number of masternodes that are not PoSe banned.
+func (l *MasternodeList) ValidCount() int {
+	n := 0
+	for _, mn := range l.mns {
+		if !mn.IsPoSeBanned() {
+			n++
+		}
+	}
+	return n
+}
+
+// Get returns a copy of the masternode registered by proTxHash.
+func (l *MasternodeList) Get(proTxHash chainhash.Hash) (*Masternode, bool) {
+	mn, ok := l.mns[proTxHash]
+	if !ok {
+		return nil, false
+	}
+	return mn.copy(), true
+}
+
+// GetByCollateral returns a copy of the masternode using the given
+// collateral outpoint.
+func (l *MasternodeList) GetByCollateral(op btcwire.OutPoint) (*Masternode, bool) {
+	proTxHash, ok := l.byCollateral[op]
+	if !ok {
+		return nil, false
+	}
+	return l.Get(proTxHash)
+}
+
+// Masternodes returns copies of every masternode sorted by ProTxHash.
+func (l *MasternodeList) Masternodes() []*Masternode {
+	mns := make([]*Masternode, 0, len(l.mns))
+	for _, mn := range l.mns {
+		mns = append(mns, mn.copy())
+	}
+	sort.Slice(mns, func(i, j int) bool {
+		return bytes.Compare(mns[i].ProTxHash[:], mns[j].ProTxHash[:]) < 0
+	})
+	return mns
+}
+
+// Snapshot returns a deep copy of the list that is unaffected by later
+// calls to ApplyBlock.
+func (l *MasternodeList) Snapshot() *MasternodeList {
+	s := &MasternodeList{
+		BlockHash:        l.BlockHash,
+		Height:           l.Height,
+		CollateralHeight: l.CollateralHeight,
+		QuorumMembers:    l.QuorumMembers,
+		MinConfirmations: l.MinConfirmations,
+		mns:              make(map[chainhash.Hash]*Masternode, len(l.mns)),
+		byCollateral:     make(map[btcwire.OutPoint]chainhash.Hash, len(l.byCollateral)),
+	}
+	for h, mn := range l.mns {
+		s.mns[h] = mn.copy()
+	}
+	for op, h := range l.byCollateral {
+		s.byCollateral[op] = h
+	}
+	return s
+}
+
+// PoSePunish adds penalty to the masternode's proof of service score and
+// bans it once the score reaches the number of valid masternodes, matching
+// syscoind.  ApplyBlock calls it for the members a quorum commitment marks
+// invalid when QuorumMembers is set.
+func (l *MasternodeList) PoSePunish(proTxHash chainhash.Hash, penalty int32) error {
+	mn, ok := l.mns[proTxHash]
+	if !ok {
+		return fmt.Errorf("masternode %v not found", proTxHash)
+	}
+	maxPenalty := int32(l.ValidCount())
+	mn.PoSePenalty += penalty
+	if mn.PoSePenalty > maxPenalty {
+		mn.PoSePenalty = maxPenalty
+	}
+	if mn.PoSePenalty >= maxPenalty {
+		mn.banIfNotBanned(l.Height)
+	}
+	return nil
+}
+
+// decreasePoSePenalties lowers the score of every valid masternode by one,
+// which syscoind does once per block.
+func (l *MasternodeList) decreasePoSePenalties() {
+	for _, mn := range l.mns {
+		if mn.PoSePenalty > 0 && !mn.IsPoSeBanned() {
+			mn.PoSePenalty--
+		}
+	}
+}
+
+func (l *MasternodeList) remove(proTxHash chainhash.Hash) {
+	if mn, ok := l.mns[proTxHash]; ok {
+		delete(l.byCollateral, mn.CollateralOutpoint)
+		delete(l.mns, proTxHash)
+	}
+}
+
+// ApplyBlock advances the list by one block.  The block must extend the
+// block the list is currently at.  On error the list is left unchanged.
+func (l *MasternodeList) ApplyBlock(block *btcwire.MsgBlock) error {
+	if l.BlockHash != (chainhash.Hash{}) && block.Header.PrevBlock != l.BlockHash {
+		return fmt.Errorf("block %v does not extend masternode list tip %v",
+			block.BlockHash(), l.BlockHash)
+	}
+	next := l.Snapshot()
+	next.Height = l.Height + 1
+	next.BlockHash = block.BlockHash()
+	// Confirmation is judged at the previous block, so a masternode is
+	// confirmed one block after it reaches MinConfirmations.
+	for _, mn := range next.mns {
+		if mn.ConfirmedHash == (chainhash.Hash{}) && l.Height-mn.RegisteredHeight >= l.MinConfirmations {
+			mn.ConfirmedHash = l.BlockHash
+		}
+	}
+	next.decreasePoSePenalties()
+
+	for _, tx := range block.Transactions {
+		if err := next.applyTx(tx); err != nil {
+			return fmt.Errorf("block %v tx %v: %v", next.BlockHash, tx.TxHash(), err)
+		}
+	}
+	// As in syscoind, spent collaterals are only removed once every
+	// provider transaction of the block is applied, so updates later in
+	// the block still find their masternode and a registration whose
+	// collateral the block spends does not survive.
+	for _, tx := range block.Transactions {
+		for _, in := range tx.TxIn {
+			if proTxHash, ok := next.byCollateral[in.PreviousOutPoint]; ok {
+				next.remove(proTxHash)
+			}
+		}
+	}
+	*l = *next
+	return nil
+}
+
+func (l *MasternodeList) applyTx(tx *btcwire.MsgTx) error {
+	switch tx.Version {
+	case wire.SYSCOIN_TX_VERSION_MN_REGISTER,
+		wire.SYSCOIN_TX_VERSION_MN_UPDATE_SERVICE,
+		wire.SYSCOIN_TX_VERSION_MN_UPDATE_REGISTRAR,
+		wire.SYSCOIN_TX_VERSION_MN_UPDATE_REVOKE:
+	case wire.SYSCOIN_TX_VERSION_MN_QUORUM_COMMITMENT:
+		if l.QuorumMembers == nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	p, err := wire.DecodeTxPayload(tx)
+	if err != nil {
+		return err
+	}
+	switch pl := p.(type) {
+	case *wire.ProRegTx:
+		return l.applyProRegTx(tx, pl)
+	case *wire.ProUpServTx:
+		return l.applyProUpServTx(pl)
+	case *wire.ProUpRegTx:
+		return l.applyProUpRegTx(pl)
+	case *wire.ProUpRevTx:
+		return l.applyProUpRevTx(pl)
+	case *wire.FinalCommitmentTxPayload:
+		return l.applyCommitment(&pl.Commitment)
+	}
+	return nil
+}
+
+func (l *MasternodeList) applyProRegTx(tx *btcwire.MsgTx, p *wire.ProRegTx) error {
+	mn := &Masternode{
+		ProTxHash:          tx.TxHash(),
+		CollateralOutpoint: p.CollateralOutpoint,
+		RegisteredHeight:   l.Height,
+		Addr:               p.Addr,
+		KeyIDOwner:         p.KeyIDOwner,
+		PubKeyOperator:     p.PubKeyOperator,
+		KeyIDVoting:        p.KeyIDVoting,
+		OperatorReward:     p.OperatorReward,
+		ScriptPayout:       p.ScriptPayout,
+		PoSeRevivedHeight:  -1,
+		PoSeBanHeight:      -1,
+	}
+	if mn.CollateralOutpoint.Hash == (chainhash.Hash{}) {
+		// The collateral is an output of the registration itself.
+		mn.CollateralOutpoint.Hash = mn.ProTxHash
+		mn.CollateralHeight = l.Height
+	} else if l.CollateralHeight != nil {
+		if height, ok := l.CollateralHeight(mn.CollateralOutpoint); ok {
+			mn.CollateralHeight = height
+		}
+	}
+	if _, ok := l.mns[mn.ProTxHash]; ok {
+		return fmt.Errorf("duplicate masternode %v", mn.ProTxHash)
+	}
+	if replaced, ok := l.byCollateral[mn.CollateralOutpoint]; ok {
+		// Only an external collateral can be registered twice.  The new
+		// registration replaces the old one, which starts over at the
+		// bottom of the payment list as syscoind does.
+		l.remove(replaced)
+	}
+	for _, other := range l.mns {
+		if other.KeyIDOwner == mn.KeyIDOwner {
+			return fmt.Errorf("owner key is already used by %v", other.ProTxHash)
+		}
+		if other.PubKeyOperator == mn.PubKeyOperator && mn.PubKeyOperator != ([wire.BLS_PUBKEY_SIZE]byte{}) {
+			return fmt.Errorf("operator key is already used by %v", other.ProTxHash)
+		}
+	}
+	l.mns[mn.ProTxHash] = mn
+	l.byCollateral[mn.CollateralOutpoint] = mn.ProTxHash
+	return nil
+}
+
+func (l *MasternodeList) applyProUpServTx(p *wire.ProUpServTx) error {
+	mn, ok := l.mns[p.ProTxHash]
+	if !ok {
+		return fmt.Errorf("update of unknown masternode %v", p.ProTxHash)
+	}
+	mn.Addr = p.Addr
+	mn.ScriptOperatorPayout = p.ScriptOperatorPayout
+	mn.NEVMAddress = p.NEVMAddress
+	if mn.IsPoSeBanned() {
+		// A banned masternode is revived by its operator updating the
+		// service.
+		mn.PoSePenalty = 0
+		mn.PoSeBanHeight = -1
+		mn.PoSeRevivedHeight = l.Height
+	}
+	return nil
+}
+
+func (l *MasternodeList) applyProUpRegTx(p *wire.ProUpRegTx) error {
+	mn, ok := l.mns[p.ProTxHash]
+	if !ok {
+		return fmt.Errorf("update of unknown masternode %v", p.ProTxHash)
+	}
+	if mn.PubKeyOperator != p.PubKeyOperator {
+		mn.resetOperatorFields()
+		mn.banIfNotBanned(l.Height)
+	}
+	mn.PubKeyOperator = p.PubKeyOperator
+	mn.KeyIDVoting = p.KeyIDVoting
+	mn.ScriptPayout = p.ScriptPayout
+	return nil
+}
+
+func (l *MasternodeList) applyProUpRevTx(p *wire.ProUpRevTx) error {
+	mn, ok := l.mns[p.ProTxHash]
+	if !ok {
+		return fmt.Errorf("revocation of unknown masternode %v", p.ProTxHash)
+	}
+	mn.resetOperatorFields()
+	mn.banIfNotBanned(l.Height)
+	mn.RevocationReason = p.Reason
+	return nil
+}
+
+// applyCommitment punishes the quorum members c marks invalid with 66
+// percent of the maximum penalty, so that a masternode failing two DKG
+// sessions in quick succession is banned.
+func (l *MasternodeList) applyCommitment(c *wire.FinalCommitment) error {
+	if c.IsNull() {
+		return nil
+	}
+	members, ok := l.QuorumMembers(c.LLMQType, c.QuorumHash)
+	if !ok {
+		return fmt.Errorf("members of quorum %v of type %d are unknown", c.QuorumHash, c.LLMQType)
+	}
+	if len(c.ValidMembers) < len(members) {
+		return fmt.Errorf("commitment of quorum %v has %d valid members bits for %d members",
+			c.QuorumHash, len(c.ValidMembers), len(members))
+	}
+	for i, proTxHash := range members {
+		if _, ok := l.mns[proTxHash]; !ok || c.ValidMembers[i] {
+			continue
+		}
+		if err := l.PoSePunish(proTxHash, int32(l.ValidCount())*66/100); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package evo
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/llmq"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// payloadTx wraps a special transaction payload in a transaction of the
+// given version, spending prevOut.
+func payloadTx(t *testing.T, version int32, p wire.Payload, prevOut btcwire.OutPoint) *btcwire.MsgTx {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(buf.Bytes()).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := btcwire.NewMsgTx(version)
+	tx.AddTxIn(btcwire.NewTxIn(&prevOut, nil, nil))
+	tx.AddTxOut(btcwire.NewTxOut(100000*1e8, []byte{txscript.OP_TRUE}))
+	tx.AddTxOut(btcwire.NewTxOut(0, script))
+	return tx
+}
+
+type chain struct {
+	tip chainhash.Hash
+}
+
+func (c *chain) block(txs ...*btcwire.MsgTx) *btcwire.MsgBlock {
+	coinbase := btcwire.NewMsgTx(2)
+	coinbase.AddTxIn(btcwire.NewTxIn(&btcwire.OutPoint{Index: ^uint32(0)}, []byte{byte(len(txs))}, nil))
+	coinbase.AddTxOut(btcwire.NewTxOut(0, []byte{txscript.OP_TRUE}))
+	b := btcwire.NewMsgBlock(btcwire.NewBlockHeader(1, &c.tip, &chainhash.Hash{}, 0, 0))
+	b.AddTransaction(coinbase)
+	for _, tx := range txs {
+		b.AddTransaction(tx)
+	}
+	c.tip = b.BlockHash()
+	return b
+}
+
+func keyID(b byte) (id [wire.KEY_ID_SIZE]byte) {
+	id[0] = b
+	return id
+}
+
+func blsKey(b byte) (k [wire.BLS_PUBKEY_SIZE]byte) {
+	k[0] = b
+	return k
+}
+
+func proRegTx(n byte) *wire.ProRegTx {
+	return &wire.ProRegTx{
+		Version:            wire.BASIC_BLS_VERSION,
+		CollateralOutpoint: btcwire.OutPoint{Index: 0},
+		Addr:               wire.NetService{IP: net.ParseIP("10.0.0.1").To16(), Port: 8369},
+		KeyIDOwner:         keyID(n),
+		PubKeyOperator:     blsKey(n),
+		KeyIDVoting:        keyID(n),
+		ScriptPayout:       []byte{txscript.OP_TRUE},
+	}
+}
+
+func TestMasternodeListLifecycle(t *testing.T) {
+	var c chain
+	list := NewMasternodeList(100)
+
+	reg1 := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, proRegTx(1), btcwire.OutPoint{Index: 1})
+	reg2 := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, proRegTx(2), btcwire.OutPoint{Index: 2})
+	if err := list.ApplyBlock(c.block(reg1, reg2)); err != nil {
+		t.Fatal(err)
+	}
+	if list.Count() != 2 || list.Height != 100 {
+		t.Fatalf("got %d masternodes at height %d", list.Count(), list.Height)
+	}
+	mn1Hash := reg1.TxHash()
+	mn1, ok := list.Get(mn1Hash)
+	if !ok || mn1.CollateralOutpoint != (btcwire.OutPoint{Hash: mn1Hash, Index: 0}) || mn1.CollateralHeight != 100 {
+		t.Fatalf("unexpected masternode %+v", mn1)
+	}
+
+	// Registering a second masternode with the same owner key fails and
+	// leaves the list untouched.
+	dup := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, proRegTx(1), btcwire.OutPoint{Index: 3})
+	if err := list.ApplyBlock(c.block(dup)); err == nil {
+		t.Fatal("expected duplicate owner key to be rejected")
+	}
+	c.tip = list.BlockHash
+
+	before := list.Snapshot()
+	nevmAddr := bytes.Repeat([]byte{0xab}, 20)
+	upServ := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_UPDATE_SERVICE, &wire.ProUpServTx{
+		Version:     wire.BASIC_BLS_VERSION,
+		ProTxHash:   mn1Hash,
+		Addr:        wire.NetService{IP: net.ParseIP("10.0.0.9").To16(), Port: 8369},
+		NEVMAddress: nevmAddr,
+	}, btcwire.OutPoint{Index: 4})
+	nevm := &wire.NEVMBlockWire{Diff: wire.NEVMAddressDiff{
+		AddedMNNEVM: []wire.NEVMAddressEntry{{Address: nevmAddr, CollateralHeight: 100}},
+	}}
+	block := c.block(upServ)
+	blockHash := block.BlockHash()
+	nevm.SYSBlockHash = blockHash[:]
+	if err := list.ApplyNEVMBlock(block, nevm); err != nil {
+		t.Fatal(err)
+	}
+	if got := list.NEVMAddresses(); len(got) != 1 || got[string(nevmAddr)] != 100 {
+		t.Fatalf("NEVMAddresses = %v", got)
+	}
+	if _, ok := before.NEVMAddresses()[string(nevmAddr)]; ok {
+		t.Fatal("snapshot changed after ApplyBlock")
+	}
+	diff := before.Diff(list)
+	if len(diff.Added) != 0 || len(diff.Updated) != 1 || len(diff.Removed) != 0 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	// Revoking the operator clears the NEVM address and bans the node.
+	before = list.Snapshot()
+	revoke := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_UPDATE_REVOKE, &wire.ProUpRevTx{
+		Version: wire.BASIC_BLS_VERSION, ProTxHash: mn1Hash, Reason: 1,
+	}, btcwire.OutPoint{Index: 5})
+	bad := &wire.NEVMBlockWire{}
+	block = c.block(revoke)
+	blockHash = block.BlockHash()
+	bad.SYSBlockHash = blockHash[:]
+	if err := list.ApplyNEVMBlock(block, bad); err == nil {
+		t.Fatal("expected missing NEVM removal to be reported")
+	}
+	good := &wire.NEVMBlockWire{SYSBlockHash: blockHash[:], Diff: wire.NEVMAddressDiff{
+		RemovedMNNEVM: []wire.NEVMRemoveEntry{{Address: nevmAddr}},
+	}}
+	if err := list.ApplyNEVMBlock(block, good); err != nil {
+		t.Fatal(err)
+	}
+	mn1, _ = list.Get(mn1Hash)
+	if !mn1.IsPoSeBanned() || mn1.RevocationReason != 1 || list.ValidCount() != 1 {
+		t.Fatalf("revoked masternode not banned: %+v", mn1)
+	}
+
+	// Spending the collateral removes the masternode.
+	spend := btcwire.NewMsgTx(2)
+	spend.AddTxIn(btcwire.NewTxIn(&mn1.CollateralOutpoint, nil, nil))
+	spend.AddTxOut(btcwire.NewTxOut(1, []byte{txscript.OP_TRUE}))
+	before = list.Snapshot()
+	if err := list.ApplyBlock(c.block(spend)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := list.Get(mn1Hash); ok || list.Count() != 1 {
+		t.Fatal("masternode not removed after collateral spend")
+	}
+	if diff := before.Diff(list); len(diff.Removed) != 1 || diff.Removed[0] != mn1Hash {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+}
+
+func TestMasternodeListRejectsGap(t *testing.T) {
+	var c chain
+	list := NewMasternodeList(1)
+	if err := list.ApplyBlock(c.block()); err != nil {
+		t.Fatal(err)
+	}
+	c.block()
+	if err := list.ApplyBlock(c.block()); err == nil {
+		t.Fatal("expected a block that skips the tip to be rejected")
+	}
+}
+
+func TestPoSePunish(t *testing.T) {
+	var c chain
+	list := NewMasternodeList(1)
+	reg1 := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, proRegTx(1), btcwire.OutPoint{Index: 1})
+	reg2 := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, proRegTx(2), btcwire.OutPoint{Index: 2})
+	if err := list.ApplyBlock(c.block(reg1, reg2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.PoSePunish(reg1.TxHash(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.ApplyBlock(c.block()); err != nil {
+		t.Fatal(err)
+	}
+	mn, _ := list.Get(reg1.TxHash())
+	if mn.PoSePenalty != 0 || mn.IsPoSeBanned() {
+		t.Fatalf("penalty not decreased: %+v", mn)
+	}
+	if err := list.PoSePunish(reg1.TxHash(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if mn, _ = list.Get(reg1.TxHash()); !mn.IsPoSeBanned() {
+		t.Fatalf("masternode at max penalty not banned: %+v", mn)
+	}
+}
+
+func TestMasternodeListCollateralOrder(t *testing.T) {
+	var c chain
+	list := NewMasternodeList(1)
+	collateral := btcwire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 7}
+	p := proRegTx(1)
+	p.CollateralOutpoint = collateral
+	reg1 := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, p, btcwire.OutPoint{Index: 1})
+	if err := list.ApplyBlock(c.block(reg1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Registering the same external collateral again replaces the old
+	// masternode.
+	p = proRegTx(2)
+	p.CollateralOutpoint = collateral
+	reg2 := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, p, btcwire.OutPoint{Index: 2})
+	if err := list.ApplyBlock(c.block(reg2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := list.Get(reg1.TxHash()); ok || list.Count() != 1 {
+		t.Fatal("old masternode not replaced")
+	}
+	if mn, ok := list.GetByCollateral(collateral); !ok || mn.ProTxHash != reg2.TxHash() || mn.RegisteredHeight != 2 {
+		t.Fatalf("unexpected masternode %+v", mn)
+	}
+
+	// A block that spends the collateral before updating the masternode
+	// still applies the update; the masternode goes away afterwards.
+	spend := btcwire.NewMsgTx(2)
+	spend.AddTxIn(btcwire.NewTxIn(&collateral, nil, nil))
+	spend.AddTxOut(btcwire.NewTxOut(1, []byte{txscript.OP_TRUE}))
+	upServ := payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_UPDATE_SERVICE, &wire.ProUpServTx{
+		Version:   wire.BASIC_BLS_VERSION,
+		ProTxHash: reg2.TxHash(),
+		Addr:      wire.NetService{IP: net.ParseIP("10.0.0.9").To16(), Port: 8369},
+	}, btcwire.OutPoint{Index: 3})
+	if err := list.ApplyBlock(c.block(spend, upServ)); err != nil {
+		t.Fatal(err)
+	}
+	if list.Count() != 0 {
+		t.Fatal("masternode not removed after collateral spend")
+	}
+}
+
+func TestQuorumCommitmentPoSe(t *testing.T) {
+	var c chain
+	list := NewMasternodeList(1)
+	list.MinConfirmations = 1
+	var regs []*btcwire.MsgTx
+	for i := byte(1); i <= 4; i++ {
+		regs = append(regs, payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_REGISTER, proRegTx(i), btcwire.OutPoint{Index: uint32(i)}))
+	}
+	if err := list.ApplyBlock(c.block(regs...)); err != nil {
+		t.Fatal(err)
+	}
+	if got := list.CalculateQuorum(llmq.LLMQ_TEST, 3); len(got) != 0 {
+		t.Fatalf("unconfirmed masternodes selected: %v", got)
+	}
+	for i := 0; i < 2; i++ {
+		if err := list.ApplyBlock(c.block()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mn, _ := list.Get(regs[0].TxHash())
+	if mn.ConfirmedHash == (chainhash.Hash{}) {
+		t.Fatal("masternode not confirmed")
+	}
+
+	base := list.Snapshot()
+	members := base.CalculateQuorum(llmq.LLMQ_TEST, 3)
+	if len(members) != 3 {
+		t.Fatalf("got %d members, want 3", len(members))
+	}
+	all := base.CalculateQuorum(llmq.LLMQ_TEST, 10)
+	if len(all) != 4 || all[0] != members[0] || all[1] != members[1] || all[2] != members[2] {
+		t.Fatalf("quorum of 3 %v is not a prefix of %v", members, all)
+	}
+
+	commitment := func() *btcwire.MsgTx {
+		return payloadTx(t, wire.SYSCOIN_TX_VERSION_MN_QUORUM_COMMITMENT, &wire.FinalCommitmentTxPayload{
+			Version: 1,
+			Height:  uint32(list.Height + 1),
+			Commitment: wire.FinalCommitment{
+				Version:      1,
+				LLMQType:     llmq.LLMQ_TEST,
+				QuorumHash:   base.BlockHash,
+				Signers:      []bool{true, true, false},
+				ValidMembers: []bool{true, true, false},
+			},
+		}, btcwire.OutPoint{Index: uint32(list.Height)})
+	}
+	// Without QuorumMembers commitments are ignored.
+	if err := list.ApplyBlock(c.block(commitment())); err != nil {
+		t.Fatal(err)
+	}
+	if mn, _ := list.Get(members[2]); mn.PoSePenalty != 0 {
+		t.Fatalf("penalty applied without quorum members: %+v", mn)
+	}
+
+	list.QuorumMembers = func(llmqType uint8, quorumHash chainhash.Hash) ([]chainhash.Hash, bool) {
+		if llmqType != llmq.LLMQ_TEST || quorumHash != base.BlockHash {
+			return nil, false
+		}
+		return members, true
+	}
+	if err := list.ApplyBlock(c.block(commitment())); err != nil {
+		t.Fatal(err)
+	}
+	for i, h := range members {
+		mn, _ := list.Get(h)
+		if want := map[bool]int32{true: 2, false: 0}[i == 2]; mn.PoSePenalty != want {
+			t.Errorf("member %d has penalty %d, want %d", i, mn.PoSePenalty, want)
+		}
+	}
+	members = all
+	if err := list.ApplyBlock(c.block(commitment())); err == nil {
+		t.Fatal("expected a commitment with too few members bits to be rejected")
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package evo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// CalculateQuorum returns the ProTxHashes of the members of the quorum of
+// the given type and size whose base block is the block the list is at,
+// following syscoind's CDeterministicMNList::CalculateQuorum.  Only valid,
+// confirmed masternodes take part.  They are ordered by a score derived
+// from their confirmed hash and the quorum, highest first.
+func (l *MasternodeList) CalculateQuorum(llmqType uint8, size int) []chainhash.Hash {
+	modifier := chainhash.DoubleHashH(append([]byte{llmqType}, l.BlockHash[:]...))
+
+	type scored struct {
+		score chainhash.Hash
+		mn    *Masternode
+	}
+	var scores []scored
+	for _, mn := range l.mns {
+		if mn.IsPoSeBanned() || mn.ConfirmedHash == (chainhash.Hash{}) {
+			continue
+		}
+		// The score is a single SHA256 over the double SHA256 of the
+		// ProTxHash and confirmed hash, followed by the modifier.
+		withProTx := chainhash.DoubleHashH(append(mn.ProTxHash[:], mn.ConfirmedHash[:]...))
+		scores = append(scores, scored{
+			score: sha256.Sum256(append(withProTx[:], modifier[:]...)),
+			mn:    mn,
+		})
+	}
+	// Scores compare as little-endian 256-bit integers and collateral
+	// outpoints as syscoind's COutPoint, both descending.
+	sort.Slice(scores, func(i, j int) bool {
+		if c := compareUint256(scores[i].score, scores[j].score); c != 0 {
+			return c > 0
+		}
+		a, b := scores[i].mn.CollateralOutpoint, scores[j].mn.CollateralOutpoint
+		if c := bytes.Compare(a.Hash[:], b.Hash[:]); c != 0 {
+			return c > 0
+		}
+		return a.Index > b.Index
+	})
+	if len(scores) > size {
+		scores = scores[:size]
+	}
+	members := make([]chainhash.Hash, len(scores))
+	for i, s := range scores {
+		members[i] = s.mn.ProTxHash
+	}
+	return members
+}
+
+func compareUint256(a, b chainhash.Hash) int {
+	for i := chainhash.HashSize - 1; i >= 0; i-- {
+		if a[i] != b[i] {
+			if a[i] > b[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
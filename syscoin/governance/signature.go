@@ -0,0 +1,27 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package governance
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/syscoin/syscoinwire/syscoin/llmq"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// SignatureVerifier returns a function that checks BLS signatures by the
+// masternode operator key pubKey, in the form
+// wire.GovernanceObject.CheckSignature expects.
+func SignatureVerifier(pubKey [wire.BLS_PUBKEY_SIZE]byte) func(hash chainhash.Hash, sig []byte) error {
+	return func(hash chainhash.Hash, sig []byte) error {
+		if len(sig) != wire.BLS_SIGNATURE_SIZE {
+			return fmt.Errorf("BLS signature has %d bytes, want %d", len(sig), wire.BLS_SIGNATURE_SIZE)
+		}
+		var s [wire.BLS_SIGNATURE_SIZE]byte
+		copy(s[:], sig)
+		return llmq.VerifySignature(pubKey, hash, s)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package governance computes the treasury payments of Syscoin superblocks
+// from governance triggers and checks the BLS signatures of governance
+// objects.
+package governance
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// Payment is one treasury payment of a superblock.
+type Payment struct {
+	Address      string
+	Amount       int64
+	ProposalHash chainhash.Hash
+}
+
+// IsSuperblockHeight reports whether a superblock may be created at height
+// for the network's superblock start block and cycle length in blocks, as
+// syscoind's CSuperblock::IsValidBlockHeight does.
+func IsSuperblockHeight(height, startBlock, cycle int32) bool {
+	return cycle > 0 && height >= startBlock && height%cycle == 0
+}
+
+// SuperblockPayments returns the payments the superblock triggered by
+// trigger must make.  limit is the superblock's payment limit in satoshis
+// for the network and height; the payments may not add up to more.
+func SuperblockPayments(trigger *wire.TriggerData, limit int64) ([]Payment, error) {
+	if trigger.Type != wire.GOVERNANCE_OBJECT_TRIGGER {
+		return nil, fmt.Errorf("governance data of type %d is not a trigger", trigger.Type)
+	}
+	n := len(trigger.PaymentAddresses)
+	if len(trigger.PaymentAmounts) != n || len(trigger.ProposalHashes) != n {
+		return nil, fmt.Errorf("trigger lists %d addresses, %d amounts and %d proposals",
+			n, len(trigger.PaymentAmounts), len(trigger.ProposalHashes))
+	}
+	payments := make([]Payment, n)
+	var total int64
+	for i := range payments {
+		amount := trigger.PaymentAmounts[i]
+		if amount <= 0 {
+			return nil, fmt.Errorf("payment %d has non-positive amount %d", i, amount)
+		}
+		total += amount
+		if total > limit {
+			return nil, fmt.Errorf("payments total more than the superblock limit of %d", limit)
+		}
+		payments[i] = Payment{
+			Address:      trigger.PaymentAddresses[i],
+			Amount:       amount,
+			ProposalHash: trigger.ProposalHashes[i],
+		}
+	}
+	return payments, nil
+}
+
+// CheckSuperblockCoinbase verifies that coinbase pays every payment.
+// payToScript converts a payment address to its output script.  Outputs are
+// matched one to one, so two identical payments need two outputs.
+func CheckSuperblockCoinbase(coinbase *btcwire.MsgTx, payments []Payment, payToScript func(address string) ([]byte, error)) error {
+	used := make([]bool, len(coinbase.TxOut))
+	for i, p := range payments {
+		script, err := payToScript(p.Address)
+		if err != nil {
+			return fmt.Errorf("payment %d: %v", i, err)
+		}
+		found := false
+		for j, out := range coinbase.TxOut {
+			if !used[j] && out.Value == p.Amount && bytes.Equal(out.PkScript, script) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("coinbase %v is missing payment of %d to %s for proposal %v",
+				coinbase.TxHash(), p.Amount, p.Address, p.ProposalHash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package governance
+
+import (
+	"crypto/rand"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/llmq"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+func testPayToScript(address string) ([]byte, error) {
+	return []byte(address), nil
+}
+
+func TestSuperblockPayments(t *testing.T) {
+	trigger := &wire.TriggerData{
+		Type:             wire.GOVERNANCE_OBJECT_TRIGGER,
+		EventBlockHeight: 175200,
+		PaymentAddresses: []string{"a", "b", "a"},
+		PaymentAmounts:   []int64{10 * wire.COIN, 5 * wire.COIN, 10 * wire.COIN},
+		ProposalHashes:   []chainhash.Hash{{1}, {2}, {3}},
+	}
+	payments, err := SuperblockPayments(trigger, 25*wire.COIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 3 || payments[1] != (Payment{"b", 5 * wire.COIN, chainhash.Hash{2}}) {
+		t.Fatalf("got %+v", payments)
+	}
+	if _, err := SuperblockPayments(trigger, 25*wire.COIN-1); err == nil {
+		t.Error("expected payments over the limit to be rejected")
+	}
+
+	coinbase := btcwire.NewMsgTx(1)
+	coinbase.AddTxOut(btcwire.NewTxOut(10*wire.COIN, []byte("a")))
+	coinbase.AddTxOut(btcwire.NewTxOut(5*wire.COIN, []byte("b")))
+	if err := CheckSuperblockCoinbase(coinbase, payments, testPayToScript); err == nil {
+		t.Error("expected one output not to pay two identical payments")
+	}
+	coinbase.AddTxOut(btcwire.NewTxOut(10*wire.COIN, []byte("a")))
+	if err := CheckSuperblockCoinbase(coinbase, payments, testPayToScript); err != nil {
+		t.Error(err)
+	}
+
+	bad := *trigger
+	bad.PaymentAmounts = bad.PaymentAmounts[:2]
+	if _, err := SuperblockPayments(&bad, math.MaxInt64); err == nil {
+		t.Error("expected mismatched list lengths to be rejected")
+	}
+	bad = *trigger
+	bad.PaymentAmounts = []int64{1, 0, 1}
+	if _, err := SuperblockPayments(&bad, math.MaxInt64); err == nil {
+		t.Error("expected a zero payment to be rejected")
+	}
+	bad = *trigger
+	bad.Type = wire.GOVERNANCE_OBJECT_PROPOSAL
+	if _, err := SuperblockPayments(&bad, math.MaxInt64); err == nil {
+		t.Error("expected a proposal to be rejected")
+	}
+}
+
+func TestIsSuperblockHeight(t *testing.T) {
+	tests := []struct {
+		height, start, cycle int32
+		want                 bool
+	}{
+		{175200, 0, 17520, true},
+		{175201, 0, 17520, false},
+		{175200, 175200, 17520, true},
+		{157680, 175200, 17520, false},
+		{0, 0, 0, false},
+	}
+	for _, test := range tests {
+		if got := IsSuperblockHeight(test.height, test.start, test.cycle); got != test.want {
+			t.Errorf("IsSuperblockHeight(%d, %d, %d) = %v", test.height, test.start, test.cycle, got)
+		}
+	}
+}
+
+func TestTriggerSignature(t *testing.T) {
+	sk, err := llmq.GenerateSecretKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger := &wire.GovernanceObject{
+		Revision: 1,
+		Time:     1700000000,
+		Data:     []byte(`{"type":2,"event_block_height":175200}`),
+		Type:     wire.GOVERNANCE_OBJECT_TRIGGER,
+	}
+	sig, err := sk.Sign(trigger.SignatureHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigBytes := sig.Bytes()
+	trigger.Sig = sigBytes[:]
+	verify := SignatureVerifier(sk.PublicKey().Bytes())
+	if err := trigger.CheckSignature(verify); err != nil {
+		t.Fatal(err)
+	}
+	trigger.Time++
+	if err := trigger.CheckSignature(verify); !errors.Is(err, llmq.ErrInvalidSignature) {
+		t.Errorf("got %v, want ErrInvalidSignature", err)
+	}
+	trigger.Sig = sigBytes[:10]
+	if err := trigger.CheckSignature(verify); err == nil {
+		t.Error("expected a short signature to be rejected")
+	}
+}
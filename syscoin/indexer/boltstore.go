@@ -0,0 +1,95 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexer
+
+import (
+	"bytes"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket is the bucket BoltStore keeps every key in.
+var boltBucket = []byte("indexer")
+
+// BoltStore is a KVStore backed by a bbolt database file.  Unlike FileStore
+// it keeps its data on disk, pages it in on demand and opens without
+// replaying a log, so it suits a full mainnet index.  Every batch is one
+// bbolt transaction, synced before Write returns.  It is safe for
+// concurrent use.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the bbolt database at path, creating it if needed.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o644, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Get implements KVStore.  The returned slice is a copy, since bbolt's own
+// memory is only valid inside a transaction.
+func (s *BoltStore) Get(key []byte) ([]byte, error) {
+	var v []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, val := tx.Bucket(boltBucket).Cursor().Seek(key)
+		if k == nil || !bytes.Equal(k, key) {
+			return ErrNotFound
+		}
+		v = append([]byte{}, val...)
+		return nil
+	})
+	return v, err
+}
+
+// Iterate implements KVStore.  fn is passed copies of the keys and values.
+func (s *BoltStore) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if err := fn(append([]byte(nil), k...), append([]byte{}, v...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Write implements KVStore.
+func (s *BoltStore) Write(b *Batch) error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, op := range b.ops {
+			var err error
+			if op.value == nil {
+				err = bucket.Delete(op.key)
+			} else {
+				err = bucket.Put(op.key, op.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close implements KVStore.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fileStoreMaxRecord bounds the payload size of one record, so that a
+// corrupted length cannot make Open allocate arbitrary memory.  Write
+// rejects larger batches and Compact splits its snapshot to stay below it.
+const fileStoreMaxRecord = 1 << 30
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FileStore is a KVStore persisted to a single append-only file.  Every
+// batch is appended as one checksummed record and synced before Write
+// returns.  A record torn by a crash is discarded when the file is reopened,
+// so a batch is either fully applied or not at all.  A bad record anywhere
+// but at the end of the file is reported as corruption instead.  It is safe for
+// concurrent use.
+//
+// FileStore is meant for tests and small deployments such as regtest or a
+// wallet's own outputs, not for indexing mainnet.  The whole key space is
+// held in memory for reads, the log grows with every batch until Compact is
+// called, and opening the store replays the entire log.  A full index should
+// use BoltStore instead.
+type FileStore struct {
+	MemStore
+	path string
+	f    *os.File
+	size int64
+}
+
+// OpenFileStore opens the store at path, creating it if needed, and loads
+// its contents.
+func OpenFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{
+		MemStore: MemStore{data: make(map[string][]byte)},
+		path:     path,
+		f:        f,
+	}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// load replays the records of the file and truncates a torn final record.
+func (s *FileStore) load() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(s.f)
+	var off int64
+	for {
+		b, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A crash during Write can only damage the last record,
+			// which reaches the end of the file and whose Write never
+			// returned.  A bad record followed by more data is
+			// corruption that truncating would turn into data loss.
+			if off+n < info.Size() {
+				return fmt.Errorf("record at offset %d: %v", off, err)
+			}
+			if err := s.f.Truncate(off); err != nil {
+				return err
+			}
+			break
+		}
+		s.apply(b)
+		off += n
+	}
+	s.size = off
+	_, err = s.f.Seek(off, io.SeekStart)
+	return err
+}
+
+// readRecord reads one record, returning its batch and encoded length.  On
+// error the length is the one the record header claims, if it was read.
+func readRecord(r io.Reader) (*Batch, int64, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, int64(len(hdr)), errors.New("torn record header")
+		}
+		return nil, 0, err
+	}
+	length := binary.LittleEndian.Uint32(hdr[:4])
+	n := int64(len(hdr)) + int64(length)
+	if length > fileStoreMaxRecord {
+		return nil, n, fmt.Errorf("record of %d bytes is too large", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, n, errors.New("torn record")
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(hdr[4:]) {
+		return nil, n, errors.New("record checksum mismatch")
+	}
+	b, err := decodeBatch(payload)
+	if err != nil {
+		return nil, n, err
+	}
+	return b, n, nil
+}
+
+// opSize returns the number of bytes encodeRecord uses for op.
+func opSize(op batchOp) int {
+	n := uvarintSize(uint64(len(op.key))) + len(op.key) + 1
+	if op.value != nil {
+		n += uvarintSize(uint64(len(op.value))) + len(op.value)
+	}
+	return n
+}
+
+func uvarintSize(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+// splitOps groups ops into batches whose record payloads are at most limit
+// bytes.  An operation that alone exceeds limit gets a batch of its own.
+func splitOps(ops []batchOp, limit int) []*Batch {
+	var batches []*Batch
+	cur, size := &Batch{}, binary.MaxVarintLen64
+	for _, op := range ops {
+		n := opSize(op)
+		if len(cur.ops) > 0 && size+n > limit {
+			batches = append(batches, cur)
+			cur, size = &Batch{}, binary.MaxVarintLen64
+		}
+		cur.ops = append(cur.ops, op)
+		size += n
+	}
+	if len(cur.ops) > 0 {
+		batches = append(batches, cur)
+	}
+	return batches
+}
+
+// encodeRecord encodes b as a record: the payload length and CRC-32C
+// followed by the operation count and, per operation, the key, a put flag
+// and for puts the value, all lengths as uvarints.
+func encodeRecord(b *Batch) []byte {
+	rec := make([]byte, 8, 64)
+	rec = binary.AppendUvarint(rec, uint64(len(b.ops)))
+	for _, op := range b.ops {
+		rec = binary.AppendUvarint(rec, uint64(len(op.key)))
+		rec = append(rec, op.key...)
+		if op.value == nil {
+			rec = append(rec, 0)
+			continue
+		}
+		rec = append(rec, 1)
+		rec = binary.AppendUvarint(rec, uint64(len(op.value)))
+		rec = append(rec, op.value...)
+	}
+	payload := rec[8:]
+	binary.LittleEndian.PutUint32(rec[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(rec[4:8], crc32.Checksum(payload, crcTable))
+	return rec
+}
+
+func decodeBatch(p []byte) (*Batch, error) {
+	errMalformed := errors.New("malformed record")
+	next := func(n uint64) ([]byte, error) {
+		if n > uint64(len(p)) {
+			return nil, errMalformed
+		}
+		v := p[:n:n]
+		p = p[n:]
+		return v, nil
+	}
+	uvarint := func() (uint64, error) {
+		v, n := binary.Uvarint(p)
+		if n <= 0 {
+			return 0, errMalformed
+		}
+		p = p[n:]
+		return v, nil
+	}
+
+	count, err := uvarint()
+	if err != nil {
+		return nil, err
+	}
+	b := &Batch{}
+	for i := uint64(0); i < count; i++ {
+		n, err := uvarint()
+		if err != nil {
+			return nil, err
+		}
+		key, err := next(n)
+		if err != nil {
+			return nil, err
+		}
+		flag, err := next(1)
+		if err != nil {
+			return nil, err
+		}
+		op := batchOp{key: key}
+		if flag[0] == 1 {
+			if n, err = uvarint(); err != nil {
+				return nil, err
+			}
+			if op.value, err = next(n); err != nil {
+				return nil, err
+			}
+		} else if flag[0] != 0 {
+			return nil, errMalformed
+		}
+		b.ops = append(b.ops, op)
+	}
+	if len(p) != 0 {
+		return nil, errMalformed
+	}
+	return b, nil
+}
+
+// Write implements KVStore.
+func (s *FileStore) Write(b *Batch) error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	rec := encodeRecord(b)
+	if len(rec)-8 > fileStoreMaxRecord {
+		return fmt.Errorf("batch of %d bytes exceeds the record limit of %d bytes",
+			len(rec)-8, fileStoreMaxRecord)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.f == nil {
+		return errors.New("file store is closed")
+	}
+	_, err := s.f.Write(rec)
+	if err == nil {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		// Drop the record, whole or partial, so that the file keeps
+		// matching the in-memory state and later records stay readable.
+		s.f.Truncate(s.size)
+		s.f.Seek(s.size, io.SeekStart)
+		return err
+	}
+	s.size += int64(len(rec))
+	s.apply(b)
+	return nil
+}
+
+// Compact rewrites the file to hold only the current contents, replacing it
+// atomically.  Large stores are written as several records.
+func (s *FileStore) Compact() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.f == nil {
+		return errors.New("file store is closed")
+	}
+	ops := make([]batchOp, 0, len(s.data))
+	for k, v := range s.data {
+		ops = append(ops, batchOp{key: []byte(k), value: v})
+	}
+
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var size int64
+	for _, b := range splitOps(ops, fileStoreMaxRecord) {
+		rec := encodeRecord(b)
+		if _, err := w.Write(rec); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+		size += int64(len(rec))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(s.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	s.f.Close()
+	s.f = f
+	s.size = size
+	return nil
+}
+
+// Close implements KVStore.
+func (s *FileStore) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
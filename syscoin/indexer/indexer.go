@@ -0,0 +1,459 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package indexer maintains an index of Syscoin's unspent outputs with the
+// assets they carry, together with per-address and per-asset balances.
+// Blocks are connected and disconnected in chain order, so the index follows
+// reorganizations.  State lives in a KVStore; MemStore and FileStore are
+// provided for tests and small deployments, and a disk-backed store can be
+// plugged in for a full index.
+package indexer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// UTXO is an unspent output and the asset it carries, if any.
+type UTXO struct {
+	Value    int64
+	PkScript []byte
+	Height   int32
+	Coinbase bool
+
+	HasAsset    bool
+	AssetGuid   uint64
+	AssetAmount wire.AssetAmount
+}
+
+// ScriptHexAddress is the default address function: it keys balances by the
+// hex-encoded output script.
+func ScriptHexAddress(pkScript []byte) string {
+	return hex.EncodeToString(pkScript)
+}
+
+// Config configures an Indexer.
+type Config struct {
+	// Store holds the index.
+	Store KVStore
+
+	// AddressOf maps an output script to the address its balances are
+	// credited to.  An empty address leaves the output out of address
+	// balances.  It defaults to ScriptHexAddress; chaincfg.AddressFunc
+	// keys balances by Syscoin address instead.  It must not change once
+	// the store holds data.
+	AddressOf func(pkScript []byte) string
+}
+
+// Indexer maintains the asset UTXO index.  Blocks must be connected in
+// chain order starting from a block whose inputs are all indexed, normally
+// the genesis block.  It is safe for concurrent use.
+type Indexer struct {
+	mtx       sync.Mutex
+	store     KVStore
+	addressOf func(pkScript []byte) string
+}
+
+// New returns an indexer using cfg.
+func New(cfg Config) (*Indexer, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("indexer: no store configured")
+	}
+	addressOf := cfg.AddressOf
+	if addressOf == nil {
+		addressOf = ScriptHexAddress
+	}
+	return &Indexer{store: cfg.Store, addressOf: addressOf}, nil
+}
+
+// Tip returns the hash and height of the last connected block.  ok is false
+// when no block has been connected.
+func (ix *Indexer) Tip() (hash chainhash.Hash, height int32, ok bool, err error) {
+	b, err := ix.store.Get([]byte{prefixTip})
+	if errors.Is(err, ErrNotFound) {
+		return hash, 0, false, nil
+	}
+	if err != nil {
+		return hash, 0, false, err
+	}
+	if len(b) != chainhash.HashSize+4 {
+		return hash, 0, false, errMalformedRecord
+	}
+	copy(hash[:], b)
+	return hash, int32(binary.LittleEndian.Uint32(b[chainhash.HashSize:])), true, nil
+}
+
+func tipRecord(hash chainhash.Hash, height int32) []byte {
+	return binary.LittleEndian.AppendUint32(append([]byte(nil), hash[:]...), uint32(height))
+}
+
+// UTXO returns the unspent output op, or ErrNotFound.
+func (ix *Indexer) UTXO(op btcwire.OutPoint) (*UTXO, error) {
+	b, err := ix.store.Get(utxoKey(op))
+	if err != nil {
+		return nil, err
+	}
+	u, rest, err := readUTXO(b)
+	if err == nil && len(rest) != 0 {
+		err = errMalformedRecord
+	}
+	return u, err
+}
+
+// AddressBalance returns the SYS balance of addr in satoshis and its asset
+// balances.
+func (ix *Indexer) AddressBalance(addr string) (int64, wire.AssetBalances, error) {
+	sys, err := ix.readInt64(addressKey(prefixSysBalance, addr))
+	if err != nil {
+		return 0, nil, err
+	}
+	assets := make(wire.AssetBalances)
+	prefix := addressKey(prefixAssetBalance, addr)
+	err = ix.store.Iterate(prefix, func(key, value []byte) error {
+		if len(key) != len(prefix)+8 {
+			return errMalformedRecord
+		}
+		v, err := decodeInt64(value)
+		if err != nil {
+			return err
+		}
+		assets[binary.BigEndian.Uint64(key[len(prefix):])] = wire.AssetAmount(v)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return sys, assets, nil
+}
+
+// AssetSupply returns the amount of the asset held in unspent outputs.
+func (ix *Indexer) AssetSupply(guid uint64) (wire.AssetAmount, error) {
+	v, err := ix.readInt64(supplyKey(guid))
+	return wire.AssetAmount(v), err
+}
+
+// readInt64 reads an integer record, treating a missing one as zero.
+func (ix *Indexer) readInt64(key []byte) (int64, error) {
+	b, err := ix.store.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return decodeInt64(b)
+}
+
+// ConnectBlock indexes block, which must extend the tip at the given
+// height.  Asset transactions are decoded and checked with
+// wire.CheckAllocationConservation; a block that violates the rules is
+// rejected and leaves the index unchanged.
+func (ix *Indexer) ConnectBlock(block *btcwire.MsgBlock, height int32) error {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+
+	blockHash := block.BlockHash()
+	tip, tipHeight, ok, err := ix.Tip()
+	if err != nil {
+		return err
+	}
+	if ok && (block.Header.PrevBlock != tip || height != tipHeight+1) {
+		return fmt.Errorf("block %v at height %d does not extend tip %v at height %d",
+			blockHash, height, tip, tipHeight)
+	}
+
+	v := ix.newView()
+	var spent []spentOutput
+	for _, tx := range block.Transactions {
+		s, err := v.connectTx(tx, height)
+		if err != nil {
+			return fmt.Errorf("block %v: tx %v: %w", blockHash, tx.TxHash(), err)
+		}
+		spent = append(spent, s...)
+	}
+
+	b := &Batch{}
+	if err := v.flush(b); err != nil {
+		return fmt.Errorf("block %v: %w", blockHash, err)
+	}
+	b.Put(undoKey(blockHash), encodeUndo(spent))
+	b.Put([]byte{prefixTip}, tipRecord(blockHash, height))
+	return ix.store.Write(b)
+}
+
+// DisconnectBlock removes block, which must be the tip, from the index and
+// restores the outputs it spent.
+func (ix *Indexer) DisconnectBlock(block *btcwire.MsgBlock) error {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+
+	blockHash := block.BlockHash()
+	tip, tipHeight, ok, err := ix.Tip()
+	if err != nil {
+		return err
+	}
+	if !ok || tip != blockHash {
+		return fmt.Errorf("block %v is not the tip", blockHash)
+	}
+	undo, err := ix.store.Get(undoKey(blockHash))
+	if err != nil {
+		return fmt.Errorf("block %v undo data: %w", blockHash, err)
+	}
+	spent, err := decodeUndo(undo)
+	if err != nil {
+		return fmt.Errorf("block %v undo data: %w", blockHash, err)
+	}
+
+	v := ix.newView()
+	for i := len(block.Transactions) - 1; i >= 0; i-- {
+		tx := block.Transactions[i]
+		if spent, err = v.disconnectTx(tx, spent); err != nil {
+			return fmt.Errorf("block %v: tx %v: %w", blockHash, tx.TxHash(), err)
+		}
+	}
+	if len(spent) != 0 {
+		return fmt.Errorf("block %v: %d undo entries left over", blockHash, len(spent))
+	}
+
+	b := &Batch{}
+	if err := v.flush(b); err != nil {
+		return fmt.Errorf("block %v: %w", blockHash, err)
+	}
+	b.Delete(undoKey(blockHash))
+	b.Put([]byte{prefixTip}, tipRecord(block.Header.PrevBlock, tipHeight-1))
+	return ix.store.Write(b)
+}
+
+// assetKey identifies an address's balance of one asset.
+type assetKey struct {
+	addr string
+	guid uint64
+}
+
+// view holds the changes of one block on top of the store until they are
+// flushed into a batch.
+type view struct {
+	ix     *Indexer
+	utxos  map[btcwire.OutPoint]*UTXO // nil marks a spent output
+	sys    map[string]int64
+	assets map[assetKey]int64
+	supply map[uint64]int64
+}
+
+func (ix *Indexer) newView() *view {
+	return &view{
+		ix:     ix,
+		utxos:  make(map[btcwire.OutPoint]*UTXO),
+		sys:    make(map[string]int64),
+		assets: make(map[assetKey]int64),
+		supply: make(map[uint64]int64),
+	}
+}
+
+func (v *view) utxo(op btcwire.OutPoint) (*UTXO, error) {
+	if u, ok := v.utxos[op]; ok {
+		if u == nil {
+			return nil, ErrNotFound
+		}
+		return u, nil
+	}
+	return v.ix.UTXO(op)
+}
+
+// add records u as unspent and credits its balances.
+func (v *view) add(op btcwire.OutPoint, u *UTXO) {
+	v.utxos[op] = u
+	v.credit(u, 1)
+}
+
+// spend removes op and debits its balances.
+func (v *view) spend(op btcwire.OutPoint) (*UTXO, error) {
+	u, err := v.utxo(op)
+	if err != nil {
+		return nil, fmt.Errorf("output %v: %w", op, err)
+	}
+	v.utxos[op] = nil
+	v.credit(u, -1)
+	return u, nil
+}
+
+func (v *view) credit(u *UTXO, sign int64) {
+	addr := v.ix.addressOf(u.PkScript)
+	if addr != "" {
+		v.sys[addr] += sign * u.Value
+	}
+	if u.HasAsset {
+		if addr != "" {
+			v.assets[assetKey{addr, u.AssetGuid}] += sign * int64(u.AssetAmount)
+		}
+		v.supply[u.AssetGuid] += sign * int64(u.AssetAmount)
+	}
+}
+
+// connectTx spends the inputs of tx and adds its outputs, returning the
+// spent outputs.
+func (v *view) connectTx(tx *btcwire.MsgTx, height int32) ([]spentOutput, error) {
+	coinbase := isCoinBase(tx)
+	var spent []spentOutput
+	inputs := make(wire.AssetBalances)
+	if !coinbase {
+		for _, in := range tx.TxIn {
+			u, err := v.spend(in.PreviousOutPoint)
+			if err != nil {
+				return nil, err
+			}
+			spent = append(spent, spentOutput{in.PreviousOutPoint, u})
+			if u.HasAsset {
+				if err := inputs.Add(u.AssetGuid, u.AssetAmount); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var alloc *wire.AssetAllocationType
+	if wire.IsAssetTxVersion(tx.Version) {
+		p, err := wire.DecodeTxPayload(tx)
+		if err != nil {
+			return nil, err
+		}
+		if alloc, err = wire.AllocationOf(p); err != nil {
+			return nil, err
+		}
+		if _, err := wire.CheckAllocationConservation(tx, alloc, inputs); err != nil {
+			return nil, err
+		}
+	} else if len(inputs) != 0 {
+		return nil, fmt.Errorf("tx version %d spends asset outputs", tx.Version)
+	}
+
+	txHash := tx.TxHash()
+	utxos := make([]*UTXO, len(tx.TxOut))
+	for i, out := range tx.TxOut {
+		if txscript.IsUnspendable(out.PkScript) {
+			continue
+		}
+		utxos[i] = &UTXO{
+			Value:    out.Value,
+			PkScript: out.PkScript,
+			Height:   height,
+			Coinbase: coinbase,
+		}
+	}
+	if alloc != nil {
+		for _, out := range alloc.VoutAssets {
+			for _, val := range out.Values {
+				// Values assigned to the OP_RETURN output are burned.
+				if u := utxos[val.N]; u != nil {
+					u.HasAsset = true
+					u.AssetGuid = out.AssetGuid
+					u.AssetAmount = wire.AssetAmount(val.ValueSat)
+				}
+			}
+		}
+	}
+	for i, u := range utxos {
+		if u != nil {
+			v.add(btcwire.OutPoint{Hash: txHash, Index: uint32(i)}, u)
+		}
+	}
+	return spent, nil
+}
+
+// disconnectTx removes the outputs of tx and restores its inputs from the
+// tail of spent, returning the remaining undo entries.
+func (v *view) disconnectTx(tx *btcwire.MsgTx, spent []spentOutput) ([]spentOutput, error) {
+	txHash := tx.TxHash()
+	for i, out := range tx.TxOut {
+		if txscript.IsUnspendable(out.PkScript) {
+			continue
+		}
+		if _, err := v.spend(btcwire.OutPoint{Hash: txHash, Index: uint32(i)}); err != nil {
+			return nil, err
+		}
+	}
+	if isCoinBase(tx) {
+		return spent, nil
+	}
+	for i := len(tx.TxIn) - 1; i >= 0; i-- {
+		if len(spent) == 0 {
+			return nil, errors.New("undo data is missing inputs")
+		}
+		s := spent[len(spent)-1]
+		spent = spent[:len(spent)-1]
+		if s.outpoint != tx.TxIn[i].PreviousOutPoint {
+			return nil, fmt.Errorf("undo entry %v does not match input %v",
+				s.outpoint, tx.TxIn[i].PreviousOutPoint)
+		}
+		v.add(s.outpoint, s.utxo)
+	}
+	return spent, nil
+}
+
+// flush writes the view's changes to b.  Balances that would leave their
+// valid range indicate a corrupt index and are reported as errors.
+func (v *view) flush(b *Batch) error {
+	for op, u := range v.utxos {
+		if u == nil {
+			b.Delete(utxoKey(op))
+		} else {
+			b.Put(utxoKey(op), appendUTXO(nil, u))
+		}
+	}
+	for addr, delta := range v.sys {
+		if err := v.applyDelta(b, addressKey(prefixSysBalance, addr), delta, false); err != nil {
+			return fmt.Errorf("address %s: %w", addr, err)
+		}
+	}
+	for k, delta := range v.assets {
+		if err := v.applyDelta(b, assetBalanceKey(k.addr, k.guid), delta, true); err != nil {
+			return fmt.Errorf("address %s asset %d: %w", k.addr, k.guid, err)
+		}
+	}
+	for guid, delta := range v.supply {
+		if err := v.applyDelta(b, supplyKey(guid), delta, true); err != nil {
+			return fmt.Errorf("asset %d supply: %w", guid, err)
+		}
+	}
+	return nil
+}
+
+// applyDelta adds delta to the integer record at key, deleting it when it
+// reaches zero.
+func (v *view) applyDelta(b *Batch, key []byte, delta int64, asset bool) error {
+	if delta == 0 {
+		return nil
+	}
+	cur, err := v.ix.readInt64(key)
+	if err != nil {
+		return err
+	}
+	sum := cur + delta
+	if sum < 0 || (asset && !wire.AssetAmount(sum).IsValid()) {
+		return fmt.Errorf("balance %d%+d out of range", cur, delta)
+	}
+	if sum == 0 {
+		b.Delete(key)
+	} else {
+		b.Put(key, encodeInt64(sum))
+	}
+	return nil
+}
+
+// isCoinBase reports whether tx is a coinbase transaction.
+func isCoinBase(tx *btcwire.MsgTx) bool {
+	if len(tx.TxIn) != 1 {
+		return false
+	}
+	prev := tx.TxIn[0].PreviousOutPoint
+	return prev.Index == btcwire.MaxPrevOutIndex && prev.Hash == chainhash.Hash{}
+}
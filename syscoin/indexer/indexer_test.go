@@ -0,0 +1,237 @@
+package indexer
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+var (
+	scriptA = []byte{txscript.OP_DATA_1, 0xa}
+	scriptB = []byte{txscript.OP_DATA_1, 0xb}
+	scriptC = []byte{txscript.OP_DATA_1, 0xc}
+)
+
+func coinbaseTx(height int32, value int64, pkScript []byte) *btcwire.MsgTx {
+	tx := btcwire.NewMsgTx(1)
+	tx.AddTxIn(&btcwire.TxIn{
+		PreviousOutPoint: btcwire.OutPoint{Index: btcwire.MaxPrevOutIndex},
+		SignatureScript:  []byte{byte(height)},
+	})
+	tx.AddTxOut(btcwire.NewTxOut(value, pkScript))
+	return tx
+}
+
+// assetTx returns a transaction spending ins with the given outputs,
+// followed by an OP_RETURN output worth dataValue carrying p.
+func assetTx(t *testing.T, version int32, ins []btcwire.OutPoint, outs []*btcwire.TxOut, dataValue int64, p wire.Payload) *btcwire.MsgTx {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(buf.Bytes()).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := btcwire.NewMsgTx(version)
+	for _, op := range ins {
+		tx.AddTxIn(btcwire.NewTxIn(&op, nil, nil))
+	}
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	tx.AddTxOut(btcwire.NewTxOut(dataValue, script))
+	return tx
+}
+
+func newBlock(prev chainhash.Hash, txs ...*btcwire.MsgTx) *btcwire.MsgBlock {
+	block := btcwire.NewMsgBlock(btcwire.NewBlockHeader(1, &prev, &chainhash.Hash{}, 0, 0))
+	for _, tx := range txs {
+		block.AddTransaction(tx)
+	}
+	return block
+}
+
+func allocation(guid uint64, values ...btcwire.TxOut) wire.AssetAllocationType {
+	out := wire.AssetOutType{AssetGuid: guid}
+	for i, v := range values {
+		out.Values = append(out.Values, wire.AssetOutValueType{N: uint32(i), ValueSat: v.Value})
+	}
+	return wire.AssetAllocationType{VoutAssets: []wire.AssetOutType{out}}
+}
+
+type balance struct {
+	sys    int64
+	assets wire.AssetBalances
+}
+
+func checkBalances(t *testing.T, ix *Indexer, want map[string]balance, supply wire.AssetAmount) {
+	t.Helper()
+	for addr, w := range want {
+		sys, assets, err := ix.AddressBalance(ScriptHexAddress([]byte(addr)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.assets == nil {
+			w.assets = wire.AssetBalances{}
+		}
+		if sys != w.sys || !reflect.DeepEqual(assets, w.assets) {
+			t.Errorf("balance of %x = %d %v, want %d %v", addr, sys, assets, w.sys, w.assets)
+		}
+	}
+	if got, err := ix.AssetSupply(7); err != nil || got != supply {
+		t.Errorf("supply = %d, %v, want %d", got, err, supply)
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix, err := New(Config{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Block 1 pays 1000 SYS satoshis to A.
+	cb1 := coinbaseTx(1, 1000, scriptA)
+	block1 := newBlock(chainhash.Hash{}, cb1)
+	if err := ix.ConnectBlock(block1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Block 2 burns 200 of A's SYS into 200 of asset 7 paid to B.
+	burnOuts := []*btcwire.TxOut{btcwire.NewTxOut(0, scriptB), btcwire.NewTxOut(800, scriptA)}
+	mint := &wire.SyscoinBurnToEthereumType{Allocation: allocation(7, btcwire.TxOut{Value: 200})}
+	mintTx := assetTx(t, wire.SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION,
+		[]btcwire.OutPoint{{Hash: cb1.TxHash()}}, burnOuts, 200, mint)
+	block2 := newBlock(block1.BlockHash(), coinbaseTx(2, 50, scriptC), mintTx)
+	if err := ix.ConnectBlock(block2, 2); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, ix, map[string]balance{
+		string(scriptA): {sys: 800},
+		string(scriptB): {assets: wire.AssetBalances{7: 200}},
+		string(scriptC): {sys: 50},
+	}, 200)
+
+	// Block 3 sends 150 of B's asset to C and burns 30 to NEVM in a
+	// second transaction spending the first.
+	mintOut := btcwire.OutPoint{Hash: mintTx.TxHash(), Index: 0}
+	send := allocation(7, btcwire.TxOut{Value: 150}, btcwire.TxOut{Value: 50})
+	sendTx := assetTx(t, wire.SYSCOIN_TX_VERSION_ALLOCATION_SEND, []btcwire.OutPoint{mintOut},
+		[]*btcwire.TxOut{btcwire.NewTxOut(0, scriptC), btcwire.NewTxOut(0, scriptB)}, 0, &send)
+	burn := &wire.SyscoinBurnToEthereumType{
+		Allocation: allocation(7, btcwire.TxOut{Value: 20}, btcwire.TxOut{Value: 30}),
+		EthAddress: bytes.Repeat([]byte{0xee}, 20),
+	}
+	burnTx := assetTx(t, wire.SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM,
+		[]btcwire.OutPoint{{Hash: sendTx.TxHash(), Index: 1}},
+		[]*btcwire.TxOut{btcwire.NewTxOut(0, scriptB)}, 0, burn)
+	block3 := newBlock(block2.BlockHash(), coinbaseTx(3, 50, scriptC), sendTx, burnTx)
+	if err := ix.ConnectBlock(block3, 3); err != nil {
+		t.Fatal(err)
+	}
+	after3 := map[string]balance{
+		string(scriptA): {sys: 800},
+		string(scriptB): {assets: wire.AssetBalances{7: 20}},
+		string(scriptC): {sys: 100, assets: wire.AssetBalances{7: 150}},
+	}
+	checkBalances(t, ix, after3, 170)
+	if _, err := ix.UTXO(mintOut); !errors.Is(err, ErrNotFound) {
+		t.Errorf("spent output still indexed: %v", err)
+	}
+	u, err := ix.UTXO(btcwire.OutPoint{Hash: sendTx.TxHash(), Index: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &UTXO{PkScript: scriptC, Height: 3, HasAsset: true, AssetGuid: 7, AssetAmount: 150}
+	if !reflect.DeepEqual(u, want) {
+		t.Errorf("UTXO = %+v, want %+v", u, want)
+	}
+
+	// A block that creates assets out of nothing is rejected without
+	// changing the index.
+	bad := allocation(7, btcwire.TxOut{Value: 151})
+	badTx := assetTx(t, wire.SYSCOIN_TX_VERSION_ALLOCATION_SEND,
+		[]btcwire.OutPoint{{Hash: sendTx.TxHash(), Index: 0}},
+		[]*btcwire.TxOut{btcwire.NewTxOut(0, scriptA)}, 0, &bad)
+	err = ix.ConnectBlock(newBlock(block3.BlockHash(), coinbaseTx(4, 50, scriptC), badTx), 4)
+	var cerr *wire.ConservationError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("got %v, want a conservation error", err)
+	}
+	checkBalances(t, ix, after3, 170)
+	if err := ix.ConnectBlock(newBlock(chainhash.Hash{1}, coinbaseTx(4, 50, scriptC)), 4); err == nil {
+		t.Error("expected a block not extending the tip to be rejected")
+	}
+
+	// Reopening the store keeps the index.
+	store.Close()
+	if store, err = OpenFileStore(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if ix, err = New(Config{Store: store}); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, ix, after3, 170)
+
+	// Reorganizing away block 3 restores the state after block 2.
+	if err := ix.DisconnectBlock(block2); err == nil {
+		t.Error("expected disconnecting a block below the tip to fail")
+	}
+	if err := ix.DisconnectBlock(block3); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, ix, map[string]balance{
+		string(scriptA): {sys: 800},
+		string(scriptB): {assets: wire.AssetBalances{7: 200}},
+		string(scriptC): {sys: 50},
+	}, 200)
+	if hash, height, ok, err := ix.Tip(); err != nil || !ok || hash != block2.BlockHash() || height != 2 {
+		t.Errorf("Tip = %v %d %v %v", hash, height, ok, err)
+	}
+	if err := ix.DisconnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, ix, map[string]balance{string(scriptA): {sys: 1000}}, 0)
+}
+
+func TestIndexerRejectsAssetSpendWithoutPayload(t *testing.T) {
+	ix, err := New(Config{Store: NewMemStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := coinbaseTx(1, 1000, scriptA)
+	mint := &wire.SyscoinBurnToEthereumType{Allocation: allocation(7, btcwire.TxOut{Value: 100})}
+	mintTx := assetTx(t, wire.SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION,
+		[]btcwire.OutPoint{{Hash: cb.TxHash()}}, []*btcwire.TxOut{btcwire.NewTxOut(900, scriptB)}, 100, mint)
+	block1 := newBlock(chainhash.Hash{}, cb, mintTx)
+	if err := ix.ConnectBlock(block1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := btcwire.NewMsgTx(2)
+	plain.AddTxIn(btcwire.NewTxIn(&btcwire.OutPoint{Hash: mintTx.TxHash()}, nil, nil))
+	plain.AddTxOut(btcwire.NewTxOut(900, scriptC))
+	if err := ix.ConnectBlock(newBlock(block1.BlockHash(), coinbaseTx(2, 50, scriptC), plain), 2); err == nil {
+		t.Error("expected a plain transaction spending an asset output to be rejected")
+	}
+
+	missing := btcwire.NewMsgTx(2)
+	missing.AddTxIn(btcwire.NewTxIn(&btcwire.OutPoint{Hash: chainhash.Hash{9}}, nil, nil))
+	if err := ix.ConnectBlock(newBlock(block1.BlockHash(), coinbaseTx(2, 50, scriptC), missing), 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound for an unknown input", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexer
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// Key prefixes of the indexer's records.  Integers in keys are big endian
+// so that keys sort numerically.
+const (
+	prefixUTXO         = 'u' // outpoint -> UTXO
+	prefixSysBalance   = 'a' // address -> SYS balance
+	prefixAssetBalance = 'A' // address, GUID -> asset balance
+	prefixSupply       = 'g' // GUID -> asset amount held in UTXOs
+	prefixUndo         = 'd' // block hash -> outputs spent by the block
+	prefixTip          = 't' // tip hash and height
+)
+
+var errMalformedRecord = errors.New("malformed index record")
+
+func utxoKey(op btcwire.OutPoint) []byte {
+	k := make([]byte, 1+chainhash.HashSize+4)
+	k[0] = prefixUTXO
+	copy(k[1:], op.Hash[:])
+	binary.BigEndian.PutUint32(k[1+chainhash.HashSize:], op.Index)
+	return k
+}
+
+// addressKey returns prefix followed by the length-prefixed address, so
+// that no address key is a prefix of another.
+func addressKey(prefix byte, addr string) []byte {
+	k := make([]byte, 0, 3+len(addr)+8)
+	k = append(k, prefix)
+	k = binary.BigEndian.AppendUint16(k, uint16(len(addr)))
+	return append(k, addr...)
+}
+
+func assetBalanceKey(addr string, guid uint64) []byte {
+	return binary.BigEndian.AppendUint64(addressKey(prefixAssetBalance, addr), guid)
+}
+
+func supplyKey(guid uint64) []byte {
+	return binary.BigEndian.AppendUint64([]byte{prefixSupply}, guid)
+}
+
+func undoKey(hash chainhash.Hash) []byte {
+	return append([]byte{prefixUndo}, hash[:]...)
+}
+
+func encodeInt64(v int64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, uint64(v))
+}
+
+func decodeInt64(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, errMalformedRecord
+	}
+	return int64(binary.LittleEndian.Uint64(b)), nil
+}
+
+// appendUTXO appends the encoding of u: value, height, flags, then the asset
+// GUID and amount if it carries an asset, then the length-prefixed script.
+func appendUTXO(b []byte, u *UTXO) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(u.Value))
+	b = binary.LittleEndian.AppendUint32(b, uint32(u.Height))
+	var flags byte
+	if u.Coinbase {
+		flags |= 1
+	}
+	if u.HasAsset {
+		flags |= 2
+	}
+	b = append(b, flags)
+	if u.HasAsset {
+		b = binary.LittleEndian.AppendUint64(b, u.AssetGuid)
+		b = binary.LittleEndian.AppendUint64(b, uint64(u.AssetAmount))
+	}
+	b = binary.AppendUvarint(b, uint64(len(u.PkScript)))
+	return append(b, u.PkScript...)
+}
+
+// readUTXO decodes a UTXO from the front of b and returns the rest.
+func readUTXO(b []byte) (*UTXO, []byte, error) {
+	if len(b) < 13 {
+		return nil, nil, errMalformedRecord
+	}
+	u := &UTXO{
+		Value:  int64(binary.LittleEndian.Uint64(b)),
+		Height: int32(binary.LittleEndian.Uint32(b[8:])),
+	}
+	flags := b[12]
+	b = b[13:]
+	u.Coinbase = flags&1 != 0
+	u.HasAsset = flags&2 != 0
+	if u.HasAsset {
+		if len(b) < 16 {
+			return nil, nil, errMalformedRecord
+		}
+		u.AssetGuid = binary.LittleEndian.Uint64(b)
+		u.AssetAmount = wire.AssetAmount(binary.LittleEndian.Uint64(b[8:]))
+		b = b[16:]
+	}
+	n, size := binary.Uvarint(b)
+	if size <= 0 || n > uint64(len(b)-size) {
+		return nil, nil, errMalformedRecord
+	}
+	b = b[size:]
+	u.PkScript = append([]byte(nil), b[:n]...)
+	return u, b[n:], nil
+}
+
+// spentOutput is an output a block spent, kept to undo the block.
+type spentOutput struct {
+	outpoint btcwire.OutPoint
+	utxo     *UTXO
+}
+
+// encodeUndo encodes the outputs a block spent in spending order.
+func encodeUndo(spent []spentOutput) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(spent)))
+	for _, s := range spent {
+		b = append(b, s.outpoint.Hash[:]...)
+		b = binary.LittleEndian.AppendUint32(b, s.outpoint.Index)
+		b = appendUTXO(b, s.utxo)
+	}
+	return b
+}
+
+func decodeUndo(b []byte) ([]spentOutput, error) {
+	count, size := binary.Uvarint(b)
+	if size <= 0 || count > uint64(len(b)) {
+		return nil, errMalformedRecord
+	}
+	b = b[size:]
+	spent := make([]spentOutput, count)
+	for i := range spent {
+		if len(b) < chainhash.HashSize+4 {
+			return nil, errMalformedRecord
+		}
+		copy(spent[i].outpoint.Hash[:], b)
+		spent[i].outpoint.Index = binary.LittleEndian.Uint32(b[chainhash.HashSize:])
+		var err error
+		spent[i].utxo, b, err = readUTXO(b[chainhash.HashSize+4:])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(b) != 0 {
+		return nil, errMalformedRecord
+	}
+	return spent, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexer
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned by KVStore.Get for a missing key.
+var ErrNotFound = errors.New("key not found")
+
+// KVStore is the key-value storage the indexer keeps its state in.  Writes
+// go through batches so that a block is connected or disconnected
+// atomically.
+type KVStore interface {
+	// Get returns the value stored under key, or ErrNotFound.  The
+	// caller must not modify the returned slice.
+	Get(key []byte) ([]byte, error)
+
+	// Iterate calls fn for every key with the given prefix in ascending
+	// key order, stopping at the first error fn returns.  fn must not
+	// modify the store.
+	Iterate(prefix []byte, fn func(key, value []byte) error) error
+
+	// Write applies every operation of b, or none of them.
+	Write(b *Batch) error
+
+	// Close releases the store's resources.
+	Close() error
+}
+
+// batchOp is one operation of a Batch.  A nil value deletes the key.
+type batchOp struct {
+	key   []byte
+	value []byte
+}
+
+// Batch collects writes to apply to a KVStore atomically.  Later operations
+// on a key override earlier ones.
+type Batch struct {
+	ops []batchOp
+}
+
+// Put stores value under key.  The batch keeps copies of both.
+func (b *Batch) Put(key, value []byte) {
+	v := make([]byte, len(value))
+	copy(v, value)
+	b.ops = append(b.ops, batchOp{key: append([]byte(nil), key...), value: v})
+}
+
+// Delete removes key.
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{key: append([]byte(nil), key...)})
+}
+
+// Len returns the number of operations in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// MemStore is a KVStore kept in memory.  It is safe for concurrent use.
+type MemStore struct {
+	mtx  sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore() *MemStore {
+	return &MemStore{data: make(map[string][]byte)}
+}
+
+// Get implements KVStore.
+func (s *MemStore) Get(key []byte) ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	v, ok := s.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+// Iterate implements KVStore.
+func (s *MemStore) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	var keys []string
+	for k := range s.data {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := fn([]byte(k), s.data[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write implements KVStore.
+func (s *MemStore) Write(b *Batch) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.apply(b)
+	return nil
+}
+
+// apply applies b to the map.  The caller must hold the lock.
+func (s *MemStore) apply(b *Batch) {
+	for _, op := range b.ops {
+		if op.value == nil {
+			delete(s.data, string(op.key))
+		} else {
+			s.data[string(op.key)] = op.value
+		}
+	}
+}
+
+// Close implements KVStore.
+func (s *MemStore) Close() error {
+	return nil
+}
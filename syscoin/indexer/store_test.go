@@ -0,0 +1,187 @@
+package indexer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// dump returns every entry of s.
+func dump(t *testing.T, s KVStore) map[string]string {
+	t.Helper()
+	m := make(map[string]string)
+	var last []byte
+	err := s.Iterate(nil, func(k, v []byte) error {
+		if last != nil && bytes.Compare(last, k) >= 0 {
+			t.Errorf("keys out of order: %q after %q", k, last)
+		}
+		last = append([]byte(nil), k...)
+		m[string(k)] = string(v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func testStore(t *testing.T, s KVStore) {
+	b := &Batch{}
+	b.Put([]byte("b1"), []byte("one"))
+	b.Put([]byte("a"), []byte("x"))
+	b.Put([]byte("b2"), nil)
+	b.Put([]byte("b3"), []byte("three"))
+	b.Delete([]byte("b3"))
+	if err := s.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get([]byte("b1")); err != nil || string(v) != "one" {
+		t.Errorf("Get(b1) = %q, %v", v, err)
+	}
+	if v, err := s.Get([]byte("b2")); err != nil || len(v) != 0 {
+		t.Errorf("Get(b2) = %q, %v", v, err)
+	}
+	if _, err := s.Get([]byte("b3")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(b3) error = %v, want ErrNotFound", err)
+	}
+	var keys []string
+	s.Iterate([]byte("b"), func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	})
+	if len(keys) != 2 || keys[0] != "b1" || keys[1] != "b2" {
+		t.Errorf("Iterate(b) = %q", keys)
+	}
+	stop := errors.New("stop")
+	if err := s.Iterate(nil, func(k, v []byte) error { return stop }); err != stop {
+		t.Errorf("Iterate error = %v, want %v", err, stop)
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	testStore(t, NewMemStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	b := &Batch{}
+	b.Put([]byte("b1"), []byte("uno"))
+	if err := s.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	want := dump(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tear the last record as a crash mid-append would.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torn := encodeRecord(b)
+	f.Write(torn[:len(torn)-2])
+	f.Close()
+
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := dump(t, s)
+	if len(got) != len(want) || got["b1"] != "uno" || got["a"] != "x" {
+		t.Errorf("reopened store = %q, want %q", got, want)
+	}
+	if info2, _ := os.Stat(path); info2.Size() != info.Size() {
+		t.Errorf("torn record not truncated: size %d, want %d", info2.Size(), info.Size())
+	}
+
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	b = &Batch{}
+	b.Delete([]byte("a"))
+	if err := s.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	delete(want, "a")
+	if got := dump(t, s); len(got) != len(want) || got["b1"] != "uno" || got["b2"] != "" {
+		t.Errorf("compacted store = %q, want %q", got, want)
+	}
+	s.Close()
+
+	// A damaged record followed by another one is not a torn append, so
+	// opening fails and leaves the file alone.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-len(encodeRecord(b))-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path); err == nil {
+		t.Fatal("expected a corrupted record to be reported")
+	}
+	if info, _ := os.Stat(path); info.Size() != int64(len(data)) {
+		t.Errorf("corrupted store truncated to %d bytes, want %d", info.Size(), len(data))
+	}
+}
+
+func TestSplitOps(t *testing.T) {
+	b := &Batch{}
+	for i := 0; i < 100; i++ {
+		b.Put(bytes.Repeat([]byte{byte(i)}, 10), bytes.Repeat([]byte{byte(i)}, i))
+		b.Delete([]byte{byte(i)})
+	}
+	b.Put([]byte("big"), make([]byte, 300))
+	const limit = 200
+	batches := splitOps(b.ops, limit)
+	var n int
+	for _, part := range batches {
+		rec := encodeRecord(part)
+		if len(rec)-8 > limit && len(part.ops) > 1 {
+			t.Errorf("record of %d ops has %d bytes, limit %d", len(part.ops), len(rec)-8, limit)
+		}
+		n += len(part.ops)
+	}
+	if n != len(b.ops) || len(batches) < 2 {
+		t.Errorf("split %d ops into %d batches holding %d", len(b.ops), len(batches), n)
+	}
+}
+
+func TestBoltStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.bolt")
+	s, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	want := dump(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = OpenBoltStore(path); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := dump(t, s); len(got) != len(want) || got["b1"] != "one" || got["b2"] != "" || got["a"] != "x" {
+		t.Errorf("reopened store = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package llmq verifies the BLS signatures of Syscoin's long-living
+// masternode quorums (LLMQs), such as ChainLocks and InstantSend locks.
+//
+// Keys and signatures use the basic BLS scheme syscoind signs with: public
+// keys are compressed G1 points, signatures compressed G2 points, and
+// messages are hashed to G2 with the IETF ciphersuite
+// BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_.  The legacy scheme used before
+// the basic scheme activated is not supported.  The curve arithmetic is pure
+// Go and needs no cgo.
+package llmq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// signatureDST is the hash-to-curve domain separation tag of the basic
+// scheme.
+var signatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_")
+
+// ErrInvalidSignature is returned when a well-formed signature does not
+// verify against the public key and message.
+var ErrInvalidSignature = errors.New("invalid BLS signature")
+
+// PublicKey is a BLS public key.
+type PublicKey struct {
+	p *bls12381.PointG1
+}
+
+// Signature is a BLS signature.
+type Signature struct {
+	p *bls12381.PointG2
+}
+
+// SecretKey is a BLS secret key.  syscoind never shares quorum secret keys;
+// it is provided for tests and regtest tooling.
+type SecretKey struct {
+	fr *bls12381.Fr
+}
+
+// ParsePublicKey decodes a compressed public key.  The point at infinity is
+// rejected.
+func ParsePublicKey(b [wire.BLS_PUBKEY_SIZE]byte) (*PublicKey, error) {
+	g1 := bls12381.NewG1()
+	p, err := g1.FromCompressed(b[:])
+	if err != nil {
+		return nil, fmt.Errorf("malformed BLS public key: %v", err)
+	}
+	if g1.IsZero(p) {
+		return nil, errors.New("BLS public key is the point at infinity")
+	}
+	return &PublicKey{p: p}, nil
+}
+
+// Bytes returns the compressed encoding of the key.
+func (k *PublicKey) Bytes() (b [wire.BLS_PUBKEY_SIZE]byte) {
+	copy(b[:], bls12381.NewG1().ToCompressed(k.p))
+	return b
+}
+
+// ParseSignature decodes a compressed signature.
+func ParseSignature(b [wire.BLS_SIGNATURE_SIZE]byte) (*Signature, error) {
+	p, err := bls12381.NewG2().FromCompressed(b[:])
+	if err != nil {
+		return nil, fmt.Errorf("malformed BLS signature: %v", err)
+	}
+	return &Signature{p: p}, nil
+}
+
+// Bytes returns the compressed encoding of the signature.
+func (s *Signature) Bytes() (b [wire.BLS_SIGNATURE_SIZE]byte) {
+	copy(b[:], bls12381.NewG2().ToCompressed(s.p))
+	return b
+}
+
+// Verify reports whether s is k's signature of hash.  The hash is signed as
+// its 32 raw bytes, the way syscoind passes a uint256 to the BLS library.
+func (s *Signature) Verify(k *PublicKey, hash chainhash.Hash) bool {
+	h, err := bls12381.NewG2().HashToCurve(hash[:], signatureDST)
+	if err != nil {
+		return false
+	}
+	g1 := bls12381.NewG1()
+	return bls12381.NewEngine().
+		AddPair(k.p, h).
+		AddPairInv(g1.One(), s.p).
+		Check()
+}
+
+// GenerateSecretKey returns a random secret key read from rand.
+func GenerateSecretKey(rand io.Reader) (*SecretKey, error) {
+	fr, err := bls12381.NewFr().Rand(rand)
+	if err != nil {
+		return nil, err
+	}
+	if fr.IsZero() {
+		return nil, errors.New("generated a zero BLS secret key")
+	}
+	return &SecretKey{fr: fr}, nil
+}
+
+// PublicKey returns the public key of sk.
+func (sk *SecretKey) PublicKey() *PublicKey {
+	g1 := bls12381.NewG1()
+	return &PublicKey{p: g1.MulScalar(g1.New(), g1.One(), sk.fr)}
+}
+
+// Sign signs hash with sk.
+func (sk *SecretKey) Sign(hash chainhash.Hash) (*Signature, error) {
+	return sk.sign(hash[:], signatureDST)
+}
+
+func (sk *SecretKey) sign(msg, dst []byte) (*Signature, error) {
+	g2 := bls12381.NewG2()
+	h, err := g2.HashToCurve(msg, dst)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{p: g2.MulScalar(g2.New(), h, sk.fr)}, nil
+}
+
+// VerifySignature checks that sig is the signature of hash by the holder of
+// pubKey.  It returns ErrInvalidSignature for a well-formed signature that
+// does not verify.
+func VerifySignature(pubKey [wire.BLS_PUBKEY_SIZE]byte, hash chainhash.Hash, sig [wire.BLS_SIGNATURE_SIZE]byte) error {
+	k, err := ParsePublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+	s, err := ParseSignature(sig)
+	if err != nil {
+		return err
+	}
+	if !s.Verify(k, hash) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package llmq
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// VerifyChainLock checks that clsig is signed by the quorum of type llmqType
+// created at quorumHash, whose public key is quorumPubKey.  A block whose
+// hash matches a verified ChainLock is final.
+func VerifyChainLock(clsig *wire.MsgCLSig, llmqType uint8, quorumHash chainhash.Hash, quorumPubKey [wire.BLS_PUBKEY_SIZE]byte) error {
+	signHash := clsig.SignHash(llmqType, quorumHash)
+	if err := VerifySignature(quorumPubKey, signHash, clsig.Sig); err != nil {
+		return fmt.Errorf("clsig for block %v at height %d: %w",
+			clsig.BlockHash, clsig.Height, err)
+	}
+	return nil
+}
+
+// VerifyInstantSendLock checks that islock is signed by the quorum of type
+// llmqType created at quorumHash, whose public key is quorumPubKey.
+func VerifyInstantSendLock(islock *wire.MsgISDLock, llmqType uint8, quorumHash chainhash.Hash, quorumPubKey [wire.BLS_PUBKEY_SIZE]byte) error {
+	signHash := islock.SignHash(llmqType, quorumHash)
+	if err := VerifySignature(quorumPubKey, signHash, islock.Sig); err != nil {
+		return fmt.Errorf("isdlock for transaction %v: %w", islock.Txid, err)
+	}
+	return nil
+}
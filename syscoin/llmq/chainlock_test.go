@@ -0,0 +1,135 @@
+package llmq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+func mustSecretKey(t *testing.T) *SecretKey {
+	t.Helper()
+	sk, err := GenerateSecretKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sk
+}
+
+func mustSign(t *testing.T, sk *SecretKey, hash chainhash.Hash) [wire.BLS_SIGNATURE_SIZE]byte {
+	t.Helper()
+	sig, err := sk.Sign(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig.Bytes()
+}
+
+// TestSignVector checks the curve arithmetic against a known answer from the
+// Ethereum consensus BLS test suite, which signs with the same ciphersuite
+// apart from its proof-of-possession domain tag.
+func TestSignVector(t *testing.T) {
+	skBytes, _ := hex.DecodeString("263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3")
+	sk := &SecretKey{fr: bls12381.NewFr().FromBytes(skBytes)}
+	sig, err := sk.sign(make([]byte, 32), []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "b6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6" +
+		"076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24" +
+		"802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55"
+	if got := sig.Bytes(); hex.EncodeToString(got[:]) != want {
+		t.Errorf("signature %x, want %s", got, want)
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	sk := mustSecretKey(t)
+	pub := sk.PublicKey().Bytes()
+	parsed, err := ParsePublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Bytes() != pub {
+		t.Error("public key does not round trip")
+	}
+
+	var infinity [wire.BLS_PUBKEY_SIZE]byte
+	infinity[0] = 0xc0
+	if _, err := ParsePublicKey(infinity); err == nil {
+		t.Error("expected the point at infinity to be rejected")
+	}
+	var garbage [wire.BLS_SIGNATURE_SIZE]byte
+	garbage[0] = 0x80
+	garbage[1] = 0x01
+	if _, err := ParseSignature(garbage); err == nil {
+		t.Error("expected a malformed signature to be rejected")
+	}
+}
+
+// TestVerifyChainLock signs with a locally generated quorum key, since no
+// quorum-signed chainlock from a live network is available to the tests.
+// TestSignVector anchors the BLS arithmetic to a known answer.
+func TestVerifyChainLock(t *testing.T) {
+	sk := mustSecretKey(t)
+	quorumPubKey := sk.PublicKey().Bytes()
+	quorumHash := chainhash.Hash{0x42}
+	const llmqType = 1
+
+	clsig := &wire.MsgCLSig{Height: 1000, BlockHash: chainhash.Hash{0x01, 0x02}}
+	clsig.Sig = mustSign(t, sk, clsig.SignHash(llmqType, quorumHash))
+	if err := VerifyChainLock(clsig, llmqType, quorumHash, quorumPubKey); err != nil {
+		t.Fatal(err)
+	}
+
+	other := mustSecretKey(t).PublicKey().Bytes()
+	tests := map[string]func() error{
+		"wrong height": func() error {
+			c := *clsig
+			c.Height++
+			return VerifyChainLock(&c, llmqType, quorumHash, quorumPubKey)
+		},
+		"wrong block": func() error {
+			c := *clsig
+			c.BlockHash[0] ^= 0xff
+			return VerifyChainLock(&c, llmqType, quorumHash, quorumPubKey)
+		},
+		"wrong quorum hash": func() error {
+			return VerifyChainLock(clsig, llmqType, chainhash.Hash{0x43}, quorumPubKey)
+		},
+		"wrong llmq type": func() error {
+			return VerifyChainLock(clsig, llmqType+1, quorumHash, quorumPubKey)
+		},
+		"wrong key": func() error {
+			return VerifyChainLock(clsig, llmqType, quorumHash, other)
+		},
+	}
+	for name, verify := range tests {
+		if err := verify(); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
+
+func TestVerifyInstantSendLock(t *testing.T) {
+	sk := mustSecretKey(t)
+	quorumHash := chainhash.Hash{0x07}
+	islock := &wire.MsgISDLock{
+		Version: 1,
+		Inputs:  []btcwire.OutPoint{{Hash: chainhash.Hash{0x09}, Index: 1}},
+		Txid:    chainhash.Hash{0x0a},
+	}
+	islock.Sig = mustSign(t, sk, islock.SignHash(1, quorumHash))
+	if err := VerifyInstantSendLock(islock, 1, quorumHash, sk.PublicKey().Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	islock.Inputs[0].Index = 2
+	if err := VerifyInstantSendLock(islock, 1, quorumHash, sk.PublicKey().Bytes()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("got %v, want ErrInvalidSignature", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package llmq
+
+// LLMQ types as numbered by syscoind's Consensus::LLMQType.
+const (
+	LLMQ_400_60 uint8 = 1
+	LLMQ_TEST   uint8 = 100
+)
+
+// SIGN_HEIGHT_OFFSET is how many blocks below the signed height syscoind
+// looks for the active quorum set when selecting a signing quorum, so that
+// all members agree on the set despite short reorgs.
+const SIGN_HEIGHT_OFFSET = 8
+
+// Params describes one LLMQ type, mirroring syscoind's
+// Consensus::LLMQParams.
+type Params struct {
+	Type uint8
+	Name string
+
+	// Size is the number of quorum members, MinSize the number of valid
+	// members a commitment needs, and Threshold the number of signature
+	// shares needed to recover a quorum signature.
+	Size      int
+	MinSize   int
+	Threshold int
+
+	// DKGInterval is the number of blocks between DKG sessions.
+	DKGInterval int32
+
+	// SigningActiveQuorumCount is the number of most recent quorums that
+	// take part in signing.
+	SigningActiveQuorumCount int
+}
+
+// Params of the LLMQ types syscoind defines.  LLMQ_400_60 signs ChainLocks
+// on mainnet and testnet; LLMQ_TEST is used on regtest.
+var (
+	Params400_60 = Params{
+		Type:                     LLMQ_400_60,
+		Name:                     "llmq_400_60",
+		Size:                     400,
+		MinSize:                  300,
+		Threshold:                240,
+		DKGInterval:              24 * 12,
+		SigningActiveQuorumCount: 4,
+	}
+
+	ParamsTest = Params{
+		Type:                     LLMQ_TEST,
+		Name:                     "llmq_test",
+		Size:                     3,
+		MinSize:                  2,
+		Threshold:                2,
+		DKGInterval:              24,
+		SigningActiveQuorumCount: 2,
+	}
+)
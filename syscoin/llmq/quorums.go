@@ -0,0 +1,230 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package llmq
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// Quorum is a quorum whose final commitment has been mined.
+type Quorum struct {
+	LLMQType       uint8
+	QuorumHash     chainhash.Hash
+	MinedHeight    int32
+	MinedBlockHash chainhash.Hash
+	Commitment     wire.FinalCommitment
+}
+
+// PublicKey returns the quorum's public key.
+func (q *Quorum) PublicKey() [wire.BLS_PUBKEY_SIZE]byte {
+	return q.Commitment.QuorumPublicKey
+}
+
+// QuorumManager tracks the quorums mined on the main chain and selects the
+// quorum responsible for a signing request the way syscoind's
+// CQuorumManager and CSigningManager do.  It is safe for concurrent use.
+type QuorumManager struct {
+	mtx    sync.RWMutex
+	params map[uint8]Params
+
+	// quorums holds the mined quorums of each type ordered by mined
+	// height.
+	quorums map[uint8][]*Quorum
+}
+
+// NewQuorumManager returns a manager tracking the given LLMQ types.
+// Commitments of other types are ignored.
+func NewQuorumManager(params ...Params) *QuorumManager {
+	m := &QuorumManager{
+		params:  make(map[uint8]Params, len(params)),
+		quorums: make(map[uint8][]*Quorum, len(params)),
+	}
+	for _, p := range params {
+		m.params[p.Type] = p
+	}
+	return m
+}
+
+// checkCommitment performs the checks of syscoind's CFinalCommitment::Verify
+// that do not need the quorum member list.  The aggregated member signature
+// is not checked.
+func checkCommitment(p *Params, c *wire.FinalCommitment) error {
+	if c.Version == 0 {
+		return fmt.Errorf("commitment version 0 is invalid")
+	}
+	if len(c.Signers) != p.Size || len(c.ValidMembers) != p.Size {
+		return fmt.Errorf("commitment has %d signers and %d valid members bits, want %d",
+			len(c.Signers), len(c.ValidMembers), p.Size)
+	}
+	if n := c.CountValidMembers(); n < p.MinSize {
+		return fmt.Errorf("commitment has %d valid members, need %d", n, p.MinSize)
+	}
+	if n := c.CountSigners(); n < p.MinSize {
+		return fmt.Errorf("commitment has %d signers, need %d", n, p.MinSize)
+	}
+	if err := VerifySignature(c.QuorumPublicKey, c.CommitmentHash(), c.QuorumSig); err != nil {
+		return fmt.Errorf("commitment quorum signature: %w", err)
+	}
+	return nil
+}
+
+// ConnectBlock records the quorums whose final commitments block, at height,
+// mines.  On error no quorum from the block is recorded.
+func (m *QuorumManager) ConnectBlock(block *btcwire.MsgBlock, height int32) error {
+	blockHash := block.BlockHash()
+	var mined []*Quorum
+	for _, tx := range block.Transactions {
+		if tx.Version != wire.SYSCOIN_TX_VERSION_MN_QUORUM_COMMITMENT {
+			continue
+		}
+		p, err := wire.DecodeTxPayload(tx)
+		if err != nil {
+			return fmt.Errorf("block %v: %v", blockHash, err)
+		}
+		qc, ok := p.(*wire.FinalCommitmentTxPayload)
+		if !ok {
+			return fmt.Errorf("block %v: transaction %v carries a %s payload, not %s",
+				blockHash, tx.TxHash(), p.Kind(), wire.KindQuorumCommitment)
+		}
+		if qc.Height != uint32(height) {
+			return fmt.Errorf("block %v: commitment for height %d mined at height %d",
+				blockHash, qc.Height, height)
+		}
+		params, ok := m.params[qc.Commitment.LLMQType]
+		if !ok || qc.Commitment.IsNull() {
+			continue
+		}
+		if err := checkCommitment(&params, &qc.Commitment); err != nil {
+			return fmt.Errorf("block %v: quorum %v: %v", blockHash, qc.Commitment.QuorumHash, err)
+		}
+		mined = append(mined, &Quorum{
+			LLMQType:       qc.Commitment.LLMQType,
+			QuorumHash:     qc.Commitment.QuorumHash,
+			MinedHeight:    height,
+			MinedBlockHash: blockHash,
+			Commitment:     qc.Commitment,
+		})
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	seen := make(map[chainhash.Hash]bool, len(mined))
+	for _, q := range mined {
+		if _, ok := m.quorum(q.LLMQType, q.QuorumHash); ok || seen[q.QuorumHash] {
+			return fmt.Errorf("block %v: quorum %v is already mined", blockHash, q.QuorumHash)
+		}
+		seen[q.QuorumHash] = true
+	}
+	for _, q := range mined {
+		qs := append(m.quorums[q.LLMQType], q)
+		sort.SliceStable(qs, func(i, j int) bool { return qs[i].MinedHeight < qs[j].MinedHeight })
+		m.quorums[q.LLMQType] = qs
+	}
+	return nil
+}
+
+// DisconnectBlock forgets the quorums mined in the block with the given hash.
+func (m *QuorumManager) DisconnectBlock(blockHash chainhash.Hash) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for llmqType, qs := range m.quorums {
+		kept := qs[:0]
+		for _, q := range qs {
+			if q.MinedBlockHash != blockHash {
+				kept = append(kept, q)
+			}
+		}
+		m.quorums[llmqType] = kept
+	}
+}
+
+// quorum looks up a mined quorum.  The caller must hold the lock.
+func (m *QuorumManager) quorum(llmqType uint8, quorumHash chainhash.Hash) (*Quorum, bool) {
+	for _, q := range m.quorums[llmqType] {
+		if q.QuorumHash == quorumHash {
+			return q, true
+		}
+	}
+	return nil, false
+}
+
+// Quorum returns the mined quorum of the given type and hash.
+func (m *QuorumManager) Quorum(llmqType uint8, quorumHash chainhash.Hash) (*Quorum, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.quorum(llmqType, quorumHash)
+}
+
+// ScanQuorums returns up to maxCount quorums of the given type mined at or
+// below height, newest first.
+func (m *QuorumManager) ScanQuorums(llmqType uint8, height int32, maxCount int) []*Quorum {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	qs := m.quorums[llmqType]
+	var result []*Quorum
+	for i := len(qs) - 1; i >= 0 && len(result) < maxCount; i-- {
+		if qs[i].MinedHeight <= height {
+			result = append(result, qs[i])
+		}
+	}
+	return result
+}
+
+// ActiveQuorums returns the quorums of the given type that sign requests at
+// height, newest first.
+func (m *QuorumManager) ActiveQuorums(llmqType uint8, height int32) ([]*Quorum, error) {
+	params, ok := m.params[llmqType]
+	if !ok {
+		return nil, fmt.Errorf("LLMQ type %d is not tracked", llmqType)
+	}
+	return m.ScanQuorums(llmqType, height, params.SigningActiveQuorumCount), nil
+}
+
+// SelectQuorumForSigning returns the quorum of the given type responsible for
+// requestID when signing at signHeight.  Like syscoind it picks, among the
+// quorums active SIGN_HEIGHT_OFFSET blocks below signHeight, the one with the
+// lowest hash of (llmqType, quorumHash, requestID).
+func (m *QuorumManager) SelectQuorumForSigning(llmqType uint8, signHeight int32, requestID chainhash.Hash) (*Quorum, error) {
+	quorums, err := m.ActiveQuorums(llmqType, signHeight-SIGN_HEIGHT_OFFSET)
+	if err != nil {
+		return nil, err
+	}
+	if len(quorums) == 0 {
+		return nil, fmt.Errorf("no active quorum of type %d at height %d",
+			llmqType, signHeight-SIGN_HEIGHT_OFFSET)
+	}
+	var (
+		best      *Quorum
+		bestScore chainhash.Hash
+		buf       [1 + 2*chainhash.HashSize]byte
+	)
+	buf[0] = llmqType
+	copy(buf[1+chainhash.HashSize:], requestID[:])
+	for _, q := range quorums {
+		copy(buf[1:], q.QuorumHash[:])
+		score := chainhash.DoubleHashH(buf[:])
+		if best == nil || bytes.Compare(score[:], bestScore[:]) < 0 {
+			best, bestScore = q, score
+		}
+	}
+	return best, nil
+}
+
+// VerifyChainLock selects the quorum of the given type that signs clsig and
+// verifies the signature against it.
+func (m *QuorumManager) VerifyChainLock(llmqType uint8, clsig *wire.MsgCLSig) error {
+	q, err := m.SelectQuorumForSigning(llmqType, clsig.Height, clsig.RequestID())
+	if err != nil {
+		return err
+	}
+	return VerifyChainLock(clsig, llmqType, q.QuorumHash, q.PublicKey())
+}
@@ -0,0 +1,155 @@
+package llmq
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+type testQuorum struct {
+	sk         *SecretKey
+	commitment wire.FinalCommitment
+}
+
+func newTestQuorum(t *testing.T, quorumHash chainhash.Hash) *testQuorum {
+	t.Helper()
+	sk := mustSecretKey(t)
+	c := wire.FinalCommitment{
+		Version:         1,
+		LLMQType:        LLMQ_TEST,
+		QuorumHash:      quorumHash,
+		Signers:         []bool{true, true, false},
+		ValidMembers:    []bool{true, true, true},
+		QuorumPublicKey: sk.PublicKey().Bytes(),
+	}
+	c.QuorumSig = mustSign(t, sk, c.CommitmentHash())
+	return &testQuorum{sk: sk, commitment: c}
+}
+
+func commitmentBlock(t *testing.T, height int32, commitments ...wire.FinalCommitment) *btcwire.MsgBlock {
+	t.Helper()
+	header := btcwire.NewBlockHeader(1, &chainhash.Hash{byte(height)}, &chainhash.Hash{}, 0, 0)
+	// NewBlockHeader stamps the current time; fix it so the block hash
+	// only depends on the arguments.
+	header.Timestamp = time.Unix(int64(height), 0)
+	block := btcwire.NewMsgBlock(header)
+	for _, c := range commitments {
+		var buf bytes.Buffer
+		p := &wire.FinalCommitmentTxPayload{Version: 1, Height: uint32(height), Commitment: c}
+		if err := p.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+		script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(buf.Bytes()).Script()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := btcwire.NewMsgTx(wire.SYSCOIN_TX_VERSION_MN_QUORUM_COMMITMENT)
+		tx.AddTxOut(btcwire.NewTxOut(0, script))
+		block.AddTransaction(tx)
+	}
+	return block
+}
+
+func TestQuorumManager(t *testing.T) {
+	m := NewQuorumManager(ParamsTest)
+	var quorums []*testQuorum
+	var blocks []*btcwire.MsgBlock
+	for i := 0; i < 3; i++ {
+		q := newTestQuorum(t, chainhash.Hash{0x10, byte(i)})
+		quorums = append(quorums, q)
+		height := int32(24*(i+1) + 10)
+		block := commitmentBlock(t, height, q.commitment)
+		blocks = append(blocks, block)
+		if err := m.ConnectBlock(block, height); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the two newest quorums mined at or below the height are active.
+	active, err := m.ActiveQuorums(LLMQ_TEST, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 2 || active[0].QuorumHash != quorums[2].commitment.QuorumHash ||
+		active[1].QuorumHash != quorums[1].commitment.QuorumHash {
+		t.Fatalf("unexpected active quorums %v", active)
+	}
+	if active, _ = m.ActiveQuorums(LLMQ_TEST, 60); len(active) != 2 ||
+		active[0].QuorumHash != quorums[1].commitment.QuorumHash {
+		t.Fatalf("unexpected active quorums at height 60 %v", active)
+	}
+
+	// The selected quorum signs the ChainLock; any other quorum's signature
+	// is rejected.
+	clsig := &wire.MsgCLSig{Height: 108, BlockHash: chainhash.Hash{0xcc}}
+	selected, err := m.SelectQuorumForSigning(LLMQ_TEST, clsig.Height, clsig.RequestID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range quorums {
+		clsig.Sig = mustSign(t, q.sk, clsig.SignHash(LLMQ_TEST, q.commitment.QuorumHash))
+		err := m.VerifyChainLock(LLMQ_TEST, clsig)
+		if q.commitment.QuorumHash == selected.QuorumHash && err != nil {
+			t.Errorf("ChainLock signed by the selected quorum rejected: %v", err)
+		}
+		if q.commitment.QuorumHash != selected.QuorumHash && err == nil {
+			t.Errorf("ChainLock signed by quorum %v accepted", q.commitment.QuorumHash)
+		}
+	}
+
+	if err := m.ConnectBlock(commitmentBlock(t, 200, quorums[0].commitment), 200); err == nil {
+		t.Error("expected an already mined quorum to be rejected")
+	}
+
+	m.DisconnectBlock(blocks[2].BlockHash())
+	if _, ok := m.Quorum(LLMQ_TEST, quorums[2].commitment.QuorumHash); ok {
+		t.Fatal("quorum still known after its block was disconnected")
+	}
+	if _, ok := m.Quorum(LLMQ_TEST, quorums[1].commitment.QuorumHash); !ok {
+		t.Fatal("quorum of another block forgotten")
+	}
+	if _, err := m.SelectQuorumForSigning(LLMQ_TEST, 40, chainhash.Hash{}); err == nil {
+		t.Error("expected no active quorum below the first commitment")
+	}
+}
+
+func TestQuorumManagerRejectsInvalidCommitments(t *testing.T) {
+	m := NewQuorumManager(ParamsTest)
+	good := newTestQuorum(t, chainhash.Hash{0x20}).commitment
+
+	badSig := good
+	badSig.QuorumVvecHash = chainhash.Hash{0x01}
+	wrongSize := good
+	wrongSize.Signers = []bool{true, true}
+	tooFewSigners := good
+	tooFewSigners.Signers = []bool{true, false, false}
+
+	for name, c := range map[string]wire.FinalCommitment{
+		"bad quorum signature": badSig,
+		"wrong size":           wrongSize,
+		"too few signers":      tooFewSigners,
+	} {
+		if err := m.ConnectBlock(commitmentBlock(t, 30, c), 30); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if err := m.ConnectBlock(commitmentBlock(t, 30, good), 31); err == nil {
+		t.Error("expected a height mismatch to be rejected")
+	}
+
+	// Null commitments and untracked types are skipped.
+	null := wire.FinalCommitment{Version: 1, LLMQType: LLMQ_TEST, Signers: make([]bool, 3), ValidMembers: make([]bool, 3)}
+	other := good
+	other.LLMQType = LLMQ_400_60
+	if err := m.ConnectBlock(commitmentBlock(t, 30, null, other), 30); err != nil {
+		t.Fatal(err)
+	}
+	if qs := m.ScanQuorums(LLMQ_TEST, 100, 10); len(qs) != 0 {
+		t.Errorf("got %d quorums, want none", len(qs))
+	}
+}
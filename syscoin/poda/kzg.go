@@ -0,0 +1,250 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package poda
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+const (
+	// BYTES_PER_FIELD_ELEMENT is the size of one big-endian scalar in a
+	// blob.
+	BYTES_PER_FIELD_ELEMENT = 32
+
+	// G1_POINT_SIZE and G2_POINT_SIZE are the sizes of compressed points
+	// in a trusted setup file.
+	G1_POINT_SIZE = 48
+	G2_POINT_SIZE = 96
+)
+
+// blsModulus is the order of the BLS12-381 scalar field, big-endian.  Every
+// field element of a blob must be below it.
+var blsModulus = [BYTES_PER_FIELD_ELEMENT]byte{
+	0x73, 0xed, 0xa7, 0x53, 0x29, 0x9d, 0x7d, 0x48,
+	0x33, 0x39, 0xd8, 0x08, 0x09, 0xa1, 0xd8, 0x05,
+	0x53, 0xbd, 0xa4, 0x02, 0xff, 0xfe, 0x5b, 0xfe,
+	0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x01,
+}
+
+// TrustedSetup holds the Lagrange-form G1 points of a KZG trusted setup,
+// which is all computing a blob's commitment needs.  A TrustedSetup is safe
+// for concurrent use.
+type TrustedSetup struct {
+	// g1 holds the points in bit-reversed order, matching the order of
+	// the field elements in a blob.  They are affine, so the multi-
+	// exponentiation only reads them.
+	g1 []*bls12381.PointG1
+}
+
+// LoadTrustedSetupFile loads a trusted setup with LoadTrustedSetup from the
+// file at path.
+func LoadTrustedSetupFile(path string) (*TrustedSetup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadTrustedSetup(f)
+}
+
+// LoadTrustedSetup reads a trusted setup in either of the formats the
+// Ethereum KZG libraries distribute it in:
+//
+//   - c-kzg-4844's trusted_setup.txt: the number of G1 points and of G2
+//     points on the first two lines, then one hex point per line, the
+//     Lagrange-form G1 points first.  Anything after the G2 points, such as
+//     the monomial G1 points later versions append, is ignored.
+//   - The JSON form with "g1_lagrange" and "g2_monomial" arrays of
+//     0x-prefixed hex points.
+//
+// In both, the G1 points are in natural order and the number of them, a
+// power of two, fixes the blob size.  Each point is checked to be in the G1
+// subgroup.  The G2 points are only used to verify KZG proofs, which this
+// package does not do, so only their encoding length is checked.
+func LoadTrustedSetup(r io.Reader) (*TrustedSetup, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	for err == nil && (first[0] == ' ' || first[0] == '\n' || first[0] == '\r' || first[0] == '\t') {
+		br.ReadByte()
+		first, err = br.Peek(1)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trusted setup: %w", err)
+	}
+
+	var g1Hex, g2Hex []string
+	if first[0] == '{' {
+		var setup struct {
+			G1Lagrange []string `json:"g1_lagrange"`
+			G2Monomial []string `json:"g2_monomial"`
+		}
+		if err := json.NewDecoder(br).Decode(&setup); err != nil {
+			return nil, fmt.Errorf("trusted setup: %w", err)
+		}
+		g1Hex, g2Hex = setup.G1Lagrange, setup.G2Monomial
+	} else {
+		g1Hex, g2Hex, err = readSetupText(br)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newTrustedSetup(g1Hex, g2Hex)
+}
+
+// readSetupText reads the points of a trusted_setup.txt.
+func readSetupText(r io.Reader) (g1Hex, g2Hex []string, err error) {
+	scanner := bufio.NewScanner(r)
+	next := func() (string, error) {
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				return line, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	var counts [2]int
+	for i := range counts {
+		line, err := next()
+		if err != nil {
+			return nil, nil, fmt.Errorf("trusted setup: %w", err)
+		}
+		if counts[i], err = strconv.Atoi(line); err != nil || counts[i] <= 0 {
+			return nil, nil, fmt.Errorf("trusted setup: bad point count %q", line)
+		}
+	}
+	points := make([]string, 0, counts[0]+counts[1])
+	for len(points) < cap(points) {
+		line, err := next()
+		if err != nil {
+			return nil, nil, fmt.Errorf("trusted setup: point %d: %w", len(points), err)
+		}
+		points = append(points, line)
+	}
+	return points[:counts[0]], points[counts[0]:], nil
+}
+
+func newTrustedSetup(g1Hex, g2Hex []string) (*TrustedSetup, error) {
+	n := len(g1Hex)
+	if n == 0 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("trusted setup has %d G1 points, want a power of two", n)
+	}
+	if len(g2Hex) < 2 {
+		return nil, fmt.Errorf("trusted setup has %d G2 points, want at least 2", len(g2Hex))
+	}
+	for i, s := range g2Hex {
+		if b, err := decodePointHex(s); err != nil || len(b) != G2_POINT_SIZE {
+			return nil, fmt.Errorf("trusted setup G2 point %d is not %d hex bytes", i, G2_POINT_SIZE)
+		}
+	}
+
+	g := bls12381.NewG1()
+	points := make([]*bls12381.PointG1, n)
+	for i, s := range g1Hex {
+		b, err := decodePointHex(s)
+		if err != nil || len(b) != G1_POINT_SIZE {
+			return nil, fmt.Errorf("trusted setup G1 point %d is not %d hex bytes", i, G1_POINT_SIZE)
+		}
+		// FromCompressed also checks the point is in the subgroup.
+		p, err := g.FromCompressed(b)
+		if err != nil {
+			return nil, fmt.Errorf("trusted setup G1 point %d: %v", i, err)
+		}
+		points[i] = p
+	}
+	g.AffineBatch(points)
+	bitReversePermute(points)
+	return &TrustedSetup{g1: points}, nil
+}
+
+func decodePointHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// bitReversePermute reorders points, whose length is a power of two, so
+// that index i holds the point previously at the bit-reversal of i.  Blob
+// field elements are evaluations over the roots of unity in this order.
+func bitReversePermute(points []*bls12381.PointG1) {
+	n := len(points)
+	bits := 0
+	for 1<<bits < n {
+		bits++
+	}
+	for i := 0; i < n; i++ {
+		j := 0
+		for b := 0; b < bits; b++ {
+			j |= (i >> b & 1) << (bits - 1 - b)
+		}
+		if i < j {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+}
+
+// FieldElements returns the number of field elements in a blob under this
+// setup: 4096 for the EIP-4844 setup.
+func (s *TrustedSetup) FieldElements() int {
+	return len(s.g1)
+}
+
+// BlobSize returns the size of a blob in bytes under this setup.
+func (s *TrustedSetup) BlobSize() int {
+	return len(s.g1) * BYTES_PER_FIELD_ELEMENT
+}
+
+// BlobToCommitment returns the compressed KZG commitment of blob, which
+// must be BlobSize bytes of canonical big-endian field elements.
+func (s *TrustedSetup) BlobToCommitment(blob []byte) ([]byte, error) {
+	if len(blob) != s.BlobSize() {
+		return nil, fmt.Errorf("blob has %d bytes, want %d", len(blob), s.BlobSize())
+	}
+	scalars := make([]*bls12381.Fr, len(s.g1))
+	for i := range scalars {
+		elem := blob[i*BYTES_PER_FIELD_ELEMENT : (i+1)*BYTES_PER_FIELD_ELEMENT]
+		if bytes.Compare(elem, blsModulus[:]) >= 0 {
+			return nil, fmt.Errorf("blob field element %d is not below the BLS modulus", i)
+		}
+		scalars[i] = bls12381.NewFr().FromBytes(elem)
+	}
+	g := bls12381.NewG1()
+	c, err := g.MultiExp(g.New(), s.g1, scalars)
+	if err != nil {
+		return nil, err
+	}
+	return g.ToCompressed(c), nil
+}
+
+// ErrBlobMismatch is returned when a blob's commitment does not hash to
+// the versioned hash it was fetched for.
+var ErrBlobMismatch = errors.New("blob does not match its versioned hash")
+
+// VerifyBlob checks that blob's KZG commitment has the versioned hash h.
+func (s *TrustedSetup) VerifyBlob(blob []byte, h wire.VersionedHash) error {
+	if !h.IsKnownVersion() {
+		return fmt.Errorf("versioned hash %v has unknown version %#02x", h, h.Version())
+	}
+	commitment, err := s.BlobToCommitment(blob)
+	if err != nil {
+		return err
+	}
+	if !h.Matches(commitment) {
+		return ErrBlobMismatch
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package poda
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// testSetupSize is the number of field elements per blob in the test
+// setup.
+const testSetupSize = 8
+
+var (
+	frModulus, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+	// testTau is the toxic waste of the insecure test setup.
+	testTau = big.NewInt(1337)
+)
+
+// rootOfUnity returns a primitive n-th root of unity of the scalar field,
+// derived from the generator 7 as the consensus specs do.
+func rootOfUnity(n int) *big.Int {
+	exp := new(big.Int).Sub(frModulus, big.NewInt(1))
+	exp.Div(exp, big.NewInt(int64(n)))
+	return new(big.Int).Exp(big.NewInt(7), exp, frModulus)
+}
+
+func reverseBits(i, n int) int {
+	j := 0
+	for b := 1; b < n; b <<= 1 {
+		j <<= 1
+		if i&b != 0 {
+			j |= 1
+		}
+	}
+	return j
+}
+
+// testSetupText returns an insecure trusted_setup.txt for testSetupSize
+// field elements: the G1 points are [L_i(tau)] for the Lagrange basis over
+// the roots of unity in natural order.
+func testSetupText() string {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	n := big.NewInt(testSetupSize)
+	omega := rootOfUnity(testSetupSize)
+	// L_i(tau) = w^i (tau^n - 1) / (n (tau - w^i))
+	tauN := new(big.Int).Exp(testTau, n, frModulus)
+	tauN.Sub(tauN, big.NewInt(1))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d\n2\n", testSetupSize)
+	wi := big.NewInt(1)
+	for i := 0; i < testSetupSize; i++ {
+		den := new(big.Int).Sub(testTau, wi)
+		den.Mul(den, n).Mod(den, frModulus).ModInverse(den, frModulus)
+		l := new(big.Int).Mul(wi, tauN)
+		l.Mul(l, den).Mod(l, frModulus)
+		p := g1.MulScalarBig(g1.New(), g1.One(), l)
+		fmt.Fprintf(&sb, "%x\n", g1.ToCompressed(p))
+		wi = new(big.Int).Mul(wi, omega)
+		wi.Mod(wi, frModulus)
+	}
+	fmt.Fprintf(&sb, "%x\n", g2.ToCompressed(g2.One()))
+	fmt.Fprintf(&sb, "%x\n", g2.ToCompressed(g2.MulScalarBig(g2.New(), g2.One(), testTau)))
+	return sb.String()
+}
+
+// testSetupJSON converts testSetupText to the JSON format.
+func testSetupJSON(t *testing.T) string {
+	lines := strings.Fields(testSetupText())
+	var setup struct {
+		G1Lagrange []string `json:"g1_lagrange"`
+		G2Monomial []string `json:"g2_monomial"`
+	}
+	for i, l := range lines[2:] {
+		if i < testSetupSize {
+			setup.G1Lagrange = append(setup.G1Lagrange, "0x"+l)
+		} else {
+			setup.G2Monomial = append(setup.G2Monomial, "0x"+l)
+		}
+	}
+	b, err := json.Marshal(setup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func loadTestSetup(t *testing.T) *TrustedSetup {
+	t.Helper()
+	s, err := LoadTrustedSetup(strings.NewReader(testSetupText()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// polyBlob returns the blob holding the evaluations of the polynomial with
+// coefficients coeffs over the roots of unity in bit-reversed order, and
+// the commitment [p(tau)]G1 it must produce.
+func polyBlob(coeffs ...int64) ([]byte, []byte) {
+	eval := func(x *big.Int) *big.Int {
+		acc := new(big.Int)
+		for i := len(coeffs) - 1; i >= 0; i-- {
+			acc.Mul(acc, x).Add(acc, big.NewInt(coeffs[i])).Mod(acc, frModulus)
+		}
+		return acc
+	}
+	omega := rootOfUnity(testSetupSize)
+	blob := make([]byte, testSetupSize*BYTES_PER_FIELD_ELEMENT)
+	for j := 0; j < testSetupSize; j++ {
+		x := new(big.Int).Exp(omega, big.NewInt(int64(reverseBits(j, testSetupSize))), frModulus)
+		eval(x).FillBytes(blob[j*BYTES_PER_FIELD_ELEMENT : (j+1)*BYTES_PER_FIELD_ELEMENT])
+	}
+	g1 := bls12381.NewG1()
+	commitment := g1.ToCompressed(g1.MulScalarBig(g1.New(), g1.One(), eval(testTau)))
+	return blob, commitment
+}
+
+func TestBlobToCommitment(t *testing.T) {
+	for name, text := range map[string]string{"text": testSetupText(), "json": testSetupJSON(t)} {
+		s, err := LoadTrustedSetup(strings.NewReader(text))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if s.FieldElements() != testSetupSize || s.BlobSize() != testSetupSize*BYTES_PER_FIELD_ELEMENT {
+			t.Errorf("%s: %d field elements, %d bytes", name, s.FieldElements(), s.BlobSize())
+		}
+		for _, coeffs := range [][]int64{{5}, {1, 2, 3}, {-4, 0, 9, 1, 1, 0, 0, 7}} {
+			blob, want := polyBlob(coeffs...)
+			got, err := s.BlobToCommitment(blob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: commitment of %v = %x, want %x", name, coeffs, got, want)
+			}
+		}
+	}
+
+	s := loadTestSetup(t)
+	zero := make([]byte, s.BlobSize())
+	if c, err := s.BlobToCommitment(zero); err != nil || c[0] != 0xc0 || !bytes.Equal(c[1:], make([]byte, 47)) {
+		t.Errorf("commitment of the zero blob = %x, %v", c, err)
+	}
+	if _, err := s.BlobToCommitment(zero[1:]); err == nil {
+		t.Error("BlobToCommitment accepted a short blob")
+	}
+	bad := append([]byte(nil), zero...)
+	copy(bad[BYTES_PER_FIELD_ELEMENT:], blsModulus[:])
+	if _, err := s.BlobToCommitment(bad); err == nil {
+		t.Error("BlobToCommitment accepted a field element equal to the modulus")
+	}
+}
+
+func TestLoadTrustedSetupMalformed(t *testing.T) {
+	lines := strings.Fields(testSetupText())
+	join := func(l ...string) string { return strings.Join(l, "\n") }
+	tests := map[string]string{
+		"empty":              "",
+		"bad count":          join(append([]string{"x"}, lines[1:]...)...),
+		"not a power of two": join(append([]string{"6", "2"}, append(lines[2:8], lines[10:]...)...)...),
+		"truncated":          join(lines[:len(lines)-1]...),
+		"bad hex":            join(append(append([]string{}, lines[:2]...), append([]string{"zz"}, lines[3:]...)...)...),
+		"not on curve":       join(append(append([]string{}, lines[:2]...), append([]string{"a" + strings.Repeat("0", 95)}, lines[3:]...)...)...),
+		"short G2":           join(append(append([]string{}, lines[:len(lines)-1]...), lines[len(lines)-1][:100])...),
+		"json":               `{"g1_lagrange": ["0x00"], "g2_monomial": []}`,
+	}
+	for name, text := range tests {
+		if _, err := LoadTrustedSetup(strings.NewReader(text)); err == nil {
+			t.Errorf("%s: LoadTrustedSetup accepted the setup", name)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "trusted_setup.txt")
+	if err := os.WriteFile(path, []byte(testSetupText()+"\n# trailing monomial points\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTrustedSetupFile(path); err != nil {
+		t.Errorf("LoadTrustedSetupFile: %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := wire.VersionedHash{wire.VERSIONED_HASH_VERSION_KZG, 0xaa}
+	if _, err := store.Get(h); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing blob = %v, want ErrNotFound", err)
+	}
+	if ok, err := store.Has(h); ok || err != nil {
+		t.Errorf("Has of a missing blob = %v, %v", ok, err)
+	}
+	for _, blob := range [][]byte{[]byte("first"), []byte("second")} {
+		if err := store.Put(h, blob); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Get(h)
+		if err != nil || !bytes.Equal(got, blob) {
+			t.Errorf("Get = %q, %v, want %q", got, err, blob)
+		}
+	}
+	if ok, err := store.Has(h); !ok || err != nil {
+		t.Errorf("Has of a stored blob = %v, %v", ok, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != hex.EncodeToString(h[:])+".blob" {
+		t.Errorf("store directory holds %v", entries)
+	}
+}
+
+func TestVerifierFetchBlock(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(store, loadTestSetup(t))
+
+	var hashes [][]byte
+	var blobs [][]byte
+	for i := int64(1); i <= 3; i++ {
+		blob, commitment := polyBlob(i, 2*i, 3*i)
+		h, err := v.Import(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !h.Matches(commitment) {
+			t.Fatalf("Import returned %v, not the hash of the blob's commitment", h)
+		}
+		hashes = append(hashes, h[:])
+		blobs = append(blobs, blob)
+	}
+	block := &wire.NEVMBlockWire{VersionHashes: hashes}
+
+	got, err := v.FetchBlock(context.Background(), block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range blobs {
+		if !bytes.Equal(got[i], blobs[i]) {
+			t.Errorf("blob %d mismatch", i)
+		}
+	}
+	if err := v.VerifyBlock(context.Background(), block); err != nil {
+		t.Errorf("VerifyBlock: %v", err)
+	}
+	if refs, err := BlockBlobs(block); err != nil || len(refs) != 3 || refs[2].Version() != wire.VERSIONED_HASH_VERSION_KZG {
+		t.Errorf("BlockBlobs = %v, %v", refs, err)
+	}
+
+	// A blob stored under another blob's hash.
+	var h1 wire.VersionedHash
+	copy(h1[:], hashes[1])
+	if err := store.Put(h1, blobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	var blobErr *BlobError
+	err = v.VerifyBlock(context.Background(), block)
+	if !errors.As(err, &blobErr) || blobErr.Index != 1 || !errors.Is(err, ErrBlobMismatch) {
+		t.Errorf("VerifyBlock with a swapped blob = %v", err)
+	}
+
+	// A blob the store does not hold.
+	missing, _ := polyBlob(99)
+	commitment, err := v.Setup.BlobToCommitment(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hm, _ := wire.FromCommitment(commitment)
+	block.VersionHashes = [][]byte{hashes[0], hm[:]}
+	_, err = v.FetchBlock(context.Background(), block)
+	if !errors.As(err, &blobErr) || blobErr.Index != 1 || blobErr.Hash != hm || !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchBlock with a missing blob = %v", err)
+	}
+
+	// A malformed version hash, and a cancelled context.
+	block.VersionHashes = [][]byte{hashes[0][:20]}
+	if _, err := v.FetchBlock(context.Background(), block); err == nil {
+		t.Error("FetchBlock accepted a 20-byte version hash")
+	}
+	block.VersionHashes = hashes
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := v.VerifyBlock(ctx, block); !errors.Is(err, context.Canceled) {
+		t.Errorf("VerifyBlock with a cancelled context = %v", err)
+	}
+}
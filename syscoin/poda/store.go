@@ -0,0 +1,102 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package poda fetches and verifies the blobs an NEVM block commits to
+// through Syscoin's Proof-of-Data-Availability (PoDA).
+//
+// Each entry of NEVMBlockWire.VersionHashes is the EIP-4844 versioned hash
+// of a blob's KZG commitment.  Blobs are kept in a BlobStore, keyed by that
+// hash, and a Verifier recomputes each blob's commitment with a trusted
+// setup loaded from a file to check it against the hash.  Everything runs
+// offline, and the curve arithmetic is pure Go.
+package poda
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// ErrNotFound is returned by BlobStore.Get for a blob the store does not
+// hold.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore holds blobs keyed by their versioned hash.  Stores do not
+// verify blobs; see Verifier.
+type BlobStore interface {
+	// Get returns the blob stored under h, or ErrNotFound.
+	Get(h wire.VersionedHash) ([]byte, error)
+
+	// Has reports whether the store holds a blob under h.
+	Has(h wire.VersionedHash) (bool, error)
+
+	// Put stores blob under h, replacing any blob already stored there.
+	Put(h wire.VersionedHash, blob []byte) error
+}
+
+// FileStore is a BlobStore keeping each blob in its own file, named after
+// the hex of its versioned hash, in one directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore in dir, creating the directory if it
+// does not exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(h wire.VersionedHash) string {
+	return filepath.Join(s.dir, hex.EncodeToString(h[:])+".blob")
+}
+
+// Get returns the blob stored under h, or ErrNotFound.
+func (s *FileStore) Get(h wire.VersionedHash) ([]byte, error) {
+	blob, err := os.ReadFile(s.path(h))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, h)
+	}
+	return blob, err
+}
+
+// Has reports whether the store holds a blob under h.
+func (s *FileStore) Has(h wire.VersionedHash) (bool, error) {
+	_, err := os.Stat(s.path(h))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Put stores blob under h.  The blob is written to a temporary file that is
+// synced and renamed into place, so a crash never leaves a partial blob
+// under h.
+func (s *FileStore) Put(h wire.VersionedHash, blob []byte) error {
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(blob)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path(h))
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package poda
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// BlobError reports a blob of a block that could not be fetched or did not
+// verify.
+type BlobError struct {
+	// Index is the position of the blob's hash in VersionHashes.
+	Index int
+
+	// Hash is the blob's versioned hash.
+	Hash wire.VersionedHash
+
+	// Err is the underlying error, such as ErrNotFound or
+	// ErrBlobMismatch.
+	Err error
+}
+
+func (e *BlobError) Error() string {
+	return fmt.Sprintf("blob %d (%v): %v", e.Index, e.Hash, e.Err)
+}
+
+func (e *BlobError) Unwrap() error {
+	return e.Err
+}
+
+// BlockBlobs returns the versioned hashes of the blobs block references, in
+// order, failing if any entry of VersionHashes is malformed.
+func BlockBlobs(block *wire.NEVMBlockWire) ([]wire.VersionedHash, error) {
+	return block.ParsedVersionHashes()
+}
+
+// Verifier fetches blobs from a store and verifies them against their
+// versioned hashes.
+type Verifier struct {
+	Store BlobStore
+	Setup *TrustedSetup
+}
+
+// NewVerifier returns a Verifier reading blobs from store and checking them
+// with setup.
+func NewVerifier(store BlobStore, setup *TrustedSetup) *Verifier {
+	return &Verifier{Store: store, Setup: setup}
+}
+
+// Fetch returns the blob stored under h after verifying it.
+func (v *Verifier) Fetch(h wire.VersionedHash) ([]byte, error) {
+	blob, err := v.Store.Get(h)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Setup.VerifyBlob(blob, h); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Import computes blob's versioned hash, stores the blob under it and
+// returns it.
+func (v *Verifier) Import(blob []byte) (wire.VersionedHash, error) {
+	commitment, err := v.Setup.BlobToCommitment(blob)
+	if err != nil {
+		return wire.VersionedHash{}, err
+	}
+	h, err := wire.FromCommitment(commitment)
+	if err != nil {
+		return wire.VersionedHash{}, err
+	}
+	return h, v.Store.Put(h, blob)
+}
+
+// FetchBlock fetches and verifies every blob block references and returns
+// them in VersionHashes order.  A blob that is missing or does not verify
+// is reported as a *BlobError.  ctx is checked between blobs.
+func (v *Verifier) FetchBlock(ctx context.Context, block *wire.NEVMBlockWire) ([][]byte, error) {
+	hashes, err := BlockBlobs(block)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		blob, err := v.Fetch(h)
+		if err != nil {
+			return nil, &BlobError{Index: i, Hash: h, Err: err}
+		}
+		blobs[i] = blob
+	}
+	return blobs, nil
+}
+
+// VerifyBlock checks that every blob block references is available and
+// matches its versioned hash, without keeping the blobs.
+func (v *Verifier) VerifyBlock(ctx context.Context, block *wire.NEVMBlockWire) error {
+	hashes, err := BlockBlobs(block)
+	if err != nil {
+		return err
+	}
+	for i, h := range hashes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := v.Fetch(h); err != nil {
+			return &BlobError{Index: i, Hash: h, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package psbt extends btcd's PSBTs with the asset information of Syscoin
+// transactions, so that signers can show what an asset transaction moves
+// instead of an opaque OP_RETURN output.
+//
+// The information is stored in BIP 174 proprietary key-value pairs: key type
+// 0xFC, the identifier "SYS" and one of the PSBT_SYS_* subtypes.  Inputs and
+// outputs carry the asset GUID and amount they hold; the global map carries
+// the serialized payload of the OP_RETURN output.
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	btcpsbt "github.com/btcsuite/btcd/btcutil/psbt"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// PSBT_PROPRIETARY_TYPE is the BIP 174 key type of proprietary entries.
+const PSBT_PROPRIETARY_TYPE = 0xfc
+
+// PSBT_SYS_IDENTIFIER prefixes the key data of Syscoin's proprietary entries.
+const PSBT_SYS_IDENTIFIER = "SYS"
+
+// Subtypes of Syscoin's proprietary entries.
+const (
+	// PSBT_SYS_IN_ASSET is the asset held by the output an input spends.
+	PSBT_SYS_IN_ASSET = 0x00
+
+	// PSBT_SYS_OUT_ASSET is the asset allocated to an output.
+	PSBT_SYS_OUT_ASSET = 0x01
+
+	// PSBT_SYS_GLOBAL_PAYLOAD is the serialized payload of the OP_RETURN
+	// output.
+	PSBT_SYS_GLOBAL_PAYLOAD = 0x02
+)
+
+// AssetValue is an amount of one asset.  It is encoded as the GUID and
+// amount, each 8 bytes little endian.
+type AssetValue struct {
+	AssetGuid uint64
+	Amount    wire.AssetAmount
+}
+
+// Format returns v as a decimal amount followed by the asset's symbol, as
+// in "10.00 USDT".
+func (v AssetValue) Format(asset *wire.AssetType) string {
+	return fmt.Sprintf("%s %s", asset.FormatAmount(v.Amount), asset.Symbol)
+}
+
+func (v AssetValue) encode() []byte {
+	b := binary.LittleEndian.AppendUint64(nil, v.AssetGuid)
+	return binary.LittleEndian.AppendUint64(b, uint64(v.Amount))
+}
+
+func decodeAssetValue(b []byte) (AssetValue, error) {
+	if len(b) != 16 {
+		return AssetValue{}, fmt.Errorf("asset value has %d bytes, want 16", len(b))
+	}
+	v := AssetValue{
+		AssetGuid: binary.LittleEndian.Uint64(b),
+		Amount:    wire.AssetAmount(binary.LittleEndian.Uint64(b[8:])),
+	}
+	if !v.Amount.IsValid() {
+		return AssetValue{}, fmt.Errorf("asset %d: %w", v.AssetGuid, wire.ErrAssetAmountRange)
+	}
+	return v, nil
+}
+
+// proprietaryKey returns the full key of a Syscoin entry with the given
+// subtype and no further key data.
+func proprietaryKey(subtype uint64) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(PSBT_PROPRIETARY_TYPE)
+	btcwire.WriteVarInt(&buf, 0, uint64(len(PSBT_SYS_IDENTIFIER)))
+	buf.WriteString(PSBT_SYS_IDENTIFIER)
+	btcwire.WriteVarInt(&buf, 0, subtype)
+	return buf.Bytes()
+}
+
+// get returns the value stored under key in unknowns.
+func get(unknowns []*btcpsbt.Unknown, key []byte) ([]byte, bool) {
+	for _, u := range unknowns {
+		if bytes.Equal(u.Key, key) {
+			return u.Value, true
+		}
+	}
+	return nil, false
+}
+
+// set replaces or adds the entry under key.
+func set(unknowns []*btcpsbt.Unknown, key, value []byte) []*btcpsbt.Unknown {
+	unknowns = remove(unknowns, key)
+	return append(unknowns, &btcpsbt.Unknown{Key: key, Value: value})
+}
+
+// remove drops any entry under key.
+func remove(unknowns []*btcpsbt.Unknown, key []byte) []*btcpsbt.Unknown {
+	kept := unknowns[:0]
+	for _, u := range unknowns {
+		if !bytes.Equal(u.Key, key) {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// SetInputAsset records that input i spends an output holding v.
+func SetInputAsset(p *btcpsbt.Packet, i int, v AssetValue) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return fmt.Errorf("input %d out of range", i)
+	}
+	in := &p.Inputs[i]
+	in.Unknowns = set(in.Unknowns, proprietaryKey(PSBT_SYS_IN_ASSET), v.encode())
+	return nil
+}
+
+// InputAsset returns the asset recorded for input i.  ok is false when the
+// input holds no asset.
+func InputAsset(p *btcpsbt.Packet, i int) (v AssetValue, ok bool, err error) {
+	if i < 0 || i >= len(p.Inputs) {
+		return v, false, fmt.Errorf("input %d out of range", i)
+	}
+	b, ok := get(p.Inputs[i].Unknowns, proprietaryKey(PSBT_SYS_IN_ASSET))
+	if !ok {
+		return v, false, nil
+	}
+	v, err = decodeAssetValue(b)
+	if err != nil {
+		return v, false, fmt.Errorf("input %d: %w", i, err)
+	}
+	return v, true, nil
+}
+
+// SetOutputAsset records that output i is allocated v.
+func SetOutputAsset(p *btcpsbt.Packet, i int, v AssetValue) error {
+	if i < 0 || i >= len(p.Outputs) {
+		return fmt.Errorf("output %d out of range", i)
+	}
+	out := &p.Outputs[i]
+	out.Unknowns = set(out.Unknowns, proprietaryKey(PSBT_SYS_OUT_ASSET), v.encode())
+	return nil
+}
+
+// OutputAsset returns the asset recorded for output i.  ok is false when the
+// output is allocated no asset.
+func OutputAsset(p *btcpsbt.Packet, i int) (v AssetValue, ok bool, err error) {
+	if i < 0 || i >= len(p.Outputs) {
+		return v, false, fmt.Errorf("output %d out of range", i)
+	}
+	b, ok := get(p.Outputs[i].Unknowns, proprietaryKey(PSBT_SYS_OUT_ASSET))
+	if !ok {
+		return v, false, nil
+	}
+	v, err = decodeAssetValue(b)
+	if err != nil {
+		return v, false, fmt.Errorf("output %d: %w", i, err)
+	}
+	return v, true, nil
+}
+
+// SetPayload records the serialized payload of the OP_RETURN output.
+func SetPayload(p *btcpsbt.Packet, payload wire.Payload) error {
+	var buf bytes.Buffer
+	if err := payload.Serialize(&buf); err != nil {
+		return err
+	}
+	p.Unknowns = set(p.Unknowns, proprietaryKey(PSBT_SYS_GLOBAL_PAYLOAD), buf.Bytes())
+	return nil
+}
+
+// Payload decodes the recorded payload with the type registered for the
+// unsigned transaction's version.  ok is false when no payload is recorded.
+func Payload(p *btcpsbt.Packet) (payload wire.Payload, ok bool, err error) {
+	b, ok := get(p.Unknowns, proprietaryKey(PSBT_SYS_GLOBAL_PAYLOAD))
+	if !ok {
+		return nil, false, nil
+	}
+	payload, err = wire.NewPayloadForTxVersion(p.UnsignedTx.Version)
+	if err != nil {
+		return nil, false, err
+	}
+	r := bytes.NewReader(b)
+	if err := payload.Deserialize(r); err != nil {
+		return nil, false, fmt.Errorf("recorded payload: %w", err)
+	}
+	if r.Len() != 0 {
+		return nil, false, fmt.Errorf("recorded payload has %d trailing bytes", r.Len())
+	}
+	return payload, true, nil
+}
+
+// AttachAllocation records the asset information of an asset transaction:
+// the payload decoded from the OP_RETURN output of the unsigned transaction,
+// the asset each output is allocated, and inputs, the asset held by the
+// output each input spends, nil for inputs holding none.  Stale output
+// entries are removed.
+func AttachAllocation(p *btcpsbt.Packet, inputs []*AssetValue) error {
+	tx := p.UnsignedTx
+	if !wire.IsAssetTxVersion(tx.Version) {
+		return fmt.Errorf("tx version %d is not an asset transaction", tx.Version)
+	}
+	if len(inputs) != len(p.Inputs) {
+		return fmt.Errorf("got assets for %d inputs, transaction has %d", len(inputs), len(p.Inputs))
+	}
+	payload, err := wire.DecodeTxPayload(tx)
+	if err != nil {
+		return err
+	}
+	alloc, err := wire.AllocationOf(payload)
+	if err != nil {
+		return err
+	}
+	for i := range p.Outputs {
+		p.Outputs[i].Unknowns = remove(p.Outputs[i].Unknowns, proprietaryKey(PSBT_SYS_OUT_ASSET))
+	}
+	for _, out := range alloc.VoutAssets {
+		for _, v := range out.Values {
+			err := SetOutputAsset(p, int(v.N), AssetValue{out.AssetGuid, wire.AssetAmount(v.ValueSat)})
+			if err != nil {
+				return fmt.Errorf("asset %d: %w", out.AssetGuid, err)
+			}
+		}
+	}
+	for i, v := range inputs {
+		key := proprietaryKey(PSBT_SYS_IN_ASSET)
+		if v == nil {
+			p.Inputs[i].Unknowns = remove(p.Inputs[i].Unknowns, key)
+			continue
+		}
+		if err := SetInputAsset(p, i, *v); err != nil {
+			return err
+		}
+	}
+	return SetPayload(p, payload)
+}
+
+// OutputSummary describes the asset allocated to one output.
+type OutputSummary struct {
+	Index  int
+	Asset  AssetValue
+	Burned bool
+}
+
+// Summary is what an asset transaction moves, for display by signers.
+type Summary struct {
+	TxVersion int32
+	Outputs   []OutputSummary
+	Flow      *wire.AllocationFlow
+}
+
+// VerifyAllocation checks the recorded asset information against the
+// unsigned transaction and returns what it moves.  It fails unless the
+// recorded payload is the one in the OP_RETURN output, every output's entry
+// matches the allocation, and the recorded input assets satisfy
+// wire.CheckAllocationConservation.  A signer should check the input assets
+// against its own view of the outputs being spent; a PSBT cannot prove
+// them.
+func VerifyAllocation(p *btcpsbt.Packet) (*Summary, error) {
+	tx := p.UnsignedTx
+	data, dataOut, ok := wire.GetSyscoinData(tx)
+	if !ok {
+		return nil, errors.New("transaction has no payload output")
+	}
+	recorded, ok := get(p.Unknowns, proprietaryKey(PSBT_SYS_GLOBAL_PAYLOAD))
+	if !ok {
+		return nil, errors.New("PSBT records no payload")
+	}
+	if !bytes.Equal(recorded, data) {
+		return nil, errors.New("recorded payload differs from the OP_RETURN output")
+	}
+	payload, err := wire.DecodeTxPayload(tx)
+	if err != nil {
+		return nil, err
+	}
+	alloc, err := wire.AllocationOf(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	want := make(map[int]AssetValue)
+	for _, out := range alloc.VoutAssets {
+		for _, v := range out.Values {
+			want[int(v.N)] = AssetValue{out.AssetGuid, wire.AssetAmount(v.ValueSat)}
+		}
+	}
+	summary := &Summary{TxVersion: tx.Version}
+	for i := range p.Outputs {
+		got, ok, err := OutputAsset(p, i)
+		if err != nil {
+			return nil, err
+		}
+		w, allocated := want[i]
+		if ok != allocated || got != w {
+			return nil, fmt.Errorf("output %d records asset %+v, allocation has %+v", i, got, w)
+		}
+		if ok {
+			summary.Outputs = append(summary.Outputs, OutputSummary{Index: i, Asset: got, Burned: i == dataOut})
+		}
+	}
+
+	inputs := make(wire.AssetBalances)
+	for i := range p.Inputs {
+		v, ok, err := InputAsset(p, i)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if err := inputs.Add(v.AssetGuid, v.Amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if summary.Flow, err = wire.CheckAllocationConservation(tx, alloc, inputs); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
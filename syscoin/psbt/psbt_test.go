@@ -0,0 +1,147 @@
+package psbt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	btcpsbt "github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+func sendPacket(t *testing.T) *btcpsbt.Packet {
+	t.Helper()
+	alloc := &wire.AssetAllocationType{VoutAssets: []wire.AssetOutType{{
+		AssetGuid: 123456,
+		Values:    []wire.AssetOutValueType{{N: 0, ValueSat: 1000}, {N: 1, ValueSat: 250}},
+	}}}
+	var buf bytes.Buffer
+	if err := alloc.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.NullDataScript(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := btcwire.NewMsgTx(wire.SYSCOIN_TX_VERSION_ALLOCATION_SEND)
+	tx.AddTxIn(btcwire.NewTxIn(&btcwire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	tx.AddTxIn(btcwire.NewTxIn(&btcwire.OutPoint{Hash: chainhash.Hash{2}}, nil, nil))
+	tx.AddTxOut(btcwire.NewTxOut(600, []byte{txscript.OP_DATA_1, 1}))
+	tx.AddTxOut(btcwire.NewTxOut(600, []byte{txscript.OP_DATA_1, 2}))
+	tx.AddTxOut(btcwire.NewTxOut(0, script))
+	p, err := btcpsbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// roundTrip serializes and parses p, as when a PSBT is passed to a signer.
+func roundTrip(t *testing.T, p *btcpsbt.Packet) *btcpsbt.Packet {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := btcpsbt.NewFromRawBytes(&buf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestAttachAndVerifyAllocation(t *testing.T) {
+	p := sendPacket(t)
+	inputs := []*AssetValue{{AssetGuid: 123456, Amount: 1250}, nil}
+	if err := AttachAllocation(p, inputs); err != nil {
+		t.Fatal(err)
+	}
+	p = roundTrip(t, p)
+
+	summary, err := VerifyAllocation(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OutputSummary{
+		{Index: 0, Asset: AssetValue{123456, 1000}},
+		{Index: 1, Asset: AssetValue{123456, 250}},
+	}
+	if len(summary.Outputs) != 2 || summary.Outputs[0] != want[0] || summary.Outputs[1] != want[1] {
+		t.Errorf("Outputs = %+v, want %+v", summary.Outputs, want)
+	}
+	if len(summary.Flow.Burned) != 0 || summary.TxVersion != wire.SYSCOIN_TX_VERSION_ALLOCATION_SEND {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if v, ok, err := InputAsset(p, 1); ok || err != nil {
+		t.Errorf("InputAsset(1) = %+v, %v, %v", v, ok, err)
+	}
+	payload, ok, err := Payload(p)
+	if err != nil || !ok {
+		t.Fatalf("Payload = %v, %v", ok, err)
+	}
+	if alloc := payload.(*wire.AssetAllocationType); len(alloc.VoutAssets) != 1 {
+		t.Errorf("Payload = %+v", alloc)
+	}
+
+	asset := &wire.AssetType{Symbol: []byte("USDT"), Precision: 2}
+	if got := summary.Outputs[0].Asset.Format(asset); got != "10.00 USDT" {
+		t.Errorf("Format = %q", got)
+	}
+}
+
+func TestVerifyAllocationRejectsTampering(t *testing.T) {
+	inputs := []*AssetValue{{AssetGuid: 123456, Amount: 1250}, nil}
+
+	p := sendPacket(t)
+	if err := AttachAllocation(p, inputs); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetOutputAsset(p, 1, AssetValue{123456, 249}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAllocation(roundTrip(t, p)); err == nil {
+		t.Error("expected a mismatched output entry to be rejected")
+	}
+
+	p = sendPacket(t)
+	if err := AttachAllocation(p, inputs); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetInputAsset(p, 1, AssetValue{123456, 1}); err != nil {
+		t.Fatal(err)
+	}
+	var cerr *wire.ConservationError
+	if _, err := VerifyAllocation(roundTrip(t, p)); !errors.As(err, &cerr) {
+		t.Errorf("got %v, want a conservation error", err)
+	}
+
+	p = sendPacket(t)
+	if err := AttachAllocation(p, inputs); err != nil {
+		t.Fatal(err)
+	}
+	other := &wire.AssetAllocationType{}
+	if err := SetPayload(p, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAllocation(roundTrip(t, p)); err == nil {
+		t.Error("expected a payload differing from the OP_RETURN output to be rejected")
+	}
+
+	if _, err := VerifyAllocation(sendPacket(t)); err == nil {
+		t.Error("expected a PSBT without asset information to be rejected")
+	}
+	if err := AttachAllocation(sendPacket(t), inputs[:1]); err == nil {
+		t.Error("expected a short input list to be rejected")
+	}
+}
+
+func TestProprietaryKey(t *testing.T) {
+	// 0xFC, identifier length and "SYS", then the subtype.
+	want := []byte{0xfc, 3, 'S', 'Y', 'S', PSBT_SYS_OUT_ASSET}
+	if got := proprietaryKey(PSBT_SYS_OUT_ASSET); !bytes.Equal(got, want) {
+		t.Errorf("proprietaryKey = %x, want %x", got, want)
+	}
+}
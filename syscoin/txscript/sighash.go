@@ -0,0 +1,98 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package txscript computes signature hashes for Syscoin transactions and
+// signs them offline.
+//
+// syscoind inherits Bitcoin Core's signature hashing unchanged: the legacy
+// algorithm, BIP 143 for segwit v0 and BIP 341 for taproot.  The transaction
+// version is committed to as a plain 32-bit integer, so the asset versions
+// (128 to 135) and the OP_RETURN payload output need no special treatment,
+// and btcd's txscript produces matching hashes.  This package wraps it with
+// the bookkeeping a Syscoin wallet needs: the previous outputs of every input
+// and a signer for the standard script types.
+package txscript
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+)
+
+// SigHasher computes the signature hashes of one transaction's inputs.  The
+// midstate shared by segwit and taproot inputs is computed once.
+type SigHasher struct {
+	tx        *btcwire.MsgTx
+	prevOuts  []*btcwire.TxOut
+	fetcher   *txscript.MultiPrevOutFetcher
+	sigHashes *txscript.TxSigHashes
+}
+
+// NewSigHasher returns a SigHasher for tx, whose inputs spend prevOuts in
+// order.  Taproot hashes commit to every previous output, so all of them
+// must be given.
+func NewSigHasher(tx *btcwire.MsgTx, prevOuts []*btcwire.TxOut) (*SigHasher, error) {
+	if len(prevOuts) != len(tx.TxIn) {
+		return nil, fmt.Errorf("got %d previous outputs for %d inputs", len(prevOuts), len(tx.TxIn))
+	}
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range tx.TxIn {
+		if prevOuts[i] == nil {
+			return nil, fmt.Errorf("previous output of input %d is missing", i)
+		}
+		fetcher.AddPrevOut(in.PreviousOutPoint, prevOuts[i])
+	}
+	return &SigHasher{
+		tx:        tx,
+		prevOuts:  prevOuts,
+		fetcher:   fetcher,
+		sigHashes: txscript.NewTxSigHashes(tx, fetcher),
+	}, nil
+}
+
+func (h *SigHasher) checkIndex(idx int) error {
+	if idx < 0 || idx >= len(h.tx.TxIn) {
+		return fmt.Errorf("input %d out of range", idx)
+	}
+	return nil
+}
+
+// LegacySigHash returns the pre-segwit signature hash of input idx, which
+// executes subScript.
+func (h *SigHasher) LegacySigHash(idx int, subScript []byte, hashType txscript.SigHashType) ([]byte, error) {
+	if err := h.checkIndex(idx); err != nil {
+		return nil, err
+	}
+	return txscript.CalcSignatureHash(subScript, hashType, h.tx, idx)
+}
+
+// WitnessV0SigHash returns the BIP 143 signature hash of input idx, which
+// executes scriptCode: the P2PKH script of the key for P2WPKH, or the
+// witness script for P2WSH.
+func (h *SigHasher) WitnessV0SigHash(idx int, scriptCode []byte, hashType txscript.SigHashType) ([]byte, error) {
+	if err := h.checkIndex(idx); err != nil {
+		return nil, err
+	}
+	return txscript.CalcWitnessSigHash(scriptCode, h.sigHashes, hashType, h.tx, idx,
+		h.prevOuts[idx].Value)
+}
+
+// TaprootKeySpendSigHash returns the BIP 341 signature hash of a key path
+// spend of input idx.
+func (h *SigHasher) TaprootKeySpendSigHash(idx int, hashType txscript.SigHashType) ([]byte, error) {
+	if err := h.checkIndex(idx); err != nil {
+		return nil, err
+	}
+	return txscript.CalcTaprootSignatureHash(h.sigHashes, hashType, h.tx, idx, h.fetcher)
+}
+
+// TaprootScriptSpendSigHash returns the BIP 342 signature hash of a script
+// path spend of input idx through leaf.
+func (h *SigHasher) TaprootScriptSpendSigHash(idx int, leaf txscript.TapLeaf, hashType txscript.SigHashType) ([]byte, error) {
+	if err := h.checkIndex(idx); err != nil {
+		return nil, err
+	}
+	return txscript.CalcTapscriptSignaturehash(h.sigHashes, hashType, h.tx, idx, h.fetcher, leaf)
+}
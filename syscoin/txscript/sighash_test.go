@@ -0,0 +1,471 @@
+package txscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// testKey derives a deterministic private key from seed.
+func testKey(seed string) *btcec.PrivateKey {
+	h := sha256.Sum256([]byte(seed))
+	key, _ := btcec.PrivKeyFromBytes(h[:])
+	return key
+}
+
+func p2pkhScript(key *btcec.PrivateKey) []byte {
+	script, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(key.PubKey().SerializeCompressed())).
+		AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+	return script
+}
+
+func p2wpkhScript(key *btcec.PrivateKey) []byte {
+	script, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).
+		AddData(btcutil.Hash160(key.PubKey().SerializeCompressed())).Script()
+	return script
+}
+
+func p2trScript(key *btcec.PrivateKey) []byte {
+	script, _ := txscript.PayToTaprootScript(txscript.ComputeTaprootKeyNoScript(key.PubKey()))
+	return script
+}
+
+var (
+	keyLegacy  = testKey("legacy")
+	keySegwit  = testKey("segwit")
+	keyTaproot = testKey("taproot")
+)
+
+// assetTestTx returns a transaction of the given asset version spending a
+// P2PKH, a P2WPKH and a P2TR output, with an OP_RETURN output carrying a
+// payload of the version's type.
+func assetTestTx(t *testing.T, version int32) (*btcwire.MsgTx, []*btcwire.TxOut) {
+	t.Helper()
+	alloc := wire.AssetAllocationType{VoutAssets: []wire.AssetOutType{{
+		AssetGuid: 123456,
+		Values:    []wire.AssetOutValueType{{N: 0, ValueSat: 1000}, {N: 2, ValueSat: 50}},
+	}}}
+	var payload wire.Payload
+	switch version {
+	case wire.SYSCOIN_TX_VERSION_ALLOCATION_SEND:
+		payload = &alloc
+	case wire.SYSCOIN_TX_VERSION_ALLOCATION_MINT:
+		payload = &wire.MintSyscoinType{Allocation: alloc, TxHash: make([]byte, 32), BlockHash: make([]byte, 32)}
+	default:
+		payload = &wire.SyscoinBurnToEthereumType{Allocation: alloc, EthAddress: bytes.Repeat([]byte{0xee}, 20)}
+	}
+	var buf bytes.Buffer
+	if err := payload.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(buf.Bytes()).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := btcwire.NewMsgTx(version)
+	for i := byte(1); i <= 3; i++ {
+		in := btcwire.NewTxIn(&btcwire.OutPoint{Hash: chainhash.Hash{i}, Index: uint32(i)}, nil, nil)
+		in.Sequence = btcwire.MaxTxInSequenceNum - uint32(i)
+		tx.AddTxIn(in)
+	}
+	tx.AddTxOut(btcwire.NewTxOut(980, p2wpkhScript(testKey("recipient"))))
+	tx.AddTxOut(btcwire.NewTxOut(150000, p2trScript(testKey("change"))))
+	tx.AddTxOut(btcwire.NewTxOut(0, data))
+	tx.LockTime = 500
+
+	prevOuts := []*btcwire.TxOut{
+		btcwire.NewTxOut(100000, p2pkhScript(keyLegacy)),
+		btcwire.NewTxOut(50000, p2wpkhScript(keySegwit)),
+		btcwire.NewTxOut(2000, p2trScript(keyTaproot)),
+	}
+	return tx, prevOuts
+}
+
+func dsha(b []byte) []byte {
+	return chainhash.DoubleHashB(b)
+}
+
+func ssha(b []byte) []byte {
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+// refLegacySigHash is an independent implementation of the legacy
+// SIGHASH_ALL algorithm.
+func refLegacySigHash(tx *btcwire.MsgTx, idx int, subScript []byte) []byte {
+	c := tx.Copy()
+	for i := range c.TxIn {
+		c.TxIn[i].SignatureScript = nil
+		c.TxIn[i].Witness = nil
+	}
+	c.TxIn[idx].SignatureScript = subScript
+	var buf bytes.Buffer
+	c.SerializeNoWitness(&buf)
+	binary.Write(&buf, binary.LittleEndian, uint32(txscript.SigHashAll))
+	return dsha(buf.Bytes())
+}
+
+// refWitnessV0SigHash is an independent implementation of BIP 143 with
+// SIGHASH_ALL.
+func refWitnessV0SigHash(tx *btcwire.MsgTx, idx int, scriptCode []byte, amount int64) []byte {
+	var prevouts, sequences, outputs, pre bytes.Buffer
+	for _, in := range tx.TxIn {
+		prevouts.Write(in.PreviousOutPoint.Hash[:])
+		binary.Write(&prevouts, binary.LittleEndian, in.PreviousOutPoint.Index)
+		binary.Write(&sequences, binary.LittleEndian, in.Sequence)
+	}
+	for _, out := range tx.TxOut {
+		btcwire.WriteTxOut(&outputs, 0, 0, out)
+	}
+	in := tx.TxIn[idx]
+	binary.Write(&pre, binary.LittleEndian, tx.Version)
+	pre.Write(dsha(prevouts.Bytes()))
+	pre.Write(dsha(sequences.Bytes()))
+	pre.Write(in.PreviousOutPoint.Hash[:])
+	binary.Write(&pre, binary.LittleEndian, in.PreviousOutPoint.Index)
+	btcwire.WriteVarBytes(&pre, 0, scriptCode)
+	binary.Write(&pre, binary.LittleEndian, amount)
+	binary.Write(&pre, binary.LittleEndian, in.Sequence)
+	pre.Write(dsha(outputs.Bytes()))
+	binary.Write(&pre, binary.LittleEndian, tx.LockTime)
+	binary.Write(&pre, binary.LittleEndian, uint32(txscript.SigHashAll))
+	return dsha(pre.Bytes())
+}
+
+// refTaprootKeySpendSigHash is an independent implementation of the BIP 341
+// key path hash with SIGHASH_DEFAULT and no annex.
+func refTaprootKeySpendSigHash(tx *btcwire.MsgTx, idx int, prevOuts []*btcwire.TxOut) []byte {
+	var prevouts, amounts, scripts, sequences, outputs, msg bytes.Buffer
+	for i, in := range tx.TxIn {
+		prevouts.Write(in.PreviousOutPoint.Hash[:])
+		binary.Write(&prevouts, binary.LittleEndian, in.PreviousOutPoint.Index)
+		binary.Write(&amounts, binary.LittleEndian, prevOuts[i].Value)
+		btcwire.WriteVarBytes(&scripts, 0, prevOuts[i].PkScript)
+		binary.Write(&sequences, binary.LittleEndian, in.Sequence)
+	}
+	for _, out := range tx.TxOut {
+		btcwire.WriteTxOut(&outputs, 0, 0, out)
+	}
+	msg.WriteByte(0) // epoch
+	msg.WriteByte(byte(txscript.SigHashDefault))
+	binary.Write(&msg, binary.LittleEndian, tx.Version)
+	binary.Write(&msg, binary.LittleEndian, tx.LockTime)
+	msg.Write(ssha(prevouts.Bytes()))
+	msg.Write(ssha(amounts.Bytes()))
+	msg.Write(ssha(scripts.Bytes()))
+	msg.Write(ssha(sequences.Bytes()))
+	msg.Write(ssha(outputs.Bytes()))
+	msg.WriteByte(0) // spend type: key path, no annex
+	binary.Write(&msg, binary.LittleEndian, uint32(idx))
+	return chainhash.TaggedHash(chainhash.TagTapSighash, msg.Bytes())[:]
+}
+
+// sighashVectors holds the SIGHASH_ALL (legacy and segwit v0) and
+// SIGHASH_DEFAULT (taproot) hashes of the three inputs of assetTestTx for
+// each asset version.  They were recorded from this package and agree with
+// the reference implementations above, so they only guard against
+// regressions; TestCoreSigHashVectors and TestCoreSignatures tie the
+// algorithms to known answers from Bitcoin Core, which syscoind inherits.
+var sighashVectors = []struct {
+	version                 int32
+	legacy, segwit, taproot string
+}{
+	{wire.SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_SYSCOIN,
+		"76e080040eb4134ec976f8ead93221de5a9aa1c5867db4e211236de9febb4305",
+		"7646d5794ab2e9e9ac7ff6b3ca496ffc7c1a7c1d9ade765445c5b3b1402e6433",
+		"9da7c8738cfd57211194124c78099e286e0774e9d13e21fb432c1f428e7e7079"},
+	{wire.SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION,
+		"f43db4794f852120fe1808cb92b2c54800d9ece54b5ede0b03c19929da62b72d",
+		"c113a835056f6be01532acceceb6a4a68503db26ce232017113b598f4b3f7a33",
+		"7e7cd1c3acd68ad914fc35a152c292ea78567a1c535dcce9d29a7cd128c4fb84"},
+	{wire.SYSCOIN_TX_VERSION_ALLOCATION_MINT,
+		"15e61e0068c35a6922c3a0cfb73ce6508689d52734b5efde702645ebf868285f",
+		"867aab809056b7be1f2d34b3317f7ed2eda985a59ed2086a5e141e8abb24706e",
+		"87eb71b6a16a80457858b01de00c26dcab0593881a4a35b0ced36b7095cc01b4"},
+	{wire.SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM,
+		"8566f104424b1a4f6cfaca99b614c76083b6737b325150b259e5768fdce6989e",
+		"57815c5bfea709903ad7b10c26768666a5608f06d7c93a65a28d80505107e8d7",
+		"38462d3cfb5bb03ab8bcc670856f6ad05ad269171b9c5a359e0194d5d2ebeac9"},
+	{wire.SYSCOIN_TX_VERSION_ALLOCATION_SEND,
+		"dbdb0b601d43eeb6100b051979abbd40400c31e15854bc312e4af34ca869e154",
+		"54b3c3b57fcfb911ed418f2ff81b29ce232ef55b77f4d1d153a7e6a201f97c5b",
+		"c865e25bbd20f9e388c5a69656539c81496e12a2f2485ec025a3bf8af7be5dab"},
+}
+
+func TestSigHashVectors(t *testing.T) {
+	for _, v := range sighashVectors {
+		t.Run(fmt.Sprint(v.version), func(t *testing.T) {
+			tx, prevOuts := assetTestTx(t, v.version)
+			h, err := NewSigHasher(tx, prevOuts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check := func(name string, got []byte, err error, ref []byte, want string) {
+				t.Helper()
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if hex.EncodeToString(got) != want {
+					t.Errorf("%s = %x, want %s", name, got, want)
+				}
+				if !bytes.Equal(ref, got) {
+					t.Errorf("%s differs from the reference: %x", name, ref)
+				}
+			}
+			got, err := h.LegacySigHash(0, prevOuts[0].PkScript, txscript.SigHashAll)
+			check("legacy", got, err, refLegacySigHash(tx, 0, prevOuts[0].PkScript), v.legacy)
+			got, err = h.WitnessV0SigHash(1, p2pkhScript(keySegwit), txscript.SigHashAll)
+			check("segwit", got, err, refWitnessV0SigHash(tx, 1, p2pkhScript(keySegwit), prevOuts[1].Value), v.segwit)
+			got, err = h.TaprootKeySpendSigHash(2, txscript.SigHashDefault)
+			check("taproot", got, err, refTaprootKeySpendSigHash(tx, 2, prevOuts), v.taproot)
+		})
+	}
+}
+
+// TestCoreSigHashVectors checks the legacy algorithm against Bitcoin Core's
+// sighash.json.  Its transactions have random versions, arbitrary scripts
+// and random hash types, so it also covers versions outside Bitcoin's.
+func TestCoreSigHashVectors(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "sighash.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests [][]interface{}
+	if err := json.Unmarshal(raw, &tests); err != nil {
+		t.Fatal(err)
+	}
+	checked := 0
+	for i, test := range tests {
+		if len(test) != 5 {
+			continue // comment
+		}
+		txBytes, _ := hex.DecodeString(test[0].(string))
+		script, _ := hex.DecodeString(test[1].(string))
+		idx := int(test[2].(float64))
+		hashType := txscript.SigHashType(uint32(int32(test[3].(float64))))
+		want, err := chainhash.NewHashFromStr(test[4].(string))
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		var tx btcwire.MsgTx
+		if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		prevOuts := make([]*btcwire.TxOut, len(tx.TxIn))
+		for j := range prevOuts {
+			prevOuts[j] = btcwire.NewTxOut(0, nil)
+		}
+		h, err := NewSigHasher(&tx, prevOuts)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		got, err := h.LegacySigHash(idx, script, hashType)
+		if err != nil {
+			// Core hashes scripts btcd cannot parse; those are
+			// never executed, so there is nothing to sign.
+			continue
+		}
+		if !bytes.Equal(got, want[:]) {
+			t.Errorf("vector %d: sighash %x, want %x", i, got, want[:])
+		}
+		checked++
+	}
+	if checked < len(tests)*9/10 {
+		t.Errorf("only %d of %d vectors checked", checked, len(tests))
+	}
+}
+
+// coreSpend is a successful input spend recorded by Bitcoin Core's
+// feature_taproot.py; see testdata/README.md.
+type coreSpend struct {
+	Comment   string   `json:"comment"`
+	Kind      string   `json:"kind"`
+	Tx        string   `json:"tx"`
+	PrevOuts  []string `json:"prevouts"`
+	Index     int      `json:"index"`
+	ScriptSig string   `json:"scriptSig"`
+	Witness   []string `json:"witness"`
+}
+
+// TestCoreSignatures verifies signatures made by Bitcoin Core over P2PKH,
+// P2WPKH, P2SH-P2WPKH and taproot key path inputs against the hashes this
+// package computes, which ties the legacy, BIP 143 and BIP 341 algorithms
+// and every hash type to a known answer.
+func TestCoreSignatures(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "taproot_ref_spends.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spends []coreSpend
+	if err := json.Unmarshal(raw, &spends); err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]int)
+	for _, s := range spends {
+		t.Run(s.Comment, func(t *testing.T) {
+			var tx btcwire.MsgTx
+			if err := tx.Deserialize(bytes.NewReader(mustDecodeHex(t, s.Tx))); err != nil {
+				t.Fatal(err)
+			}
+			prevOuts := make([]*btcwire.TxOut, len(s.PrevOuts))
+			for i, p := range s.PrevOuts {
+				r := bytes.NewReader(mustDecodeHex(t, p))
+				prevOuts[i] = new(btcwire.TxOut)
+				if err := btcwire.ReadTxOut(r, 0, 0, prevOuts[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h, err := NewSigHasher(&tx, prevOuts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkScript := prevOuts[s.Index].PkScript
+
+			if s.Kind == "taproot" {
+				sig := mustDecodeHex(t, s.Witness[0])
+				hashType := txscript.SigHashDefault
+				if len(sig) == 65 {
+					hashType = txscript.SigHashType(sig[64])
+				}
+				got, err := h.TaprootKeySpendSigHash(s.Index, hashType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				key, err := schnorr.ParsePubKey(pkScript[2:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				parsed, err := schnorr.ParseSignature(sig[:64])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !parsed.Verify(got, key) {
+					t.Errorf("Core's signature does not verify against sighash %x", got)
+				}
+				kinds[s.Kind]++
+				return
+			}
+
+			// The ECDSA kinds carry a signature and a public key.
+			var sig, pubKey []byte
+			if s.Kind == "p2pkh" {
+				pushes, err := txscript.PushedData(mustDecodeHex(t, s.ScriptSig))
+				if err != nil || len(pushes) != 2 {
+					t.Fatalf("scriptSig pushes %d items, %v", len(pushes), err)
+				}
+				sig, pubKey = pushes[0], pushes[1]
+			} else {
+				sig, pubKey = mustDecodeHex(t, s.Witness[0]), mustDecodeHex(t, s.Witness[1])
+			}
+			hashType := txscript.SigHashType(sig[len(sig)-1])
+			var got []byte
+			if s.Kind == "p2pkh" {
+				got, err = h.LegacySigHash(s.Index, pkScript, hashType)
+			} else {
+				scriptCode, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).
+					AddOp(txscript.OP_HASH160).AddData(btcutil.Hash160(pubKey)).
+					AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+				got, err = h.WitnessV0SigHash(s.Index, scriptCode, hashType)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := btcec.ParsePubKey(pubKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := ecdsa.ParseDERSignature(sig[:len(sig)-1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsed.Verify(got, key) {
+				t.Errorf("Core's signature does not verify against sighash %x", got)
+			}
+			kinds[s.Kind]++
+		})
+	}
+	for _, kind := range []string{"p2pkh", "p2wpkh", "p2sh-p2wpkh", "taproot"} {
+		if kinds[kind] == 0 {
+			t.Errorf("no %s spend verified", kind)
+		}
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSignTx(t *testing.T) {
+	keys := map[string]*btcec.PrivateKey{
+		string(p2pkhScript(keyLegacy)):  keyLegacy,
+		string(p2wpkhScript(keySegwit)): keySegwit,
+		string(p2trScript(keyTaproot)):  keyTaproot,
+	}
+	keyFunc := func(pkScript []byte) (*btcec.PrivateKey, error) {
+		return keys[string(pkScript)], nil
+	}
+	for _, v := range sighashVectors {
+		tx, prevOuts := assetTestTx(t, v.version)
+		unsigned, err := SignTx(tx, prevOuts, keyFunc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(unsigned) != 0 {
+			t.Errorf("version %d: inputs %v left unsigned", v.version, unsigned)
+		}
+		if err := VerifyTx(tx, prevOuts); err != nil {
+			t.Errorf("version %d: %v", v.version, err)
+		}
+		if len(tx.TxIn[2].Witness) != 1 || len(tx.TxIn[2].Witness[0]) != schnorr.SignatureSize {
+			t.Errorf("version %d: taproot witness %x", v.version, tx.TxIn[2].Witness)
+		}
+
+		// Changing the payload output invalidates every signature.
+		tx.TxOut[2].PkScript = append(tx.TxOut[2].PkScript[:len(tx.TxOut[2].PkScript)-1], 0xff)
+		if err := VerifyTx(tx, prevOuts); err == nil {
+			t.Errorf("version %d: tampered payload still verifies", v.version)
+		}
+	}
+
+	tx, prevOuts := assetTestTx(t, wire.SYSCOIN_TX_VERSION_ALLOCATION_SEND)
+	delete(keys, string(p2wpkhScript(keySegwit)))
+	unsigned, err := SignTx(tx, prevOuts, keyFunc)
+	if err != nil || len(unsigned) != 1 || unsigned[0] != 1 {
+		t.Errorf("SignTx = %v, %v, want input 1 unsigned", unsigned, err)
+	}
+
+	h, err := NewSigHasher(tx, prevOuts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range prevOuts {
+		if err := h.SignInput(i, testKey("other"), txscript.SigHashAll); err == nil {
+			t.Errorf("input %d: expected a mismatched key to be rejected", i)
+		}
+	}
+	if _, err := NewSigHasher(tx, prevOuts[:2]); err == nil {
+		t.Error("expected missing previous outputs to be rejected")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+)
+
+// KeyFunc returns the private key controlling pkScript, or nil if the
+// caller holds none.  For a P2TR output it returns the BIP 86 internal key,
+// whose tweak commits to no script tree.
+type KeyFunc func(pkScript []byte) (*btcec.PrivateKey, error)
+
+// SignInput signs input idx of the hasher's transaction with key, filling in
+// its signature script or witness.  P2PKH and P2WPKH inputs are signed with
+// a compressed key and hashType; P2TR inputs are key path spends, where
+// txscript.SigHashDefault gives the 64-byte signature.  key must match the
+// previous output.
+func (h *SigHasher) SignInput(idx int, key *btcec.PrivateKey, hashType txscript.SigHashType) error {
+	if err := h.checkIndex(idx); err != nil {
+		return err
+	}
+	prevOut := h.prevOuts[idx]
+	in := h.tx.TxIn[idx]
+	pubKeyHash := btcutil.Hash160(key.PubKey().SerializeCompressed())
+
+	switch txscript.GetScriptClass(prevOut.PkScript) {
+	case txscript.PubKeyHashTy:
+		if !bytes.Equal(prevOut.PkScript[3:23], pubKeyHash) {
+			return fmt.Errorf("input %d: key does not match the P2PKH output", idx)
+		}
+		sigScript, err := txscript.SignatureScript(h.tx, idx, prevOut.PkScript, hashType, key, true)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", idx, err)
+		}
+		in.SignatureScript = sigScript
+
+	case txscript.WitnessV0PubKeyHashTy:
+		if !bytes.Equal(prevOut.PkScript[2:], pubKeyHash) {
+			return fmt.Errorf("input %d: key does not match the P2WPKH output", idx)
+		}
+		witness, err := txscript.WitnessSignature(h.tx, h.sigHashes, idx, prevOut.Value,
+			prevOut.PkScript, hashType, key, true)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", idx, err)
+		}
+		in.SignatureScript = nil
+		in.Witness = witness
+
+	case txscript.WitnessV1TaprootTy:
+		outputKey := txscript.ComputeTaprootKeyNoScript(key.PubKey())
+		if !bytes.Equal(prevOut.PkScript[2:], schnorr.SerializePubKey(outputKey)) {
+			return fmt.Errorf("input %d: key does not match the P2TR output", idx)
+		}
+		witness, err := txscript.TaprootWitnessSignature(h.tx, h.sigHashes, idx, prevOut.Value,
+			prevOut.PkScript, hashType, key)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", idx, err)
+		}
+		in.SignatureScript = nil
+		in.Witness = witness
+
+	default:
+		return fmt.Errorf("input %d: cannot sign script class %v", idx, txscript.GetScriptClass(prevOut.PkScript))
+	}
+	return nil
+}
+
+// SignTx signs every input of tx, which spends prevOuts in order, whose key
+// keys returns, with SIGHASH_ALL, or SIGHASH_DEFAULT for taproot.  It
+// returns the indexes of the inputs left unsigned because keys holds no key
+// for them.  tx may be produced by any builder, including an asset
+// transaction with its OP_RETURN payload already in place; the payload is
+// covered like any other output.
+func SignTx(tx *btcwire.MsgTx, prevOuts []*btcwire.TxOut, keys KeyFunc) ([]int, error) {
+	h, err := NewSigHasher(tx, prevOuts)
+	if err != nil {
+		return nil, err
+	}
+	var unsigned []int
+	for i, prevOut := range prevOuts {
+		key, err := keys(prevOut.PkScript)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		if key == nil {
+			unsigned = append(unsigned, i)
+			continue
+		}
+		hashType := txscript.SigHashAll
+		if txscript.IsPayToTaproot(prevOut.PkScript) {
+			hashType = txscript.SigHashDefault
+		}
+		if err := h.SignInput(i, key, hashType); err != nil {
+			return nil, err
+		}
+	}
+	return unsigned, nil
+}
+
+// VerifyTx executes the scripts of every input of tx, which spends prevOuts
+// in order, with btcd's standard verification flags.
+func VerifyTx(tx *btcwire.MsgTx, prevOuts []*btcwire.TxOut) error {
+	h, err := NewSigHasher(tx, prevOuts)
+	if err != nil {
+		return err
+	}
+	for i, prevOut := range prevOuts {
+		vm, err := txscript.NewEngine(prevOut.PkScript, tx, i, txscript.StandardVerifyFlags,
+			nil, h.sigHashes, prevOut.Value, h.fetcher)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+		if err := vm.Execute(); err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// AssetBalances maps asset GUIDs to amounts.
+type AssetBalances map[uint64]AssetAmount
+
+// Add adds amount to the balance of guid, failing if the balance would
+// leave [0, MAX_ASSET].
+func (b AssetBalances) Add(guid uint64, amount AssetAmount) error {
+	sum, err := b[guid].Add(amount)
+	if err != nil {
+		return fmt.Errorf("asset %d: %w", guid, err)
+	}
+	b[guid] = sum
+	return nil
+}
+
+// Guids returns the GUIDs in b in ascending order.
+func (b AssetBalances) Guids() []uint64 {
+	guids := make([]uint64, 0, len(b))
+	for guid := range b {
+		guids = append(guids, guid)
+	}
+	sort.Slice(guids, func(i, j int) bool { return guids[i] < guids[j] })
+	return guids
+}
+
+// Totals sums the allocation's output values per asset GUID.
+func (a *AssetAllocationType) Totals() (AssetBalances, error) {
+	totals := make(AssetBalances, len(a.VoutAssets))
+	for _, out := range a.VoutAssets {
+		for _, v := range out.Values {
+			amount, err := AssetAmountFromOutValue(&v)
+			if err != nil {
+				return nil, fmt.Errorf("asset %d output %d: %w", out.AssetGuid, v.N, err)
+			}
+			if err := totals.Add(out.AssetGuid, amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return totals, nil
+}
+
+// AllocationOf returns the allocation carried by an asset payload: the
+// payload itself for allocation sends, or the allocation embedded in mint
+// and burn payloads.
+func AllocationOf(p Payload) (*AssetAllocationType, error) {
+	switch p := p.(type) {
+	case *AssetAllocationType:
+		return p, nil
+	case *MintSyscoinType:
+		return &p.Allocation, nil
+	case *SyscoinBurnToEthereumType:
+		return &p.Allocation, nil
+	}
+	return nil, fmt.Errorf("payload of kind %s carries no allocation", p.Kind())
+}
+
+// AssetDelta is the change of one asset's balance across a transaction.
+type AssetDelta struct {
+	AssetGuid uint64
+	In        AssetAmount
+	Out       AssetAmount
+}
+
+// Change returns Out-In, which is negative when the asset is burned.
+func (d AssetDelta) Change() int64 {
+	return int64(d.Out) - int64(d.In)
+}
+
+// BalanceDeltas returns the delta of every asset in in or out, ordered by
+// GUID.
+func BalanceDeltas(in, out AssetBalances) []AssetDelta {
+	all := make(AssetBalances, len(in)+len(out))
+	for guid := range in {
+		all[guid] = 0
+	}
+	for guid := range out {
+		all[guid] = 0
+	}
+	deltas := make([]AssetDelta, 0, len(all))
+	for _, guid := range all.Guids() {
+		deltas = append(deltas, AssetDelta{AssetGuid: guid, In: in[guid], Out: out[guid]})
+	}
+	return deltas
+}
+
+// ConservationError describes an asset a transaction does not conserve the
+// way its version requires.
+type ConservationError struct {
+	TxVersion int32
+	Delta     AssetDelta
+	Reason    string
+}
+
+// Error implements the error interface.
+func (e *ConservationError) Error() string {
+	return fmt.Sprintf("tx version %d: asset %d: %s (in %s, out %s)",
+		e.TxVersion, e.Delta.AssetGuid, e.Reason, e.Delta.In, e.Delta.Out)
+}
+
+// AllocationFlow summarizes the asset movement of a transaction that passed
+// CheckAllocationConservation.
+type AllocationFlow struct {
+	// Deltas holds the balance delta of every asset the transaction
+	// touches, ordered by GUID.  Out includes burned amounts.
+	Deltas []AssetDelta
+
+	// Burned holds the amounts assigned to the OP_RETURN output, which
+	// leave the UTXO set.
+	Burned AssetBalances
+
+	// Minted holds the amounts created from SYS or from an NEVM burn.
+	Minted AssetBalances
+}
+
+// CheckAllocationConservation checks that tx, carrying alloc, conserves its
+// input assets the way syscoind requires for tx's version:
+//
+//   - allocation sends move assets without creating or burning any;
+//   - burns to SYS or to NEVM move assets, assigning the burned amount of a
+//     single asset to the OP_RETURN output;
+//   - SYS burns to an allocation create a single asset's amount equal to the
+//     SYS value of the OP_RETURN output;
+//   - NEVM mints create a single asset's amount, whose proof is checked
+//     separately.
+//
+// inputs holds the asset balances of the outputs tx spends.  Every
+// allocation output must exist in tx.  A violation is reported as a
+// *ConservationError when it concerns one asset.
+func CheckAllocationConservation(tx *wire.MsgTx, alloc *AssetAllocationType, inputs AssetBalances) (*AllocationFlow, error) {
+	_, dataOut, ok := GetSyscoinData(tx)
+	if !ok {
+		return nil, fmt.Errorf("transaction %v has no payload output", tx.TxHash())
+	}
+	outputs := make(AssetBalances)
+	burned := make(AssetBalances)
+	seen := make(map[uint32]bool)
+	for _, out := range alloc.VoutAssets {
+		if len(out.Values) == 0 {
+			return nil, fmt.Errorf("asset %d has no output values", out.AssetGuid)
+		}
+		for _, v := range out.Values {
+			if int(v.N) >= len(tx.TxOut) {
+				return nil, fmt.Errorf("asset %d assigned to output %d of %d",
+					out.AssetGuid, v.N, len(tx.TxOut))
+			}
+			if seen[v.N] {
+				return nil, fmt.Errorf("output %d carries more than one asset value", v.N)
+			}
+			seen[v.N] = true
+			amount, err := AssetAmountFromOutValue(&v)
+			if err != nil {
+				return nil, fmt.Errorf("asset %d output %d: %w", out.AssetGuid, v.N, err)
+			}
+			if err := outputs.Add(out.AssetGuid, amount); err != nil {
+				return nil, err
+			}
+			if int(v.N) == dataOut {
+				if err := burned.Add(out.AssetGuid, amount); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	flow := &AllocationFlow{
+		Deltas: BalanceDeltas(inputs, outputs),
+		Burned: burned,
+		Minted: make(AssetBalances),
+	}
+	violation := func(d AssetDelta, reason string) error {
+		return &ConservationError{TxVersion: tx.Version, Delta: d, Reason: reason}
+	}
+	deltaOf := func(guid uint64) AssetDelta {
+		return AssetDelta{AssetGuid: guid, In: inputs[guid], Out: outputs[guid]}
+	}
+
+	switch tx.Version {
+	case SYSCOIN_TX_VERSION_ALLOCATION_SEND:
+		if len(burned) != 0 {
+			return nil, violation(deltaOf(burned.Guids()[0]), "allocation send assigns value to the OP_RETURN output")
+		}
+
+	case SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_SYSCOIN, SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM:
+		if len(burned) != 1 {
+			return nil, fmt.Errorf("tx version %d burns %d assets, want 1", tx.Version, len(burned))
+		}
+		for guid, amount := range burned {
+			if amount == 0 {
+				return nil, violation(deltaOf(guid), "burn of zero")
+			}
+		}
+
+	case SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION, SYSCOIN_TX_VERSION_ALLOCATION_MINT:
+		if len(burned) != 0 {
+			return nil, violation(deltaOf(burned.Guids()[0]), "mint assigns value to the OP_RETURN output")
+		}
+		var minted []AssetDelta
+		for _, d := range flow.Deltas {
+			if d.Change() != 0 {
+				minted = append(minted, d)
+			}
+		}
+		if len(minted) != 1 {
+			return nil, fmt.Errorf("tx version %d changes %d asset balances, want 1", tx.Version, len(minted))
+		}
+		d := minted[0]
+		if d.Change() < 0 {
+			return nil, violation(d, "mint decreases the balance")
+		}
+		if tx.Version == SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION && d.Change() != tx.TxOut[dataOut].Value {
+			return nil, violation(d, fmt.Sprintf("mint does not match the %d satoshis of SYS burned",
+				tx.TxOut[dataOut].Value))
+		}
+		flow.Minted[d.AssetGuid] = AssetAmount(d.Change())
+		return flow, nil
+
+	default:
+		return nil, fmt.Errorf("tx version %d is not an asset transaction", tx.Version)
+	}
+
+	for _, d := range flow.Deltas {
+		if d.Change() != 0 {
+			return nil, violation(d, "input and output amounts differ")
+		}
+	}
+	return flow, nil
+}
@@ -0,0 +1,144 @@
+package wire
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// allocationTx returns a transaction of the given version with outputs
+// regular outputs followed by an OP_RETURN output worth dataValue that
+// carries p.
+func allocationTx(t *testing.T, version int32, outputs int, dataValue int64, p Payload) *wire.MsgTx {
+	t.Helper()
+	script, err := BuildAssetDataScript(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(version)
+	for i := 0; i < outputs; i++ {
+		tx.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+	}
+	tx.AddTxOut(wire.NewTxOut(dataValue, script))
+	return tx
+}
+
+func TestAllocationTotals(t *testing.T) {
+	alloc := &AssetAllocationType{VoutAssets: []AssetOutType{
+		{AssetGuid: 7, Values: []AssetOutValueType{{N: 0, ValueSat: 5}, {N: 1, ValueSat: 6}}},
+		{AssetGuid: 9, Values: []AssetOutValueType{{N: 2, ValueSat: 1}}},
+		{AssetGuid: 7, Values: []AssetOutValueType{{N: 3, ValueSat: 4}}},
+	}}
+	totals, err := alloc.Totals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (AssetBalances{7: 15, 9: 1}); !reflect.DeepEqual(totals, want) {
+		t.Errorf("Totals = %v, want %v", totals, want)
+	}
+
+	deltas := BalanceDeltas(AssetBalances{7: 20, 3: 1}, totals)
+	want := []AssetDelta{{3, 1, 0}, {7, 20, 15}, {9, 0, 1}}
+	if !reflect.DeepEqual(deltas, want) {
+		t.Errorf("BalanceDeltas = %v, want %v", deltas, want)
+	}
+	if deltas[0].Change() != -1 || deltas[2].Change() != 1 {
+		t.Error("wrong Change")
+	}
+
+	alloc.VoutAssets[0].Values[0].ValueSat = MAX_ASSET
+	if _, err := alloc.Totals(); !errors.Is(err, ErrAssetAmountRange) {
+		t.Errorf("got %v, want ErrAssetAmountRange", err)
+	}
+}
+
+func TestCheckAllocationConservation(t *testing.T) {
+	send := &AssetAllocationType{VoutAssets: []AssetOutType{
+		{AssetGuid: 7, Values: []AssetOutValueType{{N: 0, ValueSat: 60}, {N: 1, ValueSat: 40}}},
+	}}
+	tx := allocationTx(t, SYSCOIN_TX_VERSION_ALLOCATION_SEND, 2, 0, send)
+	flow, err := CheckAllocationConservation(tx, send, AssetBalances{7: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flow.Burned) != 0 || len(flow.Minted) != 0 || len(flow.Deltas) != 1 {
+		t.Errorf("unexpected flow %+v", flow)
+	}
+
+	var cerr *ConservationError
+	_, err = CheckAllocationConservation(tx, send, AssetBalances{7: 101})
+	if !errors.As(err, &cerr) || cerr.Delta != (AssetDelta{7, 101, 100}) {
+		t.Errorf("got %v, want a conservation error for asset 7", err)
+	}
+	if _, err := CheckAllocationConservation(tx, send, AssetBalances{7: 100, 8: 1}); !errors.As(err, &cerr) || cerr.Delta.AssetGuid != 8 {
+		t.Errorf("got %v, want a conservation error for asset 8", err)
+	}
+
+	// A burn assigns the burned amount to the OP_RETURN output.
+	burn := &SyscoinBurnToEthereumType{Allocation: AssetAllocationType{VoutAssets: []AssetOutType{
+		{AssetGuid: 7, Values: []AssetOutValueType{{N: 0, ValueSat: 70}, {N: 1, ValueSat: 30}}},
+	}}}
+	tx = allocationTx(t, SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM, 1, 0, burn)
+	alloc, err := AllocationOf(burn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow, err = CheckAllocationConservation(tx, alloc, AssetBalances{7: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flow.Burned, AssetBalances{7: 30}) {
+		t.Errorf("Burned = %v", flow.Burned)
+	}
+	tx = allocationTx(t, SYSCOIN_TX_VERSION_ALLOCATION_SEND, 1, 0, burn)
+	if _, err := CheckAllocationConservation(tx, alloc, AssetBalances{7: 100}); !errors.As(err, &cerr) {
+		t.Errorf("got %v, want a conservation error for a send that burns", err)
+	}
+
+	// A SYS burn mints exactly the SYS value of the OP_RETURN output.
+	mint := &SyscoinBurnToEthereumType{Allocation: AssetAllocationType{VoutAssets: []AssetOutType{
+		{AssetGuid: 7, Values: []AssetOutValueType{{N: 0, ValueSat: 250}}},
+	}}}
+	tx = allocationTx(t, SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION, 1, 200, mint)
+	flow, err = CheckAllocationConservation(tx, &mint.Allocation, AssetBalances{7: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flow.Minted, AssetBalances{7: 200}) {
+		t.Errorf("Minted = %v", flow.Minted)
+	}
+	if _, err := CheckAllocationConservation(tx, &mint.Allocation, nil); !errors.As(err, &cerr) {
+		t.Errorf("got %v, want a conservation error for a mismatched SYS burn", err)
+	}
+
+	tx = allocationTx(t, SYSCOIN_TX_VERSION_ALLOCATION_MINT, 1, 0, mint)
+	if flow, err = CheckAllocationConservation(tx, &mint.Allocation, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flow.Minted, AssetBalances{7: 250}) {
+		t.Errorf("Minted = %v", flow.Minted)
+	}
+	if _, err := CheckAllocationConservation(tx, &mint.Allocation, AssetBalances{7: 300}); !errors.As(err, &cerr) {
+		t.Errorf("got %v, want a conservation error for a decreasing mint", err)
+	}
+
+	// Malformed allocations are rejected outright.
+	bad := &AssetAllocationType{VoutAssets: []AssetOutType{
+		{AssetGuid: 7, Values: []AssetOutValueType{{N: 5, ValueSat: 1}}},
+	}}
+	tx = allocationTx(t, SYSCOIN_TX_VERSION_ALLOCATION_SEND, 1, 0, bad)
+	if _, err := CheckAllocationConservation(tx, bad, AssetBalances{7: 1}); err == nil {
+		t.Error("expected a missing output to be rejected")
+	}
+	bad.VoutAssets[0].Values = []AssetOutValueType{{N: 0, ValueSat: 1}, {N: 0, ValueSat: 1}}
+	if _, err := CheckAllocationConservation(tx, bad, AssetBalances{7: 2}); err == nil {
+		t.Error("expected a duplicate output to be rejected")
+	}
+	tx.Version = 2
+	if _, err := CheckAllocationConservation(tx, send, AssetBalances{7: 100}); err == nil {
+		t.Error("expected a non-asset version to be rejected")
+	}
+}
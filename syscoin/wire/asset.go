@@ -6,6 +6,7 @@ package wire
 
 import (
     "io"
+    "math"
     "encoding/binary"
     "github.com/btcsuite/btcd/wire"
 )
@@ -49,33 +50,23 @@ type SyscoinBurnToEthereumType struct {
     EthAddress []byte `json:"ethAddress,omitempty"`
 }
 
+// PutUint writes n in syscoind's VARINT encoding to w with a single Write
+// call.
 func PutUint(w io.Writer, n uint64) error {
-    tmp := make([]uint8, 10)
-    var len int8=0
-    for  {
-        var mask uint64
-        if len > 0 {
-            mask = 0x80
-        }
-        tmpI := (n & 0x7F) | mask
-        tmp[len] = uint8(tmpI)
-        if n <= 0x7F {
-            break
-        }
+    bp := encoderPool.Get().(*[]byte)
+    *bp = AppendUint((*bp)[:0], n)
+    _, err := w.Write(*bp)
+    encoderPool.Put(bp)
+    return err
+}
+// SizeOfUint returns the number of bytes PutUint writes for n.
+func SizeOfUint(n uint64) int {
+    size := 1
+    for n > 0x7F {
         n = (n >> 7) - 1
-        len++
+        size++
     }
-    for {
-        err := binarySerializer.PutUint8(w, tmp[len])
-        if err != nil {
-            return err
-        }
-        len--
-        if len < 0 {
-            break
-        }
-    }
-    return nil
+    return size
 }
 func ReadUint(r io.Reader) (uint64, error) {
     var n uint64 = 0
@@ -84,8 +75,16 @@ func ReadUint(r io.Reader) (uint64, error) {
         if err != nil {
             return 0, err
         }
+        // Mirror syscoind's ReadVarInt, which refuses encodings that would
+        // overflow the destination type.
+        if n > (math.MaxUint64 >> 7) {
+            return 0, messageError("ReadUint", "size too large")
+        }
         n = (n << 7) | (uint64(chData) & 0x7F)
         if (chData & 0x80) > 0 {
+            if n == math.MaxUint64 {
+                return 0, messageError("ReadUint", "size too large")
+            }
             n++
         } else {
             return n, nil
@@ -145,74 +144,51 @@ func DecompressAmount(x uint64) uint64 {
     return n
 }
 func (a *AssetAllocationType) Deserialize(r io.Reader) error {
-    numAssets, err := wire.ReadVarInt(r, 0)
+    numAssets, err := readElementCount(r, "VoutAssets")
     if err != nil {
         return err
     }
-    a.VoutAssets = make([]AssetOutType, numAssets)
-    for i := 0; i < int(numAssets); i++ {
-        err = a.VoutAssets[i].Deserialize(r)
+    a.VoutAssets = make([]AssetOutType, 0, preallocLen(numAssets))
+    for i := uint64(0); i < numAssets; i++ {
+        var voutAsset AssetOutType
+        err = voutAsset.Deserialize(r)
         if err != nil {
             return err
         }
+        a.VoutAssets = append(a.VoutAssets, voutAsset)
     }
     return nil
 }
 func (a *AssetAllocationType) Serialize(w io.Writer) error {
-    lenAssets := len(a.VoutAssets)
-    err := wire.WriteVarInt(w, 0, uint64(lenAssets))
-    if err != nil {
-        return err
-    }
-    for i := 0; i < lenAssets; i++ {
-        err = a.VoutAssets[i].Serialize(w)
-        if err != nil {
-            return err
-        }
-    }
-    return nil
+    return writeAppended(w, a)
 }
 func (a *AssetOutValueType) Serialize(w io.Writer) error {
-    err := wire.WriteVarInt(w, 0, uint64(a.N))
-    if err != nil {
-        return err
-    }
-    err = PutUint(w, CompressAmount(uint64(a.ValueSat)))
-    if err != nil {
-        return err
-    }
-    return nil
+    return writeAppended(w, a)
 }
 func (a *AssetOutValueType) Deserialize(r io.Reader) error {
     n, err := wire.ReadVarInt(r, 0)
     if err != nil {
         return err
     }
+    if n > math.MaxUint32 {
+        return messageError("AssetOutValueType.Deserialize", "output index exceeds limit of type")
+    }
     a.N = uint32(n)
     valueSat, err := ReadUint(r)
     if err != nil {
         return err
     }
-    a.ValueSat = int64(DecompressAmount(valueSat))
+    // Amounts whose decompression overflows have no canonical encoding and
+    // would serialize back to different bytes.
+    amount := DecompressAmount(valueSat)
+    if CompressAmount(amount) != valueSat {
+        return messageError("AssetOutValueType.Deserialize", "non-canonical compressed amount")
+    }
+    a.ValueSat = int64(amount)
     return nil
 }
 func (a *AssetOutType) Serialize(w io.Writer) error {
-    err := PutUint(w, a.AssetGuid)
-    if err != nil {
-        return err
-    }
-    lenValues := len(a.Values)
-    err = wire.WriteVarInt(w, 0, uint64(lenValues))
-    if err != nil {
-        return err
-    }
-    for i := 0; i < lenValues; i++ {
-        err = a.Values[i].Serialize(w)
-        if err != nil {
-            return err
-        }
-    }
-    return nil
+    return writeAppended(w, a)
 }
 func (a *AssetOutType) Deserialize(r io.Reader) error {
     var err error
@@ -220,16 +196,18 @@ func (a *AssetOutType) Deserialize(r io.Reader) error {
     if err != nil {
         return err
     }
-    numOutputs, err := wire.ReadVarInt(r, 0)
+    numOutputs, err := readElementCount(r, "Values")
     if err != nil {
         return err
     }
-    a.Values = make([]AssetOutValueType, numOutputs)
-    for i := 0; i < int(numOutputs); i++ {
-        err = a.Values[i].Deserialize(r)
+    a.Values = make([]AssetOutValueType, 0, preallocLen(numOutputs))
+    for i := uint64(0); i < numOutputs; i++ {
+        var value AssetOutValueType
+        err = value.Deserialize(r)
         if err != nil {
             return err
         }
+        a.Values = append(a.Values, value)
     }
     return nil
 }
@@ -253,11 +231,11 @@ func (a *MintSyscoinType) Deserialize(r io.Reader) error {
     if err != nil {
         return err
     }
-    a.TxParentNodes, err = wire.ReadVarBytes(r, 0, MAX_RLP_SIZE, "TxParentNodes")
+    a.TxParentNodes, err = readVarBytes(r, MAX_RLP_SIZE, "TxParentNodes")
     if err != nil {
         return err
     }
-    a.TxPath, err = wire.ReadVarBytes(r, 0, MAX_RLP_SIZE, "TxPath")
+    a.TxPath, err = readVarBytes(r, MAX_RLP_SIZE, "TxPath")
     if err != nil {
         return err
     }
@@ -265,7 +243,7 @@ func (a *MintSyscoinType) Deserialize(r io.Reader) error {
     if err != nil {
         return err
     }
-    a.ReceiptParentNodes, err = wire.ReadVarBytes(r, 0, MAX_RLP_SIZE, "ReceiptParentNodes")
+    a.ReceiptParentNodes, err = readVarBytes(r, MAX_RLP_SIZE, "ReceiptParentNodes")
     if err != nil {
         return err
     }
@@ -283,47 +261,7 @@ func (a *MintSyscoinType) Deserialize(r io.Reader) error {
 }
 
 func (a *MintSyscoinType) Serialize(w io.Writer) error {
-    err := a.Allocation.Serialize(w)
-    if err != nil {
-        return err
-    }
-    _, err = w.Write(a.TxHash[:])
-    if err != nil {
-        return err
-    }
-    _, err = w.Write(a.BlockHash[:])
-    if err != nil {
-        return err
-    }
-    err = binarySerializer.PutUint16(w, binary.LittleEndian, a.TxPos)
-    if err != nil {
-        return err
-    }
-    err = wire.WriteVarBytes(w, 0, a.TxParentNodes)
-    if err != nil {
-        return err
-    }
-    err = wire.WriteVarBytes(w, 0, a.TxPath)
-    if err != nil {
-        return err
-    }
-    err = binarySerializer.PutUint16(w, binary.LittleEndian, a.ReceiptPos)
-    if err != nil {
-        return err
-    }
-    err = wire.WriteVarBytes(w, 0, a.ReceiptParentNodes)
-    if err != nil {
-        return err
-    }
-    _, err = w.Write(a.TxRoot[:])
-    if err != nil {
-        return err
-    }
-    _, err = w.Write(a.ReceiptRoot[:])
-    if err != nil {
-        return err
-    }
-    return nil
+    return writeAppended(w, a)
 }
 
 func (a *SyscoinBurnToEthereumType) Deserialize(r io.Reader) error {
@@ -331,7 +269,7 @@ func (a *SyscoinBurnToEthereumType) Deserialize(r io.Reader) error {
     if err != nil {
         return err
     }
-    a.EthAddress, err = wire.ReadVarBytes(r, 0, MAX_GUID_LENGTH, "ethAddress")
+    a.EthAddress, err = readVarBytes(r, MAX_GUID_LENGTH, "ethAddress")
     if err != nil {
         return err
     }
@@ -339,36 +277,18 @@ func (a *SyscoinBurnToEthereumType) Deserialize(r io.Reader) error {
 }
 
 func (a *SyscoinBurnToEthereumType) Serialize(w io.Writer) error {
-    err := a.Allocation.Serialize(w)
-    if err != nil {
-        return err
-    }
-    err = wire.WriteVarBytes(w, 0, a.EthAddress)
-    if err != nil {
-        return err
-    }
-    return nil
+    return writeAppended(w, a)
 }
 
 func (a *AssetType) Serialize(w io.Writer) error {
-    // Serialize Symbol
-    if err := wire.WriteVarBytes(w, 0, a.Symbol); err != nil {
-        return err
-    }
-
-    // Serialize Precision
-    if err := binarySerializer.PutUint8(w, a.Precision); err != nil {
-        return err
-    }
-
-    return nil
+    return writeAppended(w, a)
 }
 
 func (a *AssetType) Deserialize(r io.Reader) error {
     var err error
 
     // Deserialize Symbol
-    a.Symbol, err = wire.ReadVarBytes(r, 0, MAX_GUID_LENGTH, "Symbol")
+    a.Symbol, err = readVarBytes(r, MAX_GUID_LENGTH, "Symbol")
     if err != nil {
         return err
     }
@@ -381,3 +301,45 @@ func (a *AssetType) Deserialize(r io.Reader) error {
 
     return nil
 }
+
+func (a *AssetOutValueType) SerializeSize() int {
+    return wire.VarIntSerializeSize(uint64(a.N)) + SizeOfUint(CompressAmount(uint64(a.ValueSat)))
+}
+
+func (a *AssetOutType) SerializeSize() int {
+    n := SizeOfUint(a.AssetGuid) + wire.VarIntSerializeSize(uint64(len(a.Values)))
+    for i := range a.Values {
+        n += a.Values[i].SerializeSize()
+    }
+    return n
+}
+
+func (a *AssetAllocationType) SerializeSize() int {
+    n := wire.VarIntSerializeSize(uint64(len(a.VoutAssets)))
+    for i := range a.VoutAssets {
+        n += a.VoutAssets[i].SerializeSize()
+    }
+    return n
+}
+
+func (a *MintSyscoinType) SerializeSize() int {
+    return a.Allocation.SerializeSize() +
+        len(a.TxHash) + len(a.BlockHash) + 2 +
+        varBytesSerializeSize(a.TxParentNodes) +
+        varBytesSerializeSize(a.TxPath) + 2 +
+        varBytesSerializeSize(a.ReceiptParentNodes) +
+        len(a.TxRoot) + len(a.ReceiptRoot)
+}
+
+func (a *SyscoinBurnToEthereumType) SerializeSize() int {
+    return a.Allocation.SerializeSize() + varBytesSerializeSize(a.EthAddress)
+}
+
+func (a *AssetType) SerializeSize() int {
+    return varBytesSerializeSize(a.Symbol) + 1
+}
+
+func (a *AssetAllocationType) Kind() PayloadKind { return KindAllocation }
+func (a *MintSyscoinType) Kind() PayloadKind { return KindMint }
+func (a *SyscoinBurnToEthereumType) Kind() PayloadKind { return KindBurnToEthereum }
+func (a *AssetType) Kind() PayloadKind { return KindAsset }
@@ -0,0 +1,174 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MAX_ASSET is the largest asset amount in satoshis, as checked by
+	// syscoind's MoneyRangeAsset.
+	MAX_ASSET = 999999999999999999
+
+	// MAX_ASSET_PRECISION is the largest number of decimals an asset may
+	// be displayed with.
+	MAX_ASSET_PRECISION = 8
+)
+
+var (
+	// ErrAssetAmountRange is returned for an amount outside [0, MAX_ASSET].
+	ErrAssetAmountRange = errors.New("asset amount out of range")
+
+	// errAssetPrecision is returned for a precision above
+	// MAX_ASSET_PRECISION.
+	errAssetPrecision = fmt.Errorf("asset precision exceeds %d", MAX_ASSET_PRECISION)
+)
+
+// pow10 holds the powers of ten up to MAX_ASSET_PRECISION.
+var pow10 = [MAX_ASSET_PRECISION + 1]int64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8}
+
+// AssetAmount is an asset amount in satoshis, the indivisible unit stored in
+// AssetOutValueType.ValueSat.  An asset's Precision says how many of its
+// satoshi digits are decimals when the amount is shown to users.  A valid
+// amount lies in [0, MAX_ASSET].
+type AssetAmount int64
+
+// NewAssetAmount returns valueSat as an AssetAmount, or ErrAssetAmountRange
+// if it is out of range.
+func NewAssetAmount(valueSat int64) (AssetAmount, error) {
+	a := AssetAmount(valueSat)
+	if !a.IsValid() {
+		return 0, ErrAssetAmountRange
+	}
+	return a, nil
+}
+
+// AssetAmountFromOutValue returns the amount of an allocation output value.
+func AssetAmountFromOutValue(v *AssetOutValueType) (AssetAmount, error) {
+	return NewAssetAmount(v.ValueSat)
+}
+
+// ParseAssetAmount parses a non-negative decimal string such as "12.5" with
+// at most precision decimals.  Signs, exponents, surrounding spaces and a
+// missing integer or fraction part around the point are rejected, so the
+// string converts to satoshis exactly with no rounding.
+func ParseAssetAmount(s string, precision uint8) (AssetAmount, error) {
+	if precision > MAX_ASSET_PRECISION {
+		return 0, errAssetPrecision
+	}
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(frac)) {
+		return 0, fmt.Errorf("invalid asset amount %q", s)
+	}
+	if len(frac) > int(precision) {
+		return 0, fmt.Errorf("asset amount %q has more than %d decimals", s, precision)
+	}
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || w > MAX_ASSET/pow10[precision] {
+		return 0, fmt.Errorf("asset amount %q: %w", s, ErrAssetAmountRange)
+	}
+	var f int64
+	if frac != "" {
+		f, _ = strconv.ParseInt(frac, 10, 64)
+		f *= pow10[int(precision)-len(frac)]
+	}
+	return NewAssetAmount(w*pow10[precision] + f)
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Format returns a as a decimal string with exactly precision decimals, the
+// inverse of ParseAssetAmount.  A precision above MAX_ASSET_PRECISION is
+// clamped to it.
+func (a AssetAmount) Format(precision uint8) string {
+	if precision > MAX_ASSET_PRECISION {
+		precision = MAX_ASSET_PRECISION
+	}
+	// Invalid negative amounts are still printed faithfully.
+	sign, v := "", uint64(a)
+	if a < 0 {
+		sign, v = "-", -v
+	}
+	if precision == 0 {
+		return sign + strconv.FormatUint(v, 10)
+	}
+	unit := uint64(pow10[precision])
+	return fmt.Sprintf("%s%d.%0*d", sign, v/unit, int(precision), v%unit)
+}
+
+// String returns a formatted with MAX_ASSET_PRECISION decimals.
+func (a AssetAmount) String() string {
+	return a.Format(MAX_ASSET_PRECISION)
+}
+
+// ValueSat returns a in satoshis, for AssetOutValueType.ValueSat.
+func (a AssetAmount) ValueSat() int64 {
+	return int64(a)
+}
+
+// OutValue returns an allocation output value paying a to output n.
+func (a AssetAmount) OutValue(n uint32) AssetOutValueType {
+	return AssetOutValueType{N: n, ValueSat: int64(a)}
+}
+
+// IsValid reports whether a lies in [0, MAX_ASSET].
+func (a AssetAmount) IsValid() bool {
+	return a >= 0 && a <= MAX_ASSET
+}
+
+// Add returns a+b, or ErrAssetAmountRange if either operand or the sum is
+// out of range.  Both operands are at most MAX_ASSET, so the sum cannot
+// overflow an int64.
+func (a AssetAmount) Add(b AssetAmount) (AssetAmount, error) {
+	if !a.IsValid() || !b.IsValid() {
+		return 0, ErrAssetAmountRange
+	}
+	return NewAssetAmount(int64(a) + int64(b))
+}
+
+// Sub returns a-b, or ErrAssetAmountRange if either operand is out of range
+// or b exceeds a.
+func (a AssetAmount) Sub(b AssetAmount) (AssetAmount, error) {
+	if !a.IsValid() || !b.IsValid() {
+		return 0, ErrAssetAmountRange
+	}
+	return NewAssetAmount(int64(a) - int64(b))
+}
+
+// Cmp returns -1, 0 or +1 as a is less than, equal to or greater than b.
+func (a AssetAmount) Cmp(b AssetAmount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// ParseAmount parses s at the asset's precision.
+func (a *AssetType) ParseAmount(s string) (AssetAmount, error) {
+	return ParseAssetAmount(s, a.Precision)
+}
+
+// FormatAmount formats amount at the asset's precision.
+func (a *AssetType) FormatAmount(amount AssetAmount) string {
+	return amount.Format(a.Precision)
+}
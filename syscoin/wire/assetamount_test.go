@@ -0,0 +1,112 @@
+package wire
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAssetAmount(t *testing.T) {
+	tests := []struct {
+		in        string
+		precision uint8
+		want      AssetAmount
+		ok        bool
+	}{
+		{"0", 0, 0, true},
+		{"12", 0, 12, true},
+		{"12.5", 2, 1250, true},
+		{"12.50", 2, 1250, true},
+		{"0.00000001", 8, 1, true},
+		{"9999999999.99999999", 8, MAX_ASSET, true},
+		{"999999999999999999", 0, MAX_ASSET, true},
+		{"10000000000", 8, 0, false},
+		{"1000000000000000000", 0, 0, false},
+		{"99999999999999999999999", 0, 0, false},
+		{"12.5", 0, 0, false},
+		{"12.555", 2, 0, false},
+		{"1", 9, 0, false},
+		{"", 2, 0, false},
+		{".5", 2, 0, false},
+		{"5.", 2, 0, false},
+		{"-1", 2, 0, false},
+		{"+1", 2, 0, false},
+		{"1e2", 2, 0, false},
+		{" 1", 2, 0, false},
+		{"1.2.3", 2, 0, false},
+	}
+	for _, test := range tests {
+		got, err := ParseAssetAmount(test.in, test.precision)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("ParseAssetAmount(%q, %d) = %d, %v", test.in, test.precision, got, err)
+		}
+	}
+}
+
+func TestAssetAmountFormat(t *testing.T) {
+	tests := []struct {
+		amount    AssetAmount
+		precision uint8
+		want      string
+	}{
+		{0, 0, "0"},
+		{0, 2, "0.00"},
+		{1250, 2, "12.50"},
+		{1, 8, "0.00000001"},
+		{MAX_ASSET, 8, "9999999999.99999999"},
+		{MAX_ASSET, 0, "999999999999999999"},
+		{-5, 1, "-0.5"},
+		{1, 12, "0.00000001"},
+	}
+	for _, test := range tests {
+		got := test.amount.Format(test.precision)
+		if got != test.want {
+			t.Errorf("%d.Format(%d) = %q, want %q", test.amount, test.precision, got, test.want)
+		}
+		if test.amount < 0 || test.precision > MAX_ASSET_PRECISION {
+			continue
+		}
+		back, err := ParseAssetAmount(got, test.precision)
+		if err != nil || back != test.amount {
+			t.Errorf("ParseAssetAmount(%q, %d) = %d, %v", got, test.precision, back, err)
+		}
+	}
+
+	asset := &AssetType{Precision: 4}
+	amount, err := asset.ParseAmount("1.1")
+	if err != nil || amount != 11000 || asset.FormatAmount(amount) != "1.1000" {
+		t.Errorf("AssetType round trip: %d, %v", amount, err)
+	}
+}
+
+func TestAssetAmountArithmetic(t *testing.T) {
+	a, b := AssetAmount(700), AssetAmount(300)
+	if sum, err := a.Add(b); err != nil || sum != 1000 {
+		t.Errorf("Add = %d, %v", sum, err)
+	}
+	if diff, err := a.Sub(b); err != nil || diff != 400 {
+		t.Errorf("Sub = %d, %v", diff, err)
+	}
+	if _, err := b.Sub(a); !errors.Is(err, ErrAssetAmountRange) {
+		t.Errorf("negative Sub: got %v", err)
+	}
+	if _, err := AssetAmount(MAX_ASSET).Add(1); !errors.Is(err, ErrAssetAmountRange) {
+		t.Errorf("Add over MAX_ASSET: got %v", err)
+	}
+	if _, err := AssetAmount(-1).Add(1); !errors.Is(err, ErrAssetAmountRange) {
+		t.Errorf("Add of an invalid operand: got %v", err)
+	}
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(a) != 0 {
+		t.Error("wrong Cmp ordering")
+	}
+
+	out := a.OutValue(3)
+	if out != (AssetOutValueType{N: 3, ValueSat: 700}) {
+		t.Errorf("OutValue = %+v", out)
+	}
+	if back, err := AssetAmountFromOutValue(&out); err != nil || back != a || back.ValueSat() != 700 {
+		t.Errorf("AssetAmountFromOutValue = %d, %v", back, err)
+	}
+	if _, err := NewAssetAmount(MAX_ASSET + 1); !errors.Is(err, ErrAssetAmountRange) {
+		t.Errorf("NewAssetAmount over MAX_ASSET: got %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// VERSION_AUXPOW is the block version bit syscoind sets on merge-mined
+	// blocks, whose header is followed by an AuxPow.
+	VERSION_AUXPOW = 1 << 8
+
+	// VERSION_CHAINID_BIT is the lowest bit of the chain ID a merge-mined
+	// block version carries.
+	VERSION_CHAINID_BIT = 16
+)
+
+// AuxPow is the proof that a parent chain block merge-mined a Syscoin
+// block, syscoind's CAuxPow.  The coinbase of the parent block commits to
+// the root of a merkle tree of merge-mined chains; MerkleBranch links the
+// coinbase to the parent block's merkle root and ChainMerkleBranch links the
+// Syscoin block hash to the committed root.
+type AuxPow struct {
+	CoinbaseTx wire.MsgTx
+
+	// BlockHash and Index are the unused CMerkleTx fields syscoind keeps
+	// for compatibility.  They are normally zero.
+	BlockHash    chainhash.Hash
+	MerkleBranch []chainhash.Hash
+	Index        int32
+
+	ChainMerkleBranch []chainhash.Hash
+	ChainIndex        int32
+	ParentBlock       wire.BlockHeader
+}
+
+func readMerkleBranch(r io.Reader, fieldName string) ([]chainhash.Hash, error) {
+	count, err := readElementCount(r, fieldName)
+	if err != nil {
+		return nil, err
+	}
+	branch := make([]chainhash.Hash, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		var h chainhash.Hash
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return nil, err
+		}
+		branch = append(branch, h)
+	}
+	return branch, nil
+}
+
+func writeMerkleBranch(w io.Writer, branch []chainhash.Hash) error {
+	if err := wire.WriteVarInt(w, 0, uint64(len(branch))); err != nil {
+		return err
+	}
+	for i := range branch {
+		if _, err := w.Write(branch[i][:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AuxPow) Deserialize(r io.Reader) error {
+	if err := a.CoinbaseTx.Deserialize(r); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, a.BlockHash[:]); err != nil {
+		return err
+	}
+	var err error
+	if a.MerkleBranch, err = readMerkleBranch(r, "AuxPow.MerkleBranch"); err != nil {
+		return err
+	}
+	index, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	a.Index = int32(index)
+	if a.ChainMerkleBranch, err = readMerkleBranch(r, "AuxPow.ChainMerkleBranch"); err != nil {
+		return err
+	}
+	if index, err = binarySerializer.Uint32(r, littleEndian); err != nil {
+		return err
+	}
+	a.ChainIndex = int32(index)
+	return a.ParentBlock.Deserialize(r)
+}
+
+func (a *AuxPow) Serialize(w io.Writer) error {
+	if err := a.CoinbaseTx.Serialize(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.BlockHash[:]); err != nil {
+		return err
+	}
+	if err := writeMerkleBranch(w, a.MerkleBranch); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(a.Index)); err != nil {
+		return err
+	}
+	if err := writeMerkleBranch(w, a.ChainMerkleBranch); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(a.ChainIndex)); err != nil {
+		return err
+	}
+	return a.ParentBlock.Serialize(w)
+}
+
+func (a *AuxPow) SerializeSize() int {
+	return a.CoinbaseTx.SerializeSize() + chainhash.HashSize +
+		wire.VarIntSerializeSize(uint64(len(a.MerkleBranch))) + len(a.MerkleBranch)*chainhash.HashSize + 4 +
+		wire.VarIntSerializeSize(uint64(len(a.ChainMerkleBranch))) + len(a.ChainMerkleBranch)*chainhash.HashSize + 4 +
+		wire.MaxBlockHeaderPayload
+}
+
+// SyscoinBlockHeader is a Syscoin block header: btcd's 80-byte header,
+// followed by an AuxPow when the version has VERSION_AUXPOW set.  The block
+// hash covers only the 80-byte header.
+type SyscoinBlockHeader struct {
+	wire.BlockHeader
+	AuxPow *AuxPow
+}
+
+// IsAuxPow reports whether the header's version marks it as merge-mined.
+func (h *SyscoinBlockHeader) IsAuxPow() bool {
+	return h.Version&VERSION_AUXPOW != 0
+}
+
+// ChainID returns the merge-mining chain ID encoded in the version.
+func (h *SyscoinBlockHeader) ChainID() int32 {
+	return h.Version >> VERSION_CHAINID_BIT
+}
+
+func (h *SyscoinBlockHeader) Deserialize(r io.Reader) error {
+	if err := h.BlockHeader.Deserialize(r); err != nil {
+		return err
+	}
+	h.AuxPow = nil
+	if !h.IsAuxPow() {
+		return nil
+	}
+	h.AuxPow = &AuxPow{}
+	return h.AuxPow.Deserialize(r)
+}
+
+func (h *SyscoinBlockHeader) Serialize(w io.Writer) error {
+	if h.IsAuxPow() != (h.AuxPow != nil) {
+		str := fmt.Sprintf("header version %#x does not match the presence of an AuxPow", h.Version)
+		return messageError("SyscoinBlockHeader.Serialize", str)
+	}
+	if err := h.BlockHeader.Serialize(w); err != nil {
+		return err
+	}
+	if h.AuxPow == nil {
+		return nil
+	}
+	return h.AuxPow.Serialize(w)
+}
+
+func (h *SyscoinBlockHeader) SerializeSize() int {
+	n := wire.MaxBlockHeaderPayload
+	if h.AuxPow != nil {
+		n += h.AuxPow.SerializeSize()
+	}
+	return n
+}
+
+// MsgSyscoinBlock implements the wire.Message interface and represents a
+// Syscoin block message, whose header may carry an AuxPow.
+type MsgSyscoinBlock struct {
+	Header       SyscoinBlockHeader
+	Transactions []*wire.MsgTx
+}
+
+// BlockHash returns the hash of the block's 80-byte header.
+func (msg *MsgSyscoinBlock) BlockHash() chainhash.Hash {
+	return msg.Header.BlockHash()
+}
+
+// MsgBlock returns the block without its AuxPow as a btcd block, sharing the
+// transactions, for code such as the masternode list and the indexer that
+// takes btcd blocks.
+func (msg *MsgSyscoinBlock) MsgBlock() *wire.MsgBlock {
+	return &wire.MsgBlock{Header: msg.Header.BlockHeader, Transactions: msg.Transactions}
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgSyscoinBlock) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	if err := msg.Header.Deserialize(r); err != nil {
+		return err
+	}
+	count, err := readElementCount(r, "MsgSyscoinBlock.Transactions")
+	if err != nil {
+		return err
+	}
+	msg.Transactions = make([]*wire.MsgTx, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		tx := &wire.MsgTx{}
+		if err := tx.BtcDecode(r, pver, enc); err != nil {
+			return err
+		}
+		msg.Transactions = append(msg.Transactions, tx)
+	}
+	return nil
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgSyscoinBlock) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if err := msg.Header.Serialize(w); err != nil {
+		return err
+	}
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.Transactions))); err != nil {
+		return err
+	}
+	for _, tx := range msg.Transactions {
+		if err := tx.BtcEncode(w, pver, enc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgSyscoinBlock) Command() string {
+	return wire.CmdBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  The AuxPow is not bounded by the block weight limit, so this
+// is the general message limit.  This is part of the wire.Message interface
+// implementation.
+func (msg *MsgSyscoinBlock) MaxPayloadLength(pver uint32) uint32 {
+	return wire.MaxMessagePayload
+}
+
+// MsgSyscoinHeaders implements the wire.Message interface and represents a
+// Syscoin headers message, whose headers may carry an AuxPow.
+type MsgSyscoinHeaders struct {
+	Headers []*SyscoinBlockHeader
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgSyscoinHeaders) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	count, err := wire.ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > wire.MaxBlockHeadersPerMsg {
+		str := fmt.Sprintf("too many block headers for message "+
+			"[count %v, max %v]", count, wire.MaxBlockHeadersPerMsg)
+		return messageError("MsgSyscoinHeaders.BtcDecode", str)
+	}
+	msg.Headers = make([]*SyscoinBlockHeader, 0, count)
+	for i := uint64(0); i < count; i++ {
+		h := &SyscoinBlockHeader{}
+		if err := h.Deserialize(r); err != nil {
+			return err
+		}
+		// Each header is followed by an always empty transaction count.
+		txCount, err := wire.ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		if txCount > 0 {
+			str := fmt.Sprintf("block headers may not contain "+
+				"transactions [count %v]", txCount)
+			return messageError("MsgSyscoinHeaders.BtcDecode", str)
+		}
+		msg.Headers = append(msg.Headers, h)
+	}
+	return nil
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgSyscoinHeaders) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if len(msg.Headers) > wire.MaxBlockHeadersPerMsg {
+		str := fmt.Sprintf("too many block headers for message "+
+			"[count %v, max %v]", len(msg.Headers), wire.MaxBlockHeadersPerMsg)
+		return messageError("MsgSyscoinHeaders.BtcEncode", str)
+	}
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.Headers))); err != nil {
+		return err
+	}
+	for _, h := range msg.Headers {
+		if err := h.Serialize(w); err != nil {
+			return err
+		}
+		if err := wire.WriteVarInt(w, pver, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgSyscoinHeaders) Command() string {
+	return wire.CmdHeaders
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  AuxPow headers have no fixed size, so this is the general
+// message limit.  This is part of the wire.Message interface
+// implementation.
+func (msg *MsgSyscoinHeaders) MaxPayloadLength(pver uint32) uint32 {
+	return wire.MaxMessagePayload
+}
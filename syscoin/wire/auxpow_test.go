@@ -0,0 +1,109 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func testAuxPow() *AuxPow {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: ^uint32(0)},
+		append([]byte{0xfa, 0xbe, 'm', 'm'}, randomBytes(40)...), nil))
+	coinbase.AddTxOut(wire.NewTxOut(625000000, []byte{txscript.OP_TRUE}))
+	return &AuxPow{
+		CoinbaseTx:        *coinbase,
+		MerkleBranch:      []chainhash.Hash{randomHash(), randomHash()},
+		ChainMerkleBranch: []chainhash.Hash{randomHash()},
+		ChainIndex:        1,
+		ParentBlock: wire.BlockHeader{
+			Version:    0x20000000,
+			PrevBlock:  randomHash(),
+			MerkleRoot: randomHash(),
+			Timestamp:  time.Unix(1700000000, 0),
+			Bits:       0x17034219,
+			Nonce:      42,
+		},
+	}
+}
+
+func TestMsgSyscoinBlock(t *testing.T) {
+	header := SyscoinBlockHeader{BlockHeader: wire.BlockHeader{
+		Version:    0x10000000 | VERSION_AUXPOW | 0x04,
+		PrevBlock:  randomHash(),
+		MerkleRoot: randomHash(),
+		Timestamp:  time.Unix(1700000060, 0),
+		Bits:       0x1b0404cb,
+	}}
+	header.AuxPow = testAuxPow()
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: randomHash()}, []byte{txscript.OP_TRUE}, [][]byte{{0x01}}))
+	tx.AddTxOut(wire.NewTxOut(1, []byte{txscript.OP_TRUE}))
+	block := &MsgSyscoinBlock{Header: header, Transactions: []*wire.MsgTx{tx}}
+	plain := &MsgSyscoinBlock{Header: SyscoinBlockHeader{BlockHeader: wire.BlockHeader{
+		Version:   4,
+		Timestamp: time.Unix(1700000000, 0),
+	}}, Transactions: []*wire.MsgTx{tx}}
+	headers := &MsgSyscoinHeaders{Headers: []*SyscoinBlockHeader{&block.Header, &plain.Header}}
+
+	if !block.Header.IsAuxPow() || block.Header.ChainID() != 0x1000 || plain.Header.IsAuxPow() {
+		t.Fatalf("unexpected version bits %#x, %#x", block.Header.Version, plain.Header.Version)
+	}
+	var buf bytes.Buffer
+	if err := block.Header.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != block.Header.SerializeSize() {
+		t.Errorf("SerializeSize() = %d, wrote %d", block.Header.SerializeSize(), buf.Len())
+	}
+	// The block hash covers only the 80-byte header.
+	if want := chainhash.DoubleHashH(buf.Bytes()[:wire.MaxBlockHeaderPayload]); block.BlockHash() != want {
+		t.Errorf("BlockHash() = %v, want %v", block.BlockHash(), want)
+	}
+	if mb := block.MsgBlock(); mb.BlockHash() != block.BlockHash() || len(mb.Transactions) != 1 {
+		t.Errorf("MsgBlock() = %+v", mb)
+	}
+
+	var stream bytes.Buffer
+	msgs := []wire.Message{block, plain, headers}
+	for _, msg := range msgs {
+		if err := WriteSyscoinMessage(&stream, msg, PROTOCOL_VERSION, SyscoinRegTest); err != nil {
+			t.Fatalf("%s: %v", msg.Command(), err)
+		}
+	}
+	for _, want := range msgs {
+		got, _, err := ReadSyscoinMessage(&stream, PROTOCOL_VERSION, SyscoinRegTest)
+		if err != nil {
+			t.Fatalf("%s: %v", want.Command(), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", want.Command(), got, want)
+		}
+	}
+
+	// The version bit and the AuxPow must agree.
+	bad := plain.Header
+	bad.AuxPow = testAuxPow()
+	if err := bad.Serialize(&buf); err == nil {
+		t.Error("expected an AuxPow without the version bit to be rejected")
+	}
+	bad = block.Header
+	bad.AuxPow = nil
+	if err := bad.Serialize(&buf); err == nil {
+		t.Error("expected the version bit without an AuxPow to be rejected")
+	}
+
+	// Headers must not carry transactions.
+	buf.Reset()
+	wire.WriteVarInt(&buf, 0, 1)
+	plain.Header.Serialize(&buf)
+	wire.WriteVarInt(&buf, 0, 1)
+	if err := new(MsgSyscoinHeaders).BtcDecode(&buf, PROTOCOL_VERSION, wire.WitnessEncoding); err == nil {
+		t.Error("expected a header with transactions to be rejected")
+	}
+}
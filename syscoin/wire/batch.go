@@ -0,0 +1,168 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// BatchItem is one serialized payload to decode with DecodeBatch.
+type BatchItem struct {
+	Kind PayloadKind
+	Data []byte
+}
+
+// BatchResult is the outcome of decoding one BatchItem.
+type BatchResult struct {
+	Payload Payload
+	Err     error
+}
+
+// BatchOptions tunes DecodeBatch and DecodeBatchFunc.  The zero value uses
+// one worker per CPU.
+type BatchOptions struct {
+	// Workers is the number of payloads decoded concurrently.
+	Workers int
+
+	// Window is the largest number of decoded results held while waiting
+	// for an earlier, slower item, which bounds the memory DecodeBatchFunc
+	// uses beyond its input.  It defaults to four per worker.
+	Window int
+}
+
+func (o *BatchOptions) normalize() (workers, window int) {
+	workers = o.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	window = o.Window
+	if window <= 0 {
+		window = 4 * workers
+	}
+	return workers, window
+}
+
+// decodeBatchItem decodes item, which must be consumed exactly, stopping
+// when ctx ends.
+func decodeBatchItem(ctx context.Context, item BatchItem) (Payload, error) {
+	p, err := NewPayload(item.Kind)
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(item.Data)
+	if err := DeserializePayloadContext(ctx, p, r); err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after %s payload", r.Len(), item.Kind)
+	}
+	return p, nil
+}
+
+// DecodeBatchFunc decodes items on a pool of workers and calls fn with each
+// result in input order, from the calling goroutine.  A payload that fails
+// to decode is reported to fn through err; it does not stop the batch.
+//
+// DecodeBatchFunc stops early, after waiting for its workers, when ctx is
+// done or fn returns an error, and returns that error.
+func DecodeBatchFunc(ctx context.Context, items []BatchItem, opts BatchOptions,
+	fn func(i int, p Payload, err error) error) error {
+
+	workers, window := opts.normalize()
+	ctx, cancel := context.WithCancel(ctx)
+
+	type job struct {
+		item BatchItem
+		done chan BatchResult
+	}
+	jobs := make(chan job)
+	pending := make(chan chan BatchResult, window)
+
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// The feeder queues a result slot for every item before handing it to
+	// a worker, so pending holds the slots in input order and its capacity
+	// limits how far the workers run ahead of fn.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(pending)
+		for _, item := range items {
+			done := make(chan BatchResult, 1)
+			select {
+			case pending <- done:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{item, done}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				p, err := decodeBatchItem(ctx, j.item)
+				j.done <- BatchResult{p, err}
+			}
+		}()
+	}
+
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var done chan BatchResult
+		select {
+		case d, ok := <-pending:
+			if !ok {
+				if i == len(items) {
+					return nil
+				}
+				return ctx.Err()
+			}
+			done = d
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case res := <-done:
+			if err := fn(i, res.Payload, res.Err); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// DecodeBatch decodes items concurrently and returns their results in input
+// order.  It fails only if ctx is done before every item is decoded; errors
+// decoding individual payloads are reported in their results.
+func DecodeBatch(ctx context.Context, items []BatchItem, opts BatchOptions) ([]BatchResult, error) {
+	results := make([]BatchResult, len(items))
+	err := DecodeBatchFunc(ctx, items, opts, func(i int, p Payload, err error) error {
+		results[i] = BatchResult{p, err}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
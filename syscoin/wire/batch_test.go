@@ -0,0 +1,115 @@
+package wire
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func batchItems(n int) []BatchItem {
+	items := make([]BatchItem, 0, n)
+	for i := 0; i < n; i++ {
+		switch {
+		case i%7 == 3:
+			// Trailing garbage makes every seventh item fail.
+			alloc := &AssetAllocationType{}
+			items = append(items, BatchItem{KindAllocation, append(alloc.AppendSerialize(nil), 0x00)})
+		case i%2 == 0:
+			alloc := &AssetAllocationType{VoutAssets: []AssetOutType{{
+				AssetGuid: uint64(i),
+				Values:    []AssetOutValueType{{N: 1, ValueSat: int64(i) + 1}},
+			}}}
+			items = append(items, BatchItem{KindAllocation, alloc.AppendSerialize(nil)})
+		default:
+			block := &NEVMDisconnectBlockWire{
+				SYSBlockHash: make([]byte, HASH_SIZE),
+				Diff:         NEVMAddressDiff{RemovedMNNEVM: make([]NEVMRemoveEntry, i%5)},
+			}
+			items = append(items, BatchItem{KindNEVMDisconnect, block.AppendSerialize(nil)})
+		}
+	}
+	return items
+}
+
+func TestDecodeBatch(t *testing.T) {
+	items := batchItems(1000)
+	for _, opts := range []BatchOptions{{}, {Workers: 1}, {Workers: 8, Window: 1}} {
+		results, err := DecodeBatch(context.Background(), items, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(items) {
+			t.Fatalf("%d results for %d items", len(results), len(items))
+		}
+		for i, res := range results {
+			switch {
+			case i%7 == 3:
+				if res.Err == nil {
+					t.Errorf("item %d: decoded despite trailing bytes", i)
+				}
+			case i%2 == 0:
+				alloc, ok := res.Payload.(*AssetAllocationType)
+				if res.Err != nil || !ok || alloc.VoutAssets[0].AssetGuid != uint64(i) {
+					t.Errorf("item %d: got %+v, %v", i, res.Payload, res.Err)
+				}
+			default:
+				block, ok := res.Payload.(*NEVMDisconnectBlockWire)
+				if res.Err != nil || !ok || len(block.Diff.RemovedMNNEVM) != i%5 {
+					t.Errorf("item %d: got %+v, %v", i, res.Payload, res.Err)
+				}
+			}
+		}
+	}
+
+	if results, err := DecodeBatch(context.Background(), nil, BatchOptions{}); err != nil || len(results) != 0 {
+		t.Errorf("empty batch = %v, %v", results, err)
+	}
+}
+
+func TestDecodeBatchCancel(t *testing.T) {
+	items := batchItems(1000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DecodeBatch(ctx, items, BatchOptions{Workers: 4}); !errors.Is(err, context.Canceled) {
+		t.Errorf("DecodeBatch with a cancelled context = %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	seen := 0
+	err := DecodeBatchFunc(ctx, items, BatchOptions{Workers: 4, Window: 2}, func(i int, p Payload, err error) error {
+		if i != seen {
+			t.Fatalf("result %d delivered out of order, want %d", i, seen)
+		}
+		seen++
+		if i == 100 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || seen != 101 {
+		t.Errorf("cancelled mid-batch: err %v after %d results", err, seen)
+	}
+
+	stop := errors.New("stop")
+	err = DecodeBatchFunc(context.Background(), items, BatchOptions{Workers: 4}, func(i int, p Payload, err error) error {
+		if i == 10 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("DecodeBatchFunc = %v, want the callback's error", err)
+	}
+}
+
+func BenchmarkDecodeBatch(b *testing.B) {
+	items := batchItems(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeBatch(context.Background(), items, BatchOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Commands of the LLMQ lock P2P messages.
+const (
+	CmdCLSig   = "clsig"
+	CmdISDLock = "isdlock"
+)
+
+// Prefixes syscoind hashes into the signing request IDs of ChainLocks and
+// InstantSend locks.
+const (
+	CLSIG_REQUESTID_PREFIX  = "clsig"
+	ISLOCK_REQUESTID_PREFIX = "islock"
+)
+
+// maxISDLockInputs bounds the inputs of a decoded isdlock to what fits in a
+// message.
+const maxISDLockInputs = wire.MaxMessagePayload / (chainhash.HashSize + 4)
+
+// BuildSignHash returns the hash an LLMQ signs for a signing request,
+// syscoind's llmq::BuildSignHash.
+func BuildSignHash(llmqType uint8, quorumHash, id, msgHash chainhash.Hash) chainhash.Hash {
+	var buf [1 + 3*chainhash.HashSize]byte
+	buf[0] = llmqType
+	copy(buf[1:], quorumHash[:])
+	copy(buf[1+chainhash.HashSize:], id[:])
+	copy(buf[1+2*chainhash.HashSize:], msgHash[:])
+	return chainhash.DoubleHashH(buf[:])
+}
+
+// MsgCLSig implements the wire.Message interface and represents a clsig
+// message, the quorum signature that ChainLocks BlockHash at Height.
+type MsgCLSig struct {
+	Height    int32
+	BlockHash chainhash.Hash
+	Sig       [BLS_SIGNATURE_SIZE]byte
+}
+
+// RequestID returns the signing request ID of the ChainLock, the hash of the
+// "clsig" prefix and the height.
+func (msg *MsgCLSig) RequestID() chainhash.Hash {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, CLSIG_REQUESTID_PREFIX)
+	_ = binarySerializer.PutUint32(&buf, littleEndian, uint32(msg.Height))
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// SignHash returns the hash the quorum identified by llmqType and quorumHash
+// signs to ChainLock the block.
+func (msg *MsgCLSig) SignHash(llmqType uint8, quorumHash chainhash.Hash) chainhash.Hash {
+	return BuildSignHash(llmqType, quorumHash, msg.RequestID(), msg.BlockHash)
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgCLSig) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	height, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	msg.Height = int32(height)
+	if _, err := io.ReadFull(r, msg.BlockHash[:]); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, msg.Sig[:])
+	return err
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgCLSig) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(msg.Height)); err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.BlockHash[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg.Sig[:])
+	return err
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgCLSig) Command() string {
+	return CmdCLSig
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgCLSig) MaxPayloadLength(pver uint32) uint32 {
+	return 4 + chainhash.HashSize + BLS_SIGNATURE_SIZE
+}
+
+// MsgISDLock implements the wire.Message interface and represents an isdlock
+// message, the deterministic InstantSend lock of the inputs of Txid.
+// CycleHash is the block hash of the DKG cycle whose quorum signed it.
+type MsgISDLock struct {
+	Version   uint8
+	Inputs    []wire.OutPoint
+	Txid      chainhash.Hash
+	CycleHash chainhash.Hash
+	Sig       [BLS_SIGNATURE_SIZE]byte
+}
+
+// RequestID returns the signing request ID of the lock, the hash of the
+// "islock" prefix and the locked inputs.
+func (msg *MsgISDLock) RequestID() chainhash.Hash {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, ISLOCK_REQUESTID_PREFIX)
+	_ = wire.WriteVarInt(&buf, 0, uint64(len(msg.Inputs)))
+	for i := range msg.Inputs {
+		_ = writeOutPoint(&buf, &msg.Inputs[i])
+	}
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// SignHash returns the hash the quorum identified by llmqType and quorumHash
+// signs to lock the transaction.
+func (msg *MsgISDLock) SignHash(llmqType uint8, quorumHash chainhash.Hash) chainhash.Hash {
+	return BuildSignHash(llmqType, quorumHash, msg.RequestID(), msg.Txid)
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgISDLock) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	var err error
+	if msg.Version, err = binarySerializer.Uint8(r); err != nil {
+		return err
+	}
+	count, err := readElementCount(r, "MsgISDLock.Inputs")
+	if err != nil {
+		return err
+	}
+	if count > maxISDLockInputs {
+		return messageError("MsgISDLock.BtcDecode", "too many inputs")
+	}
+	msg.Inputs = make([]wire.OutPoint, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		var op wire.OutPoint
+		if err := readOutPoint(r, &op); err != nil {
+			return err
+		}
+		msg.Inputs = append(msg.Inputs, op)
+	}
+	if _, err := io.ReadFull(r, msg.Txid[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, msg.CycleHash[:]); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, msg.Sig[:])
+	return err
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgISDLock) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if err := binarySerializer.PutUint8(w, msg.Version); err != nil {
+		return err
+	}
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.Inputs))); err != nil {
+		return err
+	}
+	for i := range msg.Inputs {
+		if err := writeOutPoint(w, &msg.Inputs[i]); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(msg.Txid[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.CycleHash[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg.Sig[:])
+	return err
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgISDLock) Command() string {
+	return CmdISDLock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgISDLock) MaxPayloadLength(pver uint32) uint32 {
+	return wire.MaxMessagePayload
+}
@@ -0,0 +1,93 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	_ wire.Message = (*MsgCLSig)(nil)
+	_ wire.Message = (*MsgISDLock)(nil)
+)
+
+func TestMsgCLSigWire(t *testing.T) {
+	msg := &MsgCLSig{Height: 1234567, BlockHash: randomHash()}
+	copy(msg.Sig[:], randomBytes(BLS_SIGNATURE_SIZE))
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if uint32(buf.Len()) != msg.MaxPayloadLength(wire.ProtocolVersion) {
+		t.Errorf("encoded %d bytes, want %d", buf.Len(), msg.MaxPayloadLength(wire.ProtocolVersion))
+	}
+	var decoded MsgCLSig
+	if err := decoded.BtcDecode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != *msg {
+		t.Errorf("clsig round trip mismatch")
+	}
+}
+
+func TestMsgISDLockWire(t *testing.T) {
+	msg := &MsgISDLock{
+		Version:   1,
+		Inputs:    []wire.OutPoint{{Hash: randomHash(), Index: 0}, {Hash: randomHash(), Index: 7}},
+		Txid:      randomHash(),
+		CycleHash: randomHash(),
+	}
+	copy(msg.Sig[:], randomBytes(BLS_SIGNATURE_SIZE))
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	var decoded MsgISDLock
+	if err := decoded.BtcDecode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, msg) {
+		t.Errorf("isdlock round trip mismatch:\n got %+v\nwant %+v", decoded, msg)
+	}
+}
+
+// TestLockRequestIDs pins the preimages of the request IDs and sign hashes
+// byte by byte.  The expected values are derived here from syscoind's
+// serialization rules rather than taken from syscoind or Dash, so the test
+// guards the layout but is not a known-answer test.
+func TestLockRequestIDs(t *testing.T) {
+	// The clsig request ID hashes the serialized pair ("clsig", height).
+	clsig := &MsgCLSig{Height: 500}
+	pre := append([]byte{5}, "clsig"...)
+	pre = binary.LittleEndian.AppendUint32(pre, 500)
+	if got, want := clsig.RequestID(), chainhash.DoubleHashH(pre); got != want {
+		t.Errorf("clsig RequestID = %v, want %v", got, want)
+	}
+
+	// The islock request ID hashes "islock" followed by the inputs.
+	op := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 2}
+	islock := &MsgISDLock{Inputs: []wire.OutPoint{op}}
+	pre = append([]byte{6}, "islock"...)
+	pre = append(pre, 1)
+	pre = append(pre, op.Hash[:]...)
+	pre = binary.LittleEndian.AppendUint32(pre, 2)
+	if got, want := islock.RequestID(), chainhash.DoubleHashH(pre); got != want {
+		t.Errorf("islock RequestID = %v, want %v", got, want)
+	}
+
+	quorumHash := chainhash.Hash{0xaa}
+	clsig.BlockHash = chainhash.Hash{0xbb}
+	pre = append([]byte{1}, quorumHash[:]...)
+	id := clsig.RequestID()
+	pre = append(pre, id[:]...)
+	pre = append(pre, clsig.BlockHash[:]...)
+	if got, want := clsig.SignHash(1, quorumHash), chainhash.DoubleHashH(pre); got != want {
+		t.Errorf("clsig SignHash = %v, want %v", got, want)
+	}
+}
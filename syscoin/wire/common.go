@@ -0,0 +1,122 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// COIN is the number of satoshis in one SYS.
+	COIN = 100000000
+
+	// MAX_MONEY is the largest amount of SYS in satoshis, as checked by
+	// syscoind's MoneyRange.
+	MAX_MONEY = 888000000 * COIN
+
+	// MAX_SIZE is the largest compact size syscoind accepts for a list or
+	// byte array length (see ReadCompactSize in serialize.h).
+	MAX_SIZE = 0x02000000
+
+	// maxPreallocEntries caps the number of list entries reserved up front
+	// while decoding.  Lists longer than this grow as elements are actually
+	// read, so a forged count cannot force a large allocation on its own.
+	maxPreallocEntries = 64
+
+	// minReadChunk is the first chunk size used when reading a variable
+	// length byte array.  Later chunks double, which keeps the allocation
+	// proportional to the bytes the reader actually supplied.
+	minReadChunk = 4096
+)
+
+// messageError creates an error for the given function and description in
+// the same form btcd's wire package reports malformed messages.
+func messageError(f string, desc string) *wire.MessageError {
+	return &wire.MessageError{Func: f, Description: desc}
+}
+
+// readElementCount reads a compact size list length and rejects values above
+// MAX_SIZE the same way syscoind does.
+func readElementCount(r io.Reader, fieldName string) (uint64, error) {
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, err
+	}
+	if count > MAX_SIZE {
+		str := fmt.Sprintf("%s count is larger than the max allowed size "+
+			"[count %d, max %d]", fieldName, count, MAX_SIZE)
+		return 0, messageError("readElementCount", str)
+	}
+	return count, nil
+}
+
+// preallocLen returns the capacity to reserve for a list of count decoded
+// elements.
+func preallocLen(count uint64) int {
+	if count > maxPreallocEntries {
+		return maxPreallocEntries
+	}
+	return int(count)
+}
+
+// readVarBytes reads a variable length byte array like wire.ReadVarBytes but
+// grows the destination as data arrives instead of allocating the full
+// advertised length up front.
+func readVarBytes(r io.Reader, maxAllowed uint32, fieldName string) ([]byte, error) {
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(maxAllowed) {
+		str := fmt.Sprintf("%s is larger than the max allowed size "+
+			"[count %d, max %d]", fieldName, count, maxAllowed)
+		return nil, messageError("readVarBytes", str)
+	}
+	return readBytes(r, int(count))
+}
+
+// varBytesSerializeSize returns the number of bytes wire.WriteVarBytes writes
+// for b.
+func varBytesSerializeSize(b []byte) int {
+	return wire.VarIntSerializeSize(uint64(len(b))) + len(b)
+}
+
+// readBytes reads exactly n bytes from r in doubling chunks.
+func readBytes(r io.Reader, n int) ([]byte, error) {
+	if n <= minReadChunk {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	b := make([]byte, 0, minReadChunk)
+	for len(b) < n {
+		chunk := len(b)
+		if chunk < minReadChunk {
+			chunk = minReadChunk
+		}
+		if chunk > n-len(b) {
+			chunk = n - len(b)
+		}
+		if cap(b)-len(b) < chunk {
+			grown := make([]byte, len(b), len(b)+chunk)
+			copy(grown, b)
+			b = grown
+		}
+		start := len(b)
+		b = b[:start+chunk]
+		if _, err := io.ReadFull(r, b[start:]); err != nil {
+			if err == io.EOF && start > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// DecodeInterruptedError is returned by the DeserializeContext methods when
+// their context ends before decoding finishes.  It wraps the context's
+// error, so errors.Is(err, context.Canceled) and
+// errors.Is(err, context.DeadlineExceeded) work as usual.
+type DecodeInterruptedError struct {
+	// Offset is the number of bytes consumed from the reader before
+	// decoding stopped.
+	Offset int64
+
+	// Err is the context's error.
+	Err error
+}
+
+func (e *DecodeInterruptedError) Error() string {
+	return fmt.Sprintf("decoding interrupted after %d bytes: %v", e.Offset, e.Err)
+}
+
+func (e *DecodeInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// contextReader checks its context before every read from the underlying
+// reader.  The decoders read each field, list element and byte array chunk
+// separately, so this bounds the work done after the context ends to one
+// read; a read already blocked in the underlying reader is not interrupted.
+type contextReader struct {
+	ctx    context.Context
+	r      io.Reader
+	offset int64
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, &DecodeInterruptedError{Offset: cr.offset, Err: err}
+	}
+	n, err := cr.r.Read(p)
+	cr.offset += int64(n)
+	return n, err
+}
+
+// DeserializePayloadContext decodes p from r like p.Deserialize, but stops
+// with a *DecodeInterruptedError once ctx is cancelled or its deadline
+// passes.
+func DeserializePayloadContext(ctx context.Context, p Payload, r io.Reader) error {
+	cr := &contextReader{ctx: ctx, r: r}
+	if err := p.Deserialize(cr); err != nil {
+		return err
+	}
+	// Report an interruption even if it came after the last read, so that
+	// callers with an expired deadline never act on the result.
+	if err := ctx.Err(); err != nil {
+		return &DecodeInterruptedError{Offset: cr.offset, Err: err}
+	}
+	return nil
+}
+
+// DeserializeContext decodes the allocation like Deserialize, stopping when
+// ctx ends.  See DeserializePayloadContext.
+func (a *AssetAllocationType) DeserializeContext(ctx context.Context, r io.Reader) error {
+	return DeserializePayloadContext(ctx, a, r)
+}
+
+// DeserializeContext decodes the mint like Deserialize, stopping when ctx
+// ends.  See DeserializePayloadContext.
+func (a *MintSyscoinType) DeserializeContext(ctx context.Context, r io.Reader) error {
+	return DeserializePayloadContext(ctx, a, r)
+}
+
+// DeserializeContext decodes the burn like Deserialize, stopping when ctx
+// ends.  See DeserializePayloadContext.
+func (a *SyscoinBurnToEthereumType) DeserializeContext(ctx context.Context, r io.Reader) error {
+	return DeserializePayloadContext(ctx, a, r)
+}
+
+// DeserializeContext decodes the block like Deserialize, stopping when ctx
+// ends, including part way through NEVMBlockData or VersionHashes.  See
+// DeserializePayloadContext.
+func (a *NEVMBlockWire) DeserializeContext(ctx context.Context, r io.Reader) error {
+	return DeserializePayloadContext(ctx, a, r)
+}
+
+// DeserializeContext decodes the disconnect message like Deserialize,
+// stopping when ctx ends.  See DeserializePayloadContext.
+func (a *NEVMDisconnectBlockWire) DeserializeContext(ctx context.Context, r io.Reader) error {
+	return DeserializePayloadContext(ctx, a, r)
+}
+
+// NewNEVMBlockReaderContext is NewNEVMBlockReader with every later read,
+// including those through Body and Trailer, stopping once ctx ends.
+func NewNEVMBlockReaderContext(ctx context.Context, r io.Reader) (*NEVMBlockReader, error) {
+	return NewNEVMBlockReader(&contextReader{ctx: ctx, r: r})
+}
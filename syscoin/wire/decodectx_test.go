@@ -0,0 +1,118 @@
+package wire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// hookReader returns at most chunk bytes per read from r and calls hook
+// with the running total after each.
+type hookReader struct {
+	r     io.Reader
+	chunk int
+	total int64
+	hook  func(total int64)
+}
+
+func (h *hookReader) Read(p []byte) (int, error) {
+	if len(p) > h.chunk {
+		p = p[:h.chunk]
+	}
+	n, err := h.r.Read(p)
+	h.total += int64(n)
+	h.hook(h.total)
+	return n, err
+}
+
+// versionHashFlood yields an endless run of 32-byte version hashes.
+type versionHashFlood struct{ pos int }
+
+func (f *versionHashFlood) Read(p []byte) (int, error) {
+	for i := range p {
+		if f.pos%(1+HASH_SIZE) == 0 {
+			p[i] = HASH_SIZE
+		} else {
+			p[i] = 0xab
+		}
+		f.pos++
+	}
+	return len(p), nil
+}
+
+func TestDeserializeContextBlockBody(t *testing.T) {
+	block := streamTestBlock(1 << 20)
+	raw := serializeBlock(t, block)
+
+	// Decodes normally when the context stays live.
+	var got NEVMBlockWire
+	if err := got.DeserializeContext(context.Background(), bytes.NewReader(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.NEVMBlockData, block.NEVMBlockData) {
+		t.Error("block data mismatch")
+	}
+
+	// Cancelled part way through NEVMBlockData.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stopAt int64
+	r := &hookReader{r: bytes.NewReader(raw), chunk: 1000, hook: func(total int64) {
+		if total >= 200000 && stopAt == 0 {
+			stopAt = total
+			cancel()
+		}
+	}}
+	err := got.DeserializeContext(ctx, r)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("DeserializeContext = %v, want context.Canceled", err)
+	}
+	var interrupted *DecodeInterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Offset != stopAt {
+		t.Errorf("interrupted at %+v, want offset %d", interrupted, stopAt)
+	}
+}
+
+func TestDeserializeContextVersionHashes(t *testing.T) {
+	// A block claiming MAX_SIZE version hashes and supplying them forever.
+	var head bytes.Buffer
+	head.Write(make([]byte, 3*HASH_SIZE))
+	wire.WriteVarInt(&head, 0, 0)
+	head.Write(make([]byte, HASH_SIZE))
+	wire.WriteVarInt(&head, 0, MAX_SIZE)
+	r := io.MultiReader(&head, &versionHashFlood{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var block NEVMBlockWire
+	err := block.DeserializeContext(ctx, r)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DeserializeContext = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("decoding ran %v past a 20ms deadline", elapsed)
+	}
+}
+
+func TestDeserializeContextExpired(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	alloc := benchAllocation()
+	var got AssetAllocationType
+	err := got.DeserializeContext(ctx, bytes.NewReader(alloc.AppendSerialize(nil)))
+	var interrupted *DecodeInterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Offset != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("DeserializeContext with a cancelled context = %v", err)
+	}
+
+	br, err := NewNEVMBlockReaderContext(ctx, bytes.NewReader(serializeBlock(t, streamTestBlock(10))))
+	if br != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("NewNEVMBlockReaderContext with a cancelled context = %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+const (
+	// maxUintPayload is the largest number of bytes PutUint writes.
+	maxUintPayload = 10
+
+	// encoderBufferSize is the initial capacity of pooled encode buffers,
+	// enough for any allocation or burn payload.
+	encoderBufferSize = 512
+
+	// maxPooledEncoderSize caps the capacity of buffers returned to the
+	// pool, so that encoding one large payload does not pin its buffer.
+	maxPooledEncoderSize = 64 * 1024
+)
+
+// Appender is implemented by payload types that can serialize themselves by
+// appending to a byte slice.
+type Appender interface {
+	// AppendSerialize appends the value in syscoind's wire format to dst
+	// and returns the extended slice.
+	AppendSerialize(dst []byte) []byte
+}
+
+// sizedAppender is an Appender that knows its encoded size up front.
+type sizedAppender interface {
+	Appender
+	SerializeSize() int
+}
+
+// encoderPool holds reusable encode buffers.
+var encoderPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, encoderBufferSize)
+		return &b
+	},
+}
+
+// writeAppended encodes a into a pooled buffer and writes it to w with a
+// single Write call.
+func writeAppended(w io.Writer, a sizedAppender) error {
+	bp := encoderPool.Get().(*[]byte)
+	buf := *bp
+	if size := a.SerializeSize(); cap(buf) < size {
+		buf = make([]byte, 0, size)
+	}
+	buf = a.AppendSerialize(buf[:0])
+	_, err := w.Write(buf)
+	if cap(buf) <= maxPooledEncoderSize {
+		*bp = buf[:0]
+		encoderPool.Put(bp)
+	}
+	return err
+}
+
+// AppendPayload appends p in syscoind's wire format to dst.  Payloads that
+// are not Appenders are encoded through Serialize.
+func AppendPayload(dst []byte, p Payload) ([]byte, error) {
+	if a, ok := p.(Appender); ok {
+		return a.AppendSerialize(dst), nil
+	}
+	buf := bytes.NewBuffer(dst)
+	buf.Grow(p.SerializeSize())
+	if err := p.Serialize(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AppendUint appends n in syscoind's VARINT encoding, as written by PutUint,
+// to dst.
+func AppendUint(dst []byte, n uint64) []byte {
+	var tmp [maxUintPayload]byte
+	i := 0
+	for {
+		var mask uint64
+		if i > 0 {
+			mask = 0x80
+		}
+		tmp[i] = byte(n&0x7f | mask)
+		if n <= 0x7f {
+			break
+		}
+		n = (n >> 7) - 1
+		i++
+	}
+	for ; i >= 0; i-- {
+		dst = append(dst, tmp[i])
+	}
+	return dst
+}
+
+// appendVarInt appends n as a compact size, as written by wire.WriteVarInt.
+func appendVarInt(dst []byte, n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return append(dst, byte(n))
+	case n <= 0xffff:
+		return binary.LittleEndian.AppendUint16(append(dst, 0xfd), uint16(n))
+	case n <= 0xffffffff:
+		return binary.LittleEndian.AppendUint32(append(dst, 0xfe), uint32(n))
+	default:
+		return binary.LittleEndian.AppendUint64(append(dst, 0xff), n)
+	}
+}
+
+// appendVarBytes appends b prefixed with its compact size length, as written
+// by wire.WriteVarBytes.
+func appendVarBytes(dst, b []byte) []byte {
+	return append(appendVarInt(dst, uint64(len(b))), b...)
+}
+
+func (a *AssetOutValueType) AppendSerialize(dst []byte) []byte {
+	dst = appendVarInt(dst, uint64(a.N))
+	return AppendUint(dst, CompressAmount(uint64(a.ValueSat)))
+}
+
+func (a *AssetOutType) AppendSerialize(dst []byte) []byte {
+	dst = AppendUint(dst, a.AssetGuid)
+	dst = appendVarInt(dst, uint64(len(a.Values)))
+	for i := range a.Values {
+		dst = a.Values[i].AppendSerialize(dst)
+	}
+	return dst
+}
+
+func (a *AssetAllocationType) AppendSerialize(dst []byte) []byte {
+	dst = appendVarInt(dst, uint64(len(a.VoutAssets)))
+	for i := range a.VoutAssets {
+		dst = a.VoutAssets[i].AppendSerialize(dst)
+	}
+	return dst
+}
+
+func (a *MintSyscoinType) AppendSerialize(dst []byte) []byte {
+	dst = a.Allocation.AppendSerialize(dst)
+	dst = append(dst, a.TxHash...)
+	dst = append(dst, a.BlockHash...)
+	dst = binary.LittleEndian.AppendUint16(dst, a.TxPos)
+	dst = appendVarBytes(dst, a.TxParentNodes)
+	dst = appendVarBytes(dst, a.TxPath)
+	dst = binary.LittleEndian.AppendUint16(dst, a.ReceiptPos)
+	dst = appendVarBytes(dst, a.ReceiptParentNodes)
+	dst = append(dst, a.TxRoot...)
+	return append(dst, a.ReceiptRoot...)
+}
+
+func (a *SyscoinBurnToEthereumType) AppendSerialize(dst []byte) []byte {
+	dst = a.Allocation.AppendSerialize(dst)
+	return appendVarBytes(dst, a.EthAddress)
+}
+
+func (a *AssetType) AppendSerialize(dst []byte) []byte {
+	dst = appendVarBytes(dst, a.Symbol)
+	return append(dst, a.Precision)
+}
+
+func (a *NEVMAddressEntry) AppendSerialize(dst []byte) []byte {
+	dst = appendVarBytes(dst, a.Address)
+	return binary.LittleEndian.AppendUint32(dst, a.CollateralHeight)
+}
+
+func (a *NEVMAddressUpdateEntry) AppendSerialize(dst []byte) []byte {
+	dst = appendVarBytes(dst, a.OldAddress)
+	dst = appendVarBytes(dst, a.NewAddress)
+	return binary.LittleEndian.AppendUint32(dst, a.CollateralHeight)
+}
+
+func (a *NEVMRemoveEntry) AppendSerialize(dst []byte) []byte {
+	return appendVarBytes(dst, a.Address)
+}
+
+func (d *NEVMAddressDiff) AppendSerialize(dst []byte) []byte {
+	dst = appendVarInt(dst, uint64(len(d.AddedMNNEVM)))
+	for i := range d.AddedMNNEVM {
+		dst = d.AddedMNNEVM[i].AppendSerialize(dst)
+	}
+	dst = appendVarInt(dst, uint64(len(d.UpdatedMNNEVM)))
+	for i := range d.UpdatedMNNEVM {
+		dst = d.UpdatedMNNEVM[i].AppendSerialize(dst)
+	}
+	dst = appendVarInt(dst, uint64(len(d.RemovedMNNEVM)))
+	for i := range d.RemovedMNNEVM {
+		dst = d.RemovedMNNEVM[i].AppendSerialize(dst)
+	}
+	return dst
+}
+
+// appendHeader appends the fields of the block up to and including the
+// length of NEVMBlockData.
+func (a *NEVMBlockWire) appendHeader(dst []byte) []byte {
+	dst = append(dst, a.NEVMBlockHash...)
+	dst = append(dst, a.TxRoot...)
+	dst = append(dst, a.ReceiptRoot...)
+	return appendVarInt(dst, uint64(len(a.NEVMBlockData)))
+}
+
+// appendTrailer appends the fields of the block following NEVMBlockData.
+func (a *NEVMBlockWire) appendTrailer(dst []byte) []byte {
+	dst = append(dst, a.SYSBlockHash...)
+	dst = appendVarInt(dst, uint64(len(a.VersionHashes)))
+	for _, vh := range a.VersionHashes {
+		dst = appendVarBytes(dst, vh)
+	}
+	return a.Diff.AppendSerialize(dst)
+}
+
+func (a *NEVMBlockWire) AppendSerialize(dst []byte) []byte {
+	dst = a.appendHeader(dst)
+	dst = append(dst, a.NEVMBlockData...)
+	return a.appendTrailer(dst)
+}
+
+// writeNEVMBlock writes a with three Write calls, passing NEVMBlockData to
+// w directly rather than copying up to MAX_NEVM_BLOCK_SIZE bytes into an
+// encode buffer.
+func writeNEVMBlock(w io.Writer, a *NEVMBlockWire) error {
+	bp := encoderPool.Get().(*[]byte)
+	defer func() {
+		if cap(*bp) <= maxPooledEncoderSize {
+			*bp = (*bp)[:0]
+			encoderPool.Put(bp)
+		}
+	}()
+	*bp = a.appendHeader((*bp)[:0])
+	if _, err := w.Write(*bp); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.NEVMBlockData); err != nil {
+		return err
+	}
+	*bp = a.appendTrailer((*bp)[:0])
+	_, err := w.Write(*bp)
+	return err
+}
+
+func (a *NEVMDisconnectBlockWire) AppendSerialize(dst []byte) []byte {
+	dst = append(dst, a.SYSBlockHash...)
+	return a.Diff.AppendSerialize(dst)
+}
@@ -0,0 +1,167 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TestAppendSerializeGolden checks that every golden payload re-encodes to
+// its recorded bytes through AppendSerialize, after existing data in dst.
+func TestAppendSerializeGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "vectors", "v1", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := []byte{0xde, 0xad}
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file goldenFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for _, v := range file.Vectors {
+			want, err := hex.DecodeString(v.Hex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := NewPayload(PayloadKind(file.Kind))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Deserialize(bytes.NewReader(want)); err != nil {
+				t.Fatalf("%s/%s: %v", file.Kind, v.Name, err)
+			}
+			dst := append([]byte(nil), prefix...)
+			got, err := AppendPayload(dst, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got[:len(prefix)], prefix) || !bytes.Equal(got[len(prefix):], want) {
+				t.Errorf("%s/%s: AppendPayload = %x, want %x%x", file.Kind, v.Name, got, prefix, want)
+			}
+			if n := len(got) - len(prefix); n != p.SerializeSize() {
+				t.Errorf("%s/%s: appended %d bytes, SerializeSize %d", file.Kind, v.Name, n, p.SerializeSize())
+			}
+		}
+	}
+}
+
+func TestAppendVarInt(t *testing.T) {
+	for _, n := range []uint64{0, 0xfc, 0xfd, 0xffff, 0x10000, 0xffffffff, 0x100000000, 1<<64 - 1} {
+		var want bytes.Buffer
+		if err := wire.WriteVarInt(&want, 0, n); err != nil {
+			t.Fatal(err)
+		}
+		if got := appendVarInt(nil, n); !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("appendVarInt(%d) = %x, want %x", n, got, want.Bytes())
+		}
+	}
+}
+
+func TestPutUintSingleWrite(t *testing.T) {
+	for _, n := range []uint64{0, 0x7f, 0x80, 1 << 32, 1<<64 - 1} {
+		var w countingWriter
+		if err := PutUint(&w, n); err != nil {
+			t.Fatal(err)
+		}
+		if w.writes != 1 || w.n != SizeOfUint(n) {
+			t.Errorf("PutUint(%d): %d writes of %d bytes, want 1 of %d", n, w.writes, w.n, SizeOfUint(n))
+		}
+	}
+}
+
+func TestEncodeAllocations(t *testing.T) {
+	alloc := benchAllocation()
+	buf := make([]byte, 0, alloc.SerializeSize())
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"PutUint", func() { PutUint(io.Discard, 1<<40) }},
+		{"AppendSerialize", func() { buf = alloc.AppendSerialize(buf[:0]) }},
+		{"Serialize", func() { alloc.Serialize(io.Discard) }},
+	}
+	for _, test := range tests {
+		test.f()
+		if allocs := testing.AllocsPerRun(100, test.f); allocs != 0 {
+			t.Errorf("%s: %v allocations per run, want 0", test.name, allocs)
+		}
+	}
+}
+
+type countingWriter struct {
+	writes int
+	n      int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.n += len(p)
+	return len(p), nil
+}
+
+func benchAllocation() *AssetAllocationType {
+	alloc := &AssetAllocationType{}
+	for i := 0; i < 4; i++ {
+		out := AssetOutType{AssetGuid: 1<<32 | uint64(i)}
+		for j := 0; j < 8; j++ {
+			out.Values = append(out.Values, AssetOutValueType{N: uint32(i*8 + j), ValueSat: 123456789})
+		}
+		alloc.VoutAssets = append(alloc.VoutAssets, out)
+	}
+	return alloc
+}
+
+func BenchmarkPutUint(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PutUint(io.Discard, uint64(i)<<20)
+	}
+}
+
+func BenchmarkAllocationSerialize(b *testing.B) {
+	alloc := benchAllocation()
+	b.ReportAllocs()
+	b.SetBytes(int64(alloc.SerializeSize()))
+	for i := 0; i < b.N; i++ {
+		alloc.Serialize(io.Discard)
+	}
+}
+
+func BenchmarkAllocationAppendSerialize(b *testing.B) {
+	alloc := benchAllocation()
+	buf := make([]byte, 0, alloc.SerializeSize())
+	b.ReportAllocs()
+	b.SetBytes(int64(alloc.SerializeSize()))
+	for i := 0; i < b.N; i++ {
+		buf = alloc.AppendSerialize(buf[:0])
+	}
+}
+
+func BenchmarkMintSerialize(b *testing.B) {
+	mint := &MintSyscoinType{
+		Allocation:         *benchAllocation(),
+		TxHash:             randomBytes(HASH_SIZE),
+		BlockHash:          randomBytes(HASH_SIZE),
+		TxParentNodes:      randomBytes(600),
+		TxPath:             randomBytes(3),
+		TxRoot:             randomBytes(HASH_SIZE),
+		ReceiptRoot:        randomBytes(HASH_SIZE),
+		ReceiptParentNodes: randomBytes(900),
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(mint.SerializeSize()))
+	for i := 0; i < b.N; i++ {
+		mint.Serialize(io.Discard)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ETH_ADDRESS_SIZE is the length of an NEVM (Ethereum) address in bytes.
+const ETH_ADDRESS_SIZE = 20
+
+// FormatEthAddress returns addr as a 0x-prefixed hex string with the EIP-55
+// mixed-case checksum.
+func FormatEthAddress(addr []byte) (string, error) {
+	if len(addr) != ETH_ADDRESS_SIZE {
+		return "", fmt.Errorf("NEVM address has %d bytes, want %d", len(addr), ETH_ADDRESS_SIZE)
+	}
+	lower := hex.EncodeToString(addr)
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	digest := h.Sum(nil)
+
+	// A hex letter is upper-cased when the matching nibble of the
+	// Keccak-256 hash of the lower-case address is 8 or more.
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := digest[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if c >= 'a' && nibble&0xf >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out), nil
+}
+
+// ParseEthAddress decodes a 0x-prefixed hex NEVM address.  An all lower or
+// all upper case address is accepted as is; a mixed-case one must carry a
+// valid EIP-55 checksum.
+func ParseEthAddress(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("NEVM address %q lacks the 0x prefix", s)
+	}
+	digits := s[2:]
+	addr, err := hex.DecodeString(digits)
+	if err != nil || len(addr) != ETH_ADDRESS_SIZE {
+		return nil, fmt.Errorf("NEVM address %q is not %d hex bytes", s, ETH_ADDRESS_SIZE)
+	}
+	if digits == strings.ToLower(digits) || digits == strings.ToUpper(digits) {
+		return addr, nil
+	}
+	want, _ := FormatEthAddress(addr)
+	if digits != want[2:] {
+		return nil, fmt.Errorf("NEVM address %q has an invalid EIP-55 checksum", s)
+	}
+	return addr, nil
+}
+
+// EthAddressString returns the burn's destination NEVM address in EIP-55
+// form.
+func (a *SyscoinBurnToEthereumType) EthAddressString() (string, error) {
+	return FormatEthAddress(a.EthAddress)
+}
+
+// SetEthAddress parses s with ParseEthAddress and sets the burn's
+// destination to it.
+func (a *SyscoinBurnToEthereumType) SetEthAddress(s string) error {
+	addr, err := ParseEthAddress(s)
+	if err != nil {
+		return err
+	}
+	a.EthAddress = addr
+	return nil
+}
+
+// AddressString returns the masternode's NEVM address in EIP-55 form.
+func (a *NEVMAddressEntry) AddressString() (string, error) {
+	return FormatEthAddress(a.Address)
+}
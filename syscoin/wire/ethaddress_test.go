@@ -0,0 +1,59 @@
+package wire
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestEthAddressChecksum uses the examples of EIP-55.
+func TestEthAddressChecksum(t *testing.T) {
+	for _, want := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		addr, err := ParseEthAddress(want)
+		if err != nil {
+			t.Fatalf("ParseEthAddress(%s): %v", want, err)
+		}
+		burn := &SyscoinBurnToEthereumType{EthAddress: addr}
+		if got, err := burn.EthAddressString(); err != nil || got != want {
+			t.Errorf("EthAddressString = %s, %v, want %s", got, err, want)
+		}
+		entry := &NEVMAddressEntry{Address: addr}
+		if got, err := entry.AddressString(); err != nil || got != want {
+			t.Errorf("AddressString = %s, %v, want %s", got, err, want)
+		}
+		if _, err := ParseEthAddress("0x" + strings.ToLower(want[2:])); err != nil {
+			t.Errorf("lower-case %s rejected: %v", want, err)
+		}
+		if _, err := ParseEthAddress("0x" + strings.ToUpper(want[2:])); err != nil {
+			t.Errorf("upper-case %s rejected: %v", want, err)
+		}
+		// Flipping the case of one letter breaks the checksum.
+		bad := []byte(want)
+		for i := 2; i < len(bad); i++ {
+			if c := bad[i]; c >= 'a' && c <= 'f' {
+				bad[i] = c - 'a' + 'A'
+				break
+			}
+		}
+		if _, err := ParseEthAddress(string(bad)); err == nil {
+			t.Errorf("bad checksum %s accepted", bad)
+		}
+	}
+
+	var burn SyscoinBurnToEthereumType
+	if err := burn.SetEthAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"); err != nil || len(burn.EthAddress) != ETH_ADDRESS_SIZE {
+		t.Errorf("SetEthAddress = %x, %v", burn.EthAddress, err)
+	}
+	for _, s := range []string{"", "5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", "0xzzAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"} {
+		if _, err := ParseEthAddress(s); err == nil {
+			t.Errorf("ParseEthAddress(%q) accepted", s)
+		}
+	}
+	if _, err := FormatEthAddress(make([]byte, 19)); err == nil {
+		t.Error("expected a 19-byte address to be rejected")
+	}
+}
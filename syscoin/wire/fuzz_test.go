@@ -0,0 +1,280 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// serializer is implemented by every payload type with a Serialize and
+// Deserialize pair.
+type serializer interface {
+	Serialize(w io.Writer) error
+	Deserialize(r io.Reader) error
+}
+
+// maxAllocPerInputByte and maxAllocOverhead bound the memory a single decode
+// may allocate relative to the size of its input.
+const (
+	maxAllocPerInputByte = 64
+	maxAllocOverhead     = 1 << 20
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func mustSerialize(s serializer) []byte {
+	var buf bytes.Buffer
+	if err := s.Serialize(&buf); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// The fuzz seeds below are hand-built values and byte strings, not payloads
+// taken from mainnet.  Captured payloads can be added as corpus files under
+// testdata/fuzz/<FuzzTarget>/ in the "go test fuzz v1" format; go test runs
+// them as seeds without further changes.
+
+// seedAllocation is an allocation send moving two assets.
+var seedAllocation = AssetAllocationType{
+	VoutAssets: []AssetOutType{
+		{AssetGuid: 123456, Values: []AssetOutValueType{{N: 0, ValueSat: 100000000}, {N: 2, ValueSat: 5}}},
+		{AssetGuid: 2305843009213693951, Values: []AssetOutValueType{{N: 1, ValueSat: 888800000000000000}}},
+	},
+}
+
+// seedDiff exercises every masternode list section of an NEVM block.
+var seedDiff = NEVMAddressDiff{
+	AddedMNNEVM:   []NEVMAddressEntry{{Address: bytes.Repeat([]byte{0x11}, 20), CollateralHeight: 1004242}},
+	UpdatedMNNEVM: []NEVMAddressUpdateEntry{{OldAddress: bytes.Repeat([]byte{0x22}, 20), NewAddress: bytes.Repeat([]byte{0x33}, 20), CollateralHeight: 1500000}},
+	RemovedMNNEVM: []NEVMRemoveEntry{{Address: bytes.Repeat([]byte{0x44}, 20)}},
+}
+
+// fuzzRoundTrip decodes data into a fresh T, checks the allocation bound and
+// verifies that re-encoding reproduces exactly the consumed prefix of data.
+func fuzzRoundTrip[T any, PT interface {
+	*T
+	serializer
+}](t *testing.T, data []byte) {
+	var v T
+	r := bytes.NewReader(data)
+	var err error
+	allocated := measureAlloc(func() {
+		err = PT(&v).Deserialize(r)
+	})
+	if limit := uint64(len(data))*maxAllocPerInputByte + maxAllocOverhead; allocated > limit {
+		t.Fatalf("decoding %d bytes allocated %d bytes (limit %d)", len(data), allocated, limit)
+	}
+	if err != nil {
+		return
+	}
+	consumed := data[:len(data)-r.Len()]
+	var buf bytes.Buffer
+	if err := PT(&v).Serialize(&buf); err != nil {
+		t.Fatalf("Serialize of decoded value failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), consumed) {
+		t.Fatalf("round trip mismatch:\n got %x\nwant %x", buf.Bytes(), consumed)
+	}
+}
+
+// measureAlloc returns the number of heap bytes allocated while running fn.
+func measureAlloc(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func FuzzAssetAllocationType(f *testing.F) {
+	f.Add(mustSerialize(&seedAllocation))
+	f.Add([]byte{0x00})
+	f.Add(mustHex("ff0000000000000001"))
+	f.Fuzz(fuzzRoundTrip[AssetAllocationType])
+}
+
+func FuzzAssetOutType(f *testing.F) {
+	f.Add(mustSerialize(&seedAllocation.VoutAssets[0]))
+	f.Add(mustSerialize(&seedAllocation.VoutAssets[1]))
+	f.Fuzz(fuzzRoundTrip[AssetOutType])
+}
+
+func FuzzAssetOutValueType(f *testing.F) {
+	f.Add(mustSerialize(&AssetOutValueType{N: 0, ValueSat: 100000000}))
+	f.Add(mustSerialize(&AssetOutValueType{N: 4294967295, ValueSat: 1}))
+	f.Add(mustHex("00ffffffffffffffffff7f"))
+	f.Fuzz(fuzzRoundTrip[AssetOutValueType])
+}
+
+func FuzzMintSyscoinType(f *testing.F) {
+	mint := MintSyscoinType{
+		Allocation:         seedAllocation,
+		TxHash:             bytes.Repeat([]byte{0xaa}, HASH_SIZE),
+		BlockHash:          bytes.Repeat([]byte{0xbb}, HASH_SIZE),
+		TxPos:              3,
+		TxParentNodes:      bytes.Repeat([]byte{0xf9}, 532),
+		TxPath:             []byte{0x03},
+		TxRoot:             bytes.Repeat([]byte{0xcc}, HASH_SIZE),
+		ReceiptRoot:        bytes.Repeat([]byte{0xdd}, HASH_SIZE),
+		ReceiptPos:         3,
+		ReceiptParentNodes: bytes.Repeat([]byte{0xf9}, 1210),
+	}
+	f.Add(mustSerialize(&mint))
+	f.Fuzz(fuzzRoundTrip[MintSyscoinType])
+}
+
+func FuzzSyscoinBurnToEthereumType(f *testing.F) {
+	burn := SyscoinBurnToEthereumType{
+		Allocation: seedAllocation,
+		EthAddress: mustHex("9f8c0ea5bd7e0f0bd5b7e1b8b6cd6a2b2d1ee3c4"),
+	}
+	f.Add(mustSerialize(&burn))
+	f.Fuzz(fuzzRoundTrip[SyscoinBurnToEthereumType])
+}
+
+func FuzzAssetType(f *testing.F) {
+	f.Add(mustSerialize(&AssetType{Symbol: []byte("USDT"), Precision: 6}))
+	f.Add(mustSerialize(&AssetType{Symbol: []byte{}, Precision: 8}))
+	f.Fuzz(fuzzRoundTrip[AssetType])
+}
+
+func FuzzNEVMAddressEntry(f *testing.F) {
+	f.Add(mustSerialize(&seedDiff.AddedMNNEVM[0]))
+	f.Fuzz(fuzzRoundTrip[NEVMAddressEntry])
+}
+
+func FuzzNEVMAddressUpdateEntry(f *testing.F) {
+	f.Add(mustSerialize(&seedDiff.UpdatedMNNEVM[0]))
+	f.Fuzz(fuzzRoundTrip[NEVMAddressUpdateEntry])
+}
+
+func FuzzNEVMRemoveEntry(f *testing.F) {
+	f.Add(mustSerialize(&seedDiff.RemovedMNNEVM[0]))
+	f.Fuzz(fuzzRoundTrip[NEVMRemoveEntry])
+}
+
+func FuzzNEVMAddressDiff(f *testing.F) {
+	f.Add(mustSerialize(&seedDiff))
+	f.Add([]byte{0x00, 0x00, 0x00})
+	f.Fuzz(fuzzRoundTrip[NEVMAddressDiff])
+}
+
+func FuzzNEVMBlockWire(f *testing.F) {
+	block := NEVMBlockWire{
+		NEVMBlockHash: bytes.Repeat([]byte{0x01}, HASH_SIZE),
+		TxRoot:        bytes.Repeat([]byte{0x02}, HASH_SIZE),
+		ReceiptRoot:   bytes.Repeat([]byte{0x03}, HASH_SIZE),
+		NEVMBlockData: bytes.Repeat([]byte{0xf9}, 600),
+		SYSBlockHash:  bytes.Repeat([]byte{0x04}, HASH_SIZE),
+		VersionHashes: [][]byte{append([]byte{0x01}, bytes.Repeat([]byte{0x05}, HASH_SIZE-1)...), {}},
+		Diff:          seedDiff,
+	}
+	f.Add(mustSerialize(&block))
+	f.Fuzz(fuzzRoundTrip[NEVMBlockWire])
+}
+
+func FuzzNEVMDisconnectBlockWire(f *testing.F) {
+	disconnect := NEVMDisconnectBlockWire{
+		SYSBlockHash: bytes.Repeat([]byte{0x04}, HASH_SIZE),
+		Diff:         seedDiff,
+	}
+	f.Add(mustSerialize(&disconnect))
+	f.Fuzz(fuzzRoundTrip[NEVMDisconnectBlockWire])
+}
+
+func FuzzPartialMerkleTree(f *testing.F) {
+	hashes := []chainhash.Hash{{0x01}, {0x02}, {0x03}}
+	f.Add(mustSerialize(NewPartialMerkleTree(hashes, []bool{true, false, true})))
+	f.Fuzz(fuzzRoundTrip[PartialMerkleTree])
+}
+
+func FuzzFinalCommitment(f *testing.F) {
+	f.Add(mustSerialize(&FinalCommitment{
+		Version:      1,
+		LLMQType:     1,
+		Signers:      []bool{true, false, true},
+		ValidMembers: []bool{true, true, true},
+	}))
+	f.Fuzz(fuzzRoundTrip[FinalCommitment])
+}
+
+func FuzzReadUint(f *testing.F) {
+	for _, n := range []uint64{0, 0x7f, 0x80, 123456, 1<<63 + 5, ^uint64(0)} {
+		var buf bytes.Buffer
+		if err := PutUint(&buf, n); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := bytes.NewReader(data)
+		n, err := ReadUint(r)
+		if err != nil {
+			return
+		}
+		consumed := data[:len(data)-r.Len()]
+		var buf bytes.Buffer
+		if err := PutUint(&buf, n); err != nil {
+			t.Fatalf("PutUint failed: %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), consumed) {
+			t.Fatalf("round trip mismatch: got %x, want %x", buf.Bytes(), consumed)
+		}
+	})
+}
+
+func FuzzDecompressAmount(f *testing.F) {
+	for _, x := range []uint64{0, 1, 9, 10, 50, CompressAmount(2100000000000000), ^uint64(0)} {
+		f.Add(x)
+	}
+	f.Fuzz(func(t *testing.T, x uint64) {
+		n := DecompressAmount(x)
+		// Only amounts that fit in a uint64 without wrapping round trip.
+		if decompressFits(x) && CompressAmount(n) != x {
+			t.Fatalf("CompressAmount(DecompressAmount(%d)) = %d", x, CompressAmount(n))
+		}
+	})
+}
+
+// decompressFits reports whether DecompressAmount(x) can be represented
+// without overflow.
+func decompressFits(x uint64) bool {
+	if x == 0 {
+		return true
+	}
+	x--
+	e := x % 10
+	x /= 10
+	var n uint64
+	if e < 9 {
+		d := x%9 + 1
+		x /= 9
+		if x > (^uint64(0)-d)/10 {
+			return false
+		}
+		n = x*10 + d
+	} else {
+		if x == ^uint64(0) {
+			return false
+		}
+		n = x + 1
+	}
+	for ; e > 0; e-- {
+		if n > ^uint64(0)/10 {
+			return false
+		}
+		n *= 10
+	}
+	return true
+}
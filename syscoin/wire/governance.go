@@ -0,0 +1,555 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Commands of the governance P2P messages.
+const (
+	CmdGovObject     = "govobj"
+	CmdGovObjectVote = "govobjvote"
+	CmdGovSync       = "govsync"
+)
+
+// Governance object types.
+const (
+	GOVERNANCE_OBJECT_UNKNOWN  = 0
+	GOVERNANCE_OBJECT_PROPOSAL = 1
+	GOVERNANCE_OBJECT_TRIGGER  = 2
+)
+
+// Vote outcomes and signals.
+const (
+	VOTE_OUTCOME_NONE    = 0
+	VOTE_OUTCOME_YES     = 1
+	VOTE_OUTCOME_NO      = 2
+	VOTE_OUTCOME_ABSTAIN = 3
+
+	VOTE_SIGNAL_NONE     = 0
+	VOTE_SIGNAL_FUNDING  = 1
+	VOTE_SIGNAL_VALID    = 2
+	VOTE_SIGNAL_DELETE   = 3
+	VOTE_SIGNAL_ENDORSED = 4
+)
+
+const (
+	// MAX_GOVERNANCE_OBJECT_DATA_SIZE is the largest data field syscoind
+	// accepts in a governance object.
+	MAX_GOVERNANCE_OBJECT_DATA_SIZE = 16 * 1024
+
+	// maxGovernanceSigSize bounds the signature of a governance object or
+	// vote, either a BLS signature or a compact ECDSA signature.
+	maxGovernanceSigSize = BLS_SIGNATURE_SIZE
+)
+
+func init() {
+	mustRegister(RegisterMessage(func() wire.Message { return &GovernanceObject{} }))
+	mustRegister(RegisterMessage(func() wire.Message { return &GovernanceVote{} }))
+	mustRegister(RegisterMessage(func() wire.Message { return &MsgGovSync{} }))
+}
+
+// GovernanceObject is a proposal or superblock trigger, syscoind's
+// Governance::Object.  Data holds the object's JSON, the bytes the
+// gobject RPCs show hex encoded as DataHex.  Triggers are signed by the
+// operator key of the masternode at MasternodeOutpoint; proposals are not
+// signed and instead pay a collateral in CollateralHash.
+type GovernanceObject struct {
+	HashParent         chainhash.Hash
+	Revision           int32
+	Time               int64
+	CollateralHash     chainhash.Hash
+	Data               []byte
+	Type               int32
+	MasternodeOutpoint wire.OutPoint
+	Sig                []byte
+}
+
+func (g *GovernanceObject) Deserialize(r io.Reader) error {
+	if _, err := io.ReadFull(r, g.HashParent[:]); err != nil {
+		return err
+	}
+	revision, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	g.Revision = int32(revision)
+	t, err := binarySerializer.Uint64(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	g.Time = int64(t)
+	if _, err := io.ReadFull(r, g.CollateralHash[:]); err != nil {
+		return err
+	}
+	if g.Data, err = readVarBytes(r, MAX_GOVERNANCE_OBJECT_DATA_SIZE, "GovernanceObject.Data"); err != nil {
+		return err
+	}
+	objType, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	g.Type = int32(objType)
+	if err := readOutPoint(r, &g.MasternodeOutpoint); err != nil {
+		return err
+	}
+	g.Sig, err = readVarBytes(r, maxGovernanceSigSize, "GovernanceObject.Sig")
+	return err
+}
+
+func (g *GovernanceObject) Serialize(w io.Writer) error {
+	if err := g.serializeUnsigned(w); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, g.Sig)
+}
+
+func (g *GovernanceObject) serializeUnsigned(w io.Writer) error {
+	if _, err := w.Write(g.HashParent[:]); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(g.Revision)); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint64(w, littleEndian, uint64(g.Time)); err != nil {
+		return err
+	}
+	if _, err := w.Write(g.CollateralHash[:]); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(w, 0, g.Data); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(g.Type)); err != nil {
+		return err
+	}
+	return writeOutPoint(w, &g.MasternodeOutpoint)
+}
+
+func (g *GovernanceObject) SerializeSize() int {
+	return 2*chainhash.HashSize + 4 + 8 + varBytesSerializeSize(g.Data) + 4 +
+		chainhash.HashSize + 4 + varBytesSerializeSize(g.Sig)
+}
+
+// Hash returns the object hash syscoind identifies the object by in the
+// gobject RPCs, inventory announcements, votes and trigger proposal_hashes.
+// It does not match the serialization: Data is hashed as its hex string,
+// the outpoint is followed by the empty script and final sequence number of
+// the legacy layout, the signature is included, and CollateralHash and Type
+// are left out.
+func (g *GovernanceObject) Hash() chainhash.Hash {
+	var buf bytes.Buffer
+	buf.Write(g.HashParent[:])
+	_ = binarySerializer.PutUint32(&buf, littleEndian, uint32(g.Revision))
+	_ = binarySerializer.PutUint64(&buf, littleEndian, uint64(g.Time))
+	_ = wire.WriteVarString(&buf, 0, g.DataHex())
+	_ = writeOutPoint(&buf, &g.MasternodeOutpoint)
+	buf.Write([]byte{0x00, 0xff, 0xff, 0xff, 0xff})
+	_ = wire.WriteVarBytes(&buf, 0, g.Sig)
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// SignatureHash returns the hash a trigger's operator signature signs, the
+// double SHA-256 of every field but the signature.
+func (g *GovernanceObject) SignatureHash() chainhash.Hash {
+	var buf bytes.Buffer
+	buf.Grow(g.SerializeSize())
+	_ = g.serializeUnsigned(&buf)
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// DataHex returns Data hex encoded as shown by the gobject RPCs.
+func (g *GovernanceObject) DataHex() string {
+	return hex.EncodeToString(g.Data)
+}
+
+// CheckSignature passes the signature hash and signature to verify, which
+// checks them against the signing masternode's operator key.  The llmq
+// package provides a BLS verifier.
+func (g *GovernanceObject) CheckSignature(verify func(hash chainhash.Hash, sig []byte) error) error {
+	if g.Type != GOVERNANCE_OBJECT_TRIGGER {
+		return fmt.Errorf("governance object of type %d is not signed", g.Type)
+	}
+	return verify(g.SignatureHash(), g.Sig)
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (g *GovernanceObject) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	return g.Deserialize(r)
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (g *GovernanceObject) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	return g.Serialize(w)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (g *GovernanceObject) Command() string {
+	return CmdGovObject
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (g *GovernanceObject) MaxPayloadLength(pver uint32) uint32 {
+	return 2*chainhash.HashSize + 4 + 8 + MaxVarIntPayload + MAX_GOVERNANCE_OBJECT_DATA_SIZE +
+		4 + chainhash.HashSize + 4 + MaxVarIntPayload + maxGovernanceSigSize
+}
+
+// GovernanceVote is a masternode's vote on a governance object, syscoind's
+// CGovernanceVote.  It is signed with the masternode's voting key.
+type GovernanceVote struct {
+	MasternodeOutpoint wire.OutPoint
+	ParentHash         chainhash.Hash
+	VoteOutcome        int32
+	VoteSignal         int32
+	Time               int64
+	Sig                []byte
+}
+
+func (v *GovernanceVote) Deserialize(r io.Reader) error {
+	if err := readOutPoint(r, &v.MasternodeOutpoint); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, v.ParentHash[:]); err != nil {
+		return err
+	}
+	outcome, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	v.VoteOutcome = int32(outcome)
+	signal, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	v.VoteSignal = int32(signal)
+	t, err := binarySerializer.Uint64(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	v.Time = int64(t)
+	v.Sig, err = readVarBytes(r, maxGovernanceSigSize, "GovernanceVote.Sig")
+	return err
+}
+
+func (v *GovernanceVote) Serialize(w io.Writer) error {
+	if err := v.serializeUnsigned(w); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, v.Sig)
+}
+
+func (v *GovernanceVote) serializeUnsigned(w io.Writer) error {
+	if err := writeOutPoint(w, &v.MasternodeOutpoint); err != nil {
+		return err
+	}
+	if _, err := w.Write(v.ParentHash[:]); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(v.VoteOutcome)); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(v.VoteSignal)); err != nil {
+		return err
+	}
+	return binarySerializer.PutUint64(w, littleEndian, uint64(v.Time))
+}
+
+func (v *GovernanceVote) SerializeSize() int {
+	return chainhash.HashSize + 4 + chainhash.HashSize + 4 + 4 + 8 + varBytesSerializeSize(v.Sig)
+}
+
+// Hash returns the vote hash syscoind relays votes by.  It keeps the legacy
+// layout in which the outpoint was followed by an empty script and a final
+// sequence number, and orders the signal before the outcome.
+func (v *GovernanceVote) Hash() chainhash.Hash {
+	var buf bytes.Buffer
+	_ = writeOutPoint(&buf, &v.MasternodeOutpoint)
+	buf.Write([]byte{0x00, 0xff, 0xff, 0xff, 0xff})
+	buf.Write(v.ParentHash[:])
+	_ = binarySerializer.PutUint32(&buf, littleEndian, uint32(v.VoteSignal))
+	_ = binarySerializer.PutUint32(&buf, littleEndian, uint32(v.VoteOutcome))
+	_ = binarySerializer.PutUint64(&buf, littleEndian, uint64(v.Time))
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// SignatureHash returns the hash the voting key signs, the double SHA-256 of
+// every field but the signature.
+func (v *GovernanceVote) SignatureHash() chainhash.Hash {
+	var buf bytes.Buffer
+	buf.Grow(v.SerializeSize())
+	_ = v.serializeUnsigned(&buf)
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// CheckSignature verifies that the vote's compact ECDSA signature was made
+// by the voting key with the given key ID.
+func (v *GovernanceVote) CheckSignature(keyIDVoting [KEY_ID_SIZE]byte) error {
+	hash := v.SignatureHash()
+	pubKey, compressed, err := ecdsa.RecoverCompact(v.Sig, hash[:])
+	if err != nil {
+		return fmt.Errorf("vote %v: %v", v.Hash(), err)
+	}
+	var serialized []byte
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	} else {
+		serialized = pubKey.SerializeUncompressed()
+	}
+	if !bytes.Equal(btcutil.Hash160(serialized), keyIDVoting[:]) {
+		return fmt.Errorf("vote %v is not signed by voting key %x", v.Hash(), keyIDVoting)
+	}
+	return nil
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (v *GovernanceVote) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	return v.Deserialize(r)
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (v *GovernanceVote) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	return v.Serialize(w)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (v *GovernanceVote) Command() string {
+	return CmdGovObjectVote
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (v *GovernanceVote) MaxPayloadLength(pver uint32) uint32 {
+	return chainhash.HashSize + 4 + chainhash.HashSize + 4 + 4 + 8 + MaxVarIntPayload + maxGovernanceSigSize
+}
+
+// MsgGovSync implements the wire.Message interface and represents a govsync
+// message requesting one governance object, or all of them when Hash is zero,
+// with the votes matching Filter.
+type MsgGovSync struct {
+	Hash   chainhash.Hash
+	Filter wire.MsgFilterLoad
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgGovSync) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := io.ReadFull(r, msg.Hash[:]); err != nil {
+		return err
+	}
+	return msg.Filter.BtcDecode(r, wire.BIP0037Version, enc)
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgGovSync) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := w.Write(msg.Hash[:]); err != nil {
+		return err
+	}
+	return msg.Filter.BtcEncode(w, wire.BIP0037Version, enc)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgGovSync) Command() string {
+	return CmdGovSync
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgGovSync) MaxPayloadLength(pver uint32) uint32 {
+	return chainhash.HashSize + msg.Filter.MaxPayloadLength(wire.BIP0037Version)
+}
+
+// ProposalData is the JSON data of a proposal.  Amounts are in satoshis.
+type ProposalData struct {
+	Type           int32
+	Name           string
+	StartEpoch     int64
+	EndEpoch       int64
+	PaymentAddress string
+	PaymentAmount  int64
+	URL            string
+}
+
+// TriggerData is the JSON data of a superblock trigger.  The payment fields
+// are parallel lists; amounts are in satoshis.
+type TriggerData struct {
+	Type             int32
+	EventBlockHeight int32
+	PaymentAddresses []string
+	PaymentAmounts   []int64
+	ProposalHashes   []chainhash.Hash
+}
+
+// DecodeGovernanceDataHex decodes the DataHex field of the gobject RPCs into
+// the object's JSON data.
+func DecodeGovernanceDataHex(dataHex string) ([]byte, error) {
+	data, err := hex.DecodeString(dataHex)
+	if err != nil {
+		return nil, fmt.Errorf("governance data hex: %v", err)
+	}
+	return data, nil
+}
+
+// governanceJSON unmarshals data into v.  Besides a plain JSON object it
+// accepts the legacy [["<type>", {...}]] wrapping of older objects.
+func governanceJSON(data []byte, v interface{}) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var legacy [][]json.RawMessage
+		if err := json.Unmarshal(trimmed, &legacy); err != nil {
+			return fmt.Errorf("governance data: %v", err)
+		}
+		if len(legacy) != 1 || len(legacy[0]) != 2 {
+			return fmt.Errorf("governance data: malformed legacy object")
+		}
+		trimmed = legacy[0][1]
+	}
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("governance data: %v", err)
+	}
+	return nil
+}
+
+// Proposal decodes the object's data as a proposal.
+func (g *GovernanceObject) Proposal() (*ProposalData, error) {
+	if g.Type != GOVERNANCE_OBJECT_PROPOSAL {
+		return nil, fmt.Errorf("governance object of type %d is not a proposal", g.Type)
+	}
+	var raw struct {
+		Type           json.Number `json:"type"`
+		Name           string      `json:"name"`
+		StartEpoch     json.Number `json:"start_epoch"`
+		EndEpoch       json.Number `json:"end_epoch"`
+		PaymentAddress string      `json:"payment_address"`
+		PaymentAmount  json.Number `json:"payment_amount"`
+		URL            string      `json:"url"`
+	}
+	if err := governanceJSON(g.Data, &raw); err != nil {
+		return nil, err
+	}
+	p := &ProposalData{Name: raw.Name, PaymentAddress: raw.PaymentAddress, URL: raw.URL}
+	var err error
+	if p.Type, err = parseInt32(raw.Type, "type"); err != nil {
+		return nil, err
+	}
+	if p.StartEpoch, err = raw.StartEpoch.Int64(); err != nil {
+		return nil, fmt.Errorf("proposal start_epoch: %v", err)
+	}
+	if p.EndEpoch, err = raw.EndEpoch.Int64(); err != nil {
+		return nil, fmt.Errorf("proposal end_epoch: %v", err)
+	}
+	if p.PaymentAmount, err = ParseCoinAmount(raw.PaymentAmount.String()); err != nil {
+		return nil, fmt.Errorf("proposal payment_amount: %v", err)
+	}
+	return p, nil
+}
+
+// Trigger decodes the object's data as a superblock trigger.
+func (g *GovernanceObject) Trigger() (*TriggerData, error) {
+	if g.Type != GOVERNANCE_OBJECT_TRIGGER {
+		return nil, fmt.Errorf("governance object of type %d is not a trigger", g.Type)
+	}
+	var raw struct {
+		Type             json.Number `json:"type"`
+		EventBlockHeight json.Number `json:"event_block_height"`
+		PaymentAddresses string      `json:"payment_addresses"`
+		PaymentAmounts   string      `json:"payment_amounts"`
+		ProposalHashes   string      `json:"proposal_hashes"`
+	}
+	if err := governanceJSON(g.Data, &raw); err != nil {
+		return nil, err
+	}
+	t := &TriggerData{}
+	var err error
+	if t.Type, err = parseInt32(raw.Type, "type"); err != nil {
+		return nil, err
+	}
+	if t.EventBlockHeight, err = parseInt32(raw.EventBlockHeight, "event_block_height"); err != nil {
+		return nil, err
+	}
+	if raw.PaymentAddresses != "" {
+		t.PaymentAddresses = strings.Split(raw.PaymentAddresses, "|")
+	}
+	if raw.PaymentAmounts != "" {
+		for _, s := range strings.Split(raw.PaymentAmounts, "|") {
+			amount, err := ParseCoinAmount(s)
+			if err != nil {
+				return nil, fmt.Errorf("trigger payment_amounts: %v", err)
+			}
+			t.PaymentAmounts = append(t.PaymentAmounts, amount)
+		}
+	}
+	if raw.ProposalHashes != "" {
+		for _, s := range strings.Split(raw.ProposalHashes, "|") {
+			h, err := chainhash.NewHashFromStr(s)
+			if err != nil {
+				return nil, fmt.Errorf("trigger proposal_hashes: %v", err)
+			}
+			t.ProposalHashes = append(t.ProposalHashes, *h)
+		}
+	}
+	return t, nil
+}
+
+func parseInt32(n json.Number, field string) (int32, error) {
+	v, err := n.Int64()
+	if err != nil || int64(int32(v)) != v {
+		return 0, fmt.Errorf("governance data %s %q is not a 32-bit integer", field, n)
+	}
+	return int32(v), nil
+}
+
+// ParseCoinAmount converts a decimal coin amount such as "12.5" to satoshis
+// using the grammar of syscoind's ParseMoney: surrounding whitespace, then
+// at most ten digits, optionally followed by '.' and at most eight decimals.
+// Signs, exponents, fractions and hex are rejected.
+func ParseCoinAmount(s string) (int64, error) {
+	whole, frac, hasPoint := strings.Cut(strings.TrimSpace(s), ".")
+	if (whole != "" && !isDigits(whole)) || (frac != "" && !isDigits(frac)) ||
+		(whole == "" && frac == "") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(whole) > 10 {
+		return 0, fmt.Errorf("amount %q is out of range", s)
+	}
+	if hasPoint && len(frac) > 8 {
+		return 0, fmt.Errorf("amount %q has more than 8 decimals", s)
+	}
+	var w, f int64
+	if whole != "" {
+		w, _ = strconv.ParseInt(whole, 10, 64)
+	}
+	if frac != "" {
+		f, _ = strconv.ParseInt(frac, 10, 64)
+		f *= pow10[8-len(frac)]
+	}
+	n := w*COIN + f
+	if n > MAX_MONEY {
+		return 0, fmt.Errorf("amount %q is out of range", s)
+	}
+	return n, nil
+}
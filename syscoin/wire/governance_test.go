@@ -0,0 +1,218 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestGovernanceObjectSerialize(t *testing.T) {
+	obj := &GovernanceObject{
+		Revision:           1,
+		Time:               1700000000,
+		CollateralHash:     randomHash(),
+		Data:               []byte(`{"type":1,"name":"test"}`),
+		Type:               GOVERNANCE_OBJECT_PROPOSAL,
+		MasternodeOutpoint: wire.OutPoint{Hash: randomHash(), Index: 1},
+		Sig:                randomBytes(BLS_SIGNATURE_SIZE),
+	}
+	var buf bytes.Buffer
+	if err := obj.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != obj.SerializeSize() {
+		t.Errorf("SerializeSize() = %d, wrote %d", obj.SerializeSize(), buf.Len())
+	}
+	var decoded GovernanceObject
+	if err := decoded.Deserialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, obj) {
+		t.Errorf("got %+v, want %+v", decoded, obj)
+	}
+
+	// The signature hash covers every serialized field but the signature.
+	sigHash := obj.SignatureHash()
+	decoded.Sig = nil
+	if decoded.SignatureHash() != sigHash {
+		t.Error("signature hash depends on the signature")
+	}
+	decoded.Type++
+	if decoded.SignatureHash() == sigHash {
+		t.Error("signature hash does not depend on the type")
+	}
+
+	// The object hash follows syscoind's GetHash layout: data as hex, the
+	// legacy outpoint dummies and the signature, but neither the
+	// collateral hash nor the type.
+	var pre bytes.Buffer
+	pre.Write(obj.HashParent[:])
+	binary.Write(&pre, binary.LittleEndian, obj.Revision)
+	binary.Write(&pre, binary.LittleEndian, obj.Time)
+	dataHex := hex.EncodeToString(obj.Data)
+	pre.WriteByte(byte(len(dataHex)))
+	pre.WriteString(dataHex)
+	pre.Write(obj.MasternodeOutpoint.Hash[:])
+	binary.Write(&pre, binary.LittleEndian, obj.MasternodeOutpoint.Index)
+	pre.Write([]byte{0x00, 0xff, 0xff, 0xff, 0xff})
+	pre.WriteByte(byte(len(obj.Sig)))
+	pre.Write(obj.Sig)
+	hash := obj.Hash()
+	if want := chainhash.DoubleHashH(pre.Bytes()); hash != want {
+		t.Errorf("Hash() = %v, want %v", hash, want)
+	}
+	if hash == sigHash {
+		t.Error("object hash equals the signature hash")
+	}
+	other := *obj
+	other.CollateralHash[0] ^= 0xff
+	other.Type++
+	if other.Hash() != hash {
+		t.Error("object hash depends on the collateral hash or type")
+	}
+	other.Sig = append([]byte{0x01}, obj.Sig...)
+	if other.Hash() == hash {
+		t.Error("object hash does not depend on the signature")
+	}
+}
+
+func TestGovernanceVoteSignature(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyID [KEY_ID_SIZE]byte
+	copy(keyID[:], btcutil.Hash160(key.PubKey().SerializeCompressed()))
+
+	vote := &GovernanceVote{
+		MasternodeOutpoint: wire.OutPoint{Hash: randomHash(), Index: 0},
+		ParentHash:         randomHash(),
+		VoteOutcome:        VOTE_OUTCOME_YES,
+		VoteSignal:         VOTE_SIGNAL_FUNDING,
+		Time:               1700000000,
+	}
+	sigHash := vote.SignatureHash()
+	vote.Sig, err = ecdsa.SignCompact(key, sigHash[:], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vote.CheckSignature(keyID); err != nil {
+		t.Fatal(err)
+	}
+
+	var stream bytes.Buffer
+	if err := WriteSyscoinMessage(&stream, vote, PROTOCOL_VERSION, SyscoinMainNet); err != nil {
+		t.Fatal(err)
+	}
+	msg, _, err := ReadSyscoinMessage(&stream, PROTOCOL_VERSION, SyscoinMainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := msg.(*GovernanceVote)
+	if !reflect.DeepEqual(decoded, vote) || decoded.Hash() != vote.Hash() {
+		t.Errorf("got %+v, want %+v", decoded, vote)
+	}
+
+	decoded.VoteOutcome = VOTE_OUTCOME_NO
+	if err := decoded.CheckSignature(keyID); err == nil {
+		t.Error("expected a changed vote to fail verification")
+	}
+	// The relay hash orders the signal before the outcome.
+	a, b := *vote, *vote
+	a.VoteOutcome, a.VoteSignal = VOTE_OUTCOME_NO, VOTE_SIGNAL_DELETE
+	b.VoteOutcome, b.VoteSignal = VOTE_OUTCOME_ABSTAIN, VOTE_SIGNAL_VALID
+	if a.Hash() == b.Hash() {
+		t.Error("hash does not distinguish outcome and signal")
+	}
+}
+
+func TestGovernanceData(t *testing.T) {
+	proposalJSON := `{"type":1,"name":"dev-fund","start_epoch":1700000000,"end_epoch":1702592000,` +
+		`"payment_address":"sys1qexample","payment_amount":1250.5,"url":"https://example.org"}`
+	dataHex := "5b5b2270726f706f73616c222c" // [["proposal",
+	data, err := DecodeGovernanceDataHex(dataHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := &GovernanceObject{Type: GOVERNANCE_OBJECT_PROPOSAL}
+	for _, d := range [][]byte{[]byte(proposalJSON), append(append(data, proposalJSON...), "]]"...)} {
+		obj.Data = d
+		p, err := obj.Proposal()
+		if err != nil {
+			t.Fatalf("%s: %v", d, err)
+		}
+		want := &ProposalData{
+			Type: 1, Name: "dev-fund", StartEpoch: 1700000000, EndEpoch: 1702592000,
+			PaymentAddress: "sys1qexample", PaymentAmount: 125050000000, URL: "https://example.org",
+		}
+		if !reflect.DeepEqual(p, want) {
+			t.Errorf("got %+v, want %+v", p, want)
+		}
+	}
+	if _, err := obj.Trigger(); err == nil {
+		t.Error("expected a proposal not to decode as a trigger")
+	}
+
+	h1, h2 := randomHash(), randomHash()
+	trigger := &GovernanceObject{
+		Type: GOVERNANCE_OBJECT_TRIGGER,
+		Data: []byte(`{"type":2,"event_block_height":175200,"payment_addresses":"sys1qa|sys1qb",` +
+			`"payment_amounts":"10|0.00000001","proposal_hashes":"` + h1.String() + `|` + h2.String() + `"}`),
+	}
+	td, err := trigger.Trigger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &TriggerData{
+		Type:             2,
+		EventBlockHeight: 175200,
+		PaymentAddresses: []string{"sys1qa", "sys1qb"},
+		PaymentAmounts:   []int64{10 * COIN, 1},
+		ProposalHashes:   []chainhash.Hash{h1, h2},
+	}
+	if !reflect.DeepEqual(td, want) {
+		t.Errorf("got %+v, want %+v", td, want)
+	}
+}
+
+func TestParseCoinAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"1", COIN, true},
+		{"0.1", 10000000, true},
+		{"888000000", MAX_MONEY, true},
+		{" 2.5 ", 250000000, true},
+		{".5", 50000000, true},
+		{"5.", 5 * COIN, true},
+		{"1e2", 0, false},
+		{"1/2", 0, false},
+		{"0x10", 0, false},
+		{"+1", 0, false},
+		{"1 .5", 0, false},
+		{".", 0, false},
+		{"", 0, false},
+		{"00000000001", 0, false},
+		{"0.000000001", 0, false},
+		{"-1", 0, false},
+		{"888000000.00000001", 0, false},
+		{"abc", 0, false},
+	}
+	for _, test := range tests {
+		got, err := ParseCoinAmount(test.in)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("ParseCoinAmount(%q) = %d, %v", test.in, got, err)
+		}
+	}
+}
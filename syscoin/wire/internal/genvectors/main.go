@@ -0,0 +1,187 @@
+// Command genvectors regenerates the golden test vectors under
+// syscoin/wire/testdata/vectors from a dump file of payloads.
+//
+// The dump is a plain text file with one payload per line:
+//
+//	<source> <name> <hex>
+//
+// source says what hex holds:
+//
+//   - a registered wire.PayloadKind such as allocation, mint, burn-eth,
+//     asset, nevmblock or nevmdisconnect: the serialized payload itself;
+//   - tx: a whole raw transaction as syscoind's getrawtransaction prints it.
+//     The payload is taken from the transaction's data output and decoded by
+//     transaction version, as syscoind does;
+//   - zmq:<command>, for example zmq:nevmblock: the body of a message
+//     syscoind exchanged with its NEVM client under that command, decoded
+//     with wire.NewPayloadForNEVMCommand.
+//
+// Blank lines and lines starting with # are ignored.
+//
+// For every line the payload is decoded with this package, the decoded value
+// is recorded as JSON next to the payload hex, and one file per payload kind
+// is written to the output directory.  Payloads that fail to decode or do
+// not re-encode to the same bytes abort the run.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/syscoin/syscoinwire/syscoin/wire"
+)
+
+// vectorsVersion is bumped whenever the file layout changes incompatibly.
+const vectorsVersion = 1
+
+type vector struct {
+	Name    string          `json:"name"`
+	Hex     string          `json:"hex"`
+	Decoded json.RawMessage `json:"decoded"`
+}
+
+type vectorFile struct {
+	Version int      `json:"version"`
+	Kind    string   `json:"kind"`
+	Vectors []vector `json:"vectors"`
+}
+
+func main() {
+	dump := flag.String("dump", "", "payload dump file")
+	out := flag.String("out", ".", "output directory for the vector files")
+	flag.Parse()
+
+	if *dump == "" {
+		fmt.Fprintln(os.Stderr, "genvectors: -dump is required")
+		os.Exit(2)
+	}
+	if err := run(*dump, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "genvectors: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dumpPath, outDir string) error {
+	f, err := os.Open(dumpPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	files := make(map[string]*vectorFile)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*wire.MAX_NEVM_BLOCK_SIZE+1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return fmt.Errorf("%s:%d: expected <source> <name> <hex>", dumpPath, lineNum)
+		}
+		kind, v, err := decode(fields[0], fields[1], fields[2])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", dumpPath, lineNum, err)
+		}
+		vf, ok := files[kind]
+		if !ok {
+			vf = &vectorFile{Version: vectorsVersion, Kind: kind}
+			files[kind] = vf
+		}
+		vf.Vectors = append(vf.Vectors, *v)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(files))
+	for kind := range files {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+	for _, kind := range names {
+		b, err := json.MarshalIndent(files[kind], "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(outDir, kind+".json")
+		if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("wrote %d vectors to %s\n", len(files[kind].Vectors), path)
+	}
+	return nil
+}
+
+// decode decodes the payload a dump line describes and returns its kind and
+// vector.
+func decode(source, name, hexStr string) (string, *vector, error) {
+	raw, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %v", name, err)
+	}
+	var p wire.Payload
+	switch {
+	case source == "tx":
+		if p, raw, err = payloadFromTx(raw); err != nil {
+			return "", nil, fmt.Errorf("%s: %v", name, err)
+		}
+	case strings.HasPrefix(source, "zmq:"):
+		p, err = wire.NewPayloadForNEVMCommand(strings.TrimPrefix(source, "zmq:"))
+	default:
+		p, err = wire.NewPayload(wire.PayloadKind(source))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	r := bytes.NewReader(raw)
+	if err := p.Deserialize(r); err != nil {
+		return "", nil, fmt.Errorf("%s: decode: %v", name, err)
+	}
+	if r.Len() != 0 {
+		return "", nil, fmt.Errorf("%s: %d trailing bytes", name, r.Len())
+	}
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return "", nil, fmt.Errorf("%s: encode: %v", name, err)
+	}
+	if !bytes.Equal(buf.Bytes(), raw) {
+		return "", nil, fmt.Errorf("%s: re-encoding differs from the input bytes", name)
+	}
+	decoded, err := json.Marshal(p)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(p.Kind()), &vector{Name: name, Hex: hex.EncodeToString(raw), Decoded: decoded}, nil
+}
+
+// payloadFromTx returns an empty payload for the version of the raw
+// transaction tx and the payload bytes of its data output.
+func payloadFromTx(tx []byte) (wire.Payload, []byte, error) {
+	var msg btcwire.MsgTx
+	if err := msg.Deserialize(bytes.NewReader(tx)); err != nil {
+		return nil, nil, fmt.Errorf("transaction: %v", err)
+	}
+	p, err := wire.NewPayloadForTxVersion(msg.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, _, ok := wire.GetSyscoinData(&msg)
+	if !ok {
+		return nil, nil, fmt.Errorf("transaction %v has no payload output", msg.TxHash())
+	}
+	return p, data, nil
+}
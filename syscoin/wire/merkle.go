@@ -0,0 +1,227 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// maxPartialMerkleTxs is the most transactions a partial merkle tree may
+// claim, MAX_BLOCK_WEIGHT / MIN_TRANSACTION_WEIGHT in syscoind.
+const maxPartialMerkleTxs = 4000000 / (4 * 60)
+
+// PartialMerkleTree is syscoind's CPartialMerkleTree: the subset of a block's
+// merkle tree needed to prove that some transactions are part of it.  Flag
+// bits are packed least significant bit first.
+type PartialMerkleTree struct {
+	Transactions uint32
+	Hashes       []chainhash.Hash
+	Flags        []byte
+}
+
+// NewPartialMerkleTree builds the partial merkle tree proving the
+// transactions whose matches entry is true.
+func NewPartialMerkleTree(txHashes []chainhash.Hash, matches []bool) *PartialMerkleTree {
+	t := &PartialMerkleTree{Transactions: uint32(len(txHashes))}
+	var bits []bool
+	height := t.treeHeight()
+	t.traverseAndBuild(height, 0, txHashes, matches, &bits)
+	t.Flags = make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			t.Flags[i/8] |= 1 << (i % 8)
+		}
+	}
+	return t
+}
+
+func (t *PartialMerkleTree) treeWidth(height uint) uint32 {
+	return (t.Transactions + (1 << height) - 1) >> height
+}
+
+func (t *PartialMerkleTree) treeHeight() uint {
+	var height uint
+	for t.treeWidth(height) > 1 {
+		height++
+	}
+	return height
+}
+
+// calcHash returns the hash of the node at height and pos of the full tree.
+func (t *PartialMerkleTree) calcHash(height uint, pos uint32, txHashes []chainhash.Hash) chainhash.Hash {
+	if height == 0 {
+		return txHashes[pos]
+	}
+	left := t.calcHash(height-1, pos*2, txHashes)
+	right := left
+	if pos*2+1 < t.treeWidth(height-1) {
+		right = t.calcHash(height-1, pos*2+1, txHashes)
+	}
+	return hashMerkleBranches(&left, &right)
+}
+
+func (t *PartialMerkleTree) traverseAndBuild(height uint, pos uint32, txHashes []chainhash.Hash, matches []bool, bits *[]bool) {
+	parentOfMatch := false
+	for p := pos << height; p < (pos+1)<<height && p < t.Transactions; p++ {
+		parentOfMatch = parentOfMatch || matches[p]
+	}
+	*bits = append(*bits, parentOfMatch)
+	if height == 0 || !parentOfMatch {
+		t.Hashes = append(t.Hashes, t.calcHash(height, pos, txHashes))
+		return
+	}
+	t.traverseAndBuild(height-1, pos*2, txHashes, matches, bits)
+	if pos*2+1 < t.treeWidth(height-1) {
+		t.traverseAndBuild(height-1, pos*2+1, txHashes, matches, bits)
+	}
+}
+
+// partialMerkleTraversal tracks how many flag bits and hashes have been
+// consumed while extracting matches.
+type partialMerkleTraversal struct {
+	tree     *PartialMerkleTree
+	bitsUsed int
+	hashUsed int
+	matches  []chainhash.Hash
+	indexes  []uint32
+}
+
+func (t *partialMerkleTraversal) traverse(height uint, pos uint32) (chainhash.Hash, error) {
+	if t.bitsUsed >= len(t.tree.Flags)*8 {
+		return chainhash.Hash{}, fmt.Errorf("partial merkle tree overflowed its flag bits")
+	}
+	parentOfMatch := t.tree.Flags[t.bitsUsed/8]&(1<<(t.bitsUsed%8)) != 0
+	t.bitsUsed++
+	if height == 0 || !parentOfMatch {
+		if t.hashUsed >= len(t.tree.Hashes) {
+			return chainhash.Hash{}, fmt.Errorf("partial merkle tree overflowed its hashes")
+		}
+		hash := t.tree.Hashes[t.hashUsed]
+		t.hashUsed++
+		if height == 0 && parentOfMatch {
+			t.matches = append(t.matches, hash)
+			t.indexes = append(t.indexes, pos)
+		}
+		return hash, nil
+	}
+	left, err := t.traverse(height-1, pos*2)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	right := left
+	if pos*2+1 < t.tree.treeWidth(height-1) {
+		if right, err = t.traverse(height-1, pos*2+1); err != nil {
+			return chainhash.Hash{}, err
+		}
+		// A duplicated right branch would allow CVE-2012-2459 style
+		// forgeries.
+		if right == left {
+			return chainhash.Hash{}, fmt.Errorf("partial merkle tree has identical sibling hashes")
+		}
+	}
+	return hashMerkleBranches(&left, &right), nil
+}
+
+// ExtractMatches returns the merkle root the tree commits to together with
+// the matched transaction hashes and their positions in the block.  Like
+// syscoind it rejects trees that do not consume every hash and flag byte.
+func (t *PartialMerkleTree) ExtractMatches() (root chainhash.Hash, matches []chainhash.Hash, indexes []uint32, err error) {
+	switch {
+	case t.Transactions == 0:
+		return root, nil, nil, fmt.Errorf("partial merkle tree has no transactions")
+	case t.Transactions > maxPartialMerkleTxs:
+		return root, nil, nil, fmt.Errorf("partial merkle tree claims %d transactions", t.Transactions)
+	case uint32(len(t.Hashes)) > t.Transactions:
+		return root, nil, nil, fmt.Errorf("partial merkle tree has more hashes than transactions")
+	case len(t.Flags)*8 < len(t.Hashes):
+		return root, nil, nil, fmt.Errorf("partial merkle tree has fewer flag bits than hashes")
+	}
+	trav := partialMerkleTraversal{tree: t}
+	root, err = trav.traverse(t.treeHeight(), 0)
+	if err != nil {
+		return chainhash.Hash{}, nil, nil, err
+	}
+	if (trav.bitsUsed+7)/8 != len(t.Flags) {
+		return chainhash.Hash{}, nil, nil, fmt.Errorf("partial merkle tree has unused flag bytes")
+	}
+	if trav.hashUsed != len(t.Hashes) {
+		return chainhash.Hash{}, nil, nil, fmt.Errorf("partial merkle tree has unused hashes")
+	}
+	return root, trav.matches, trav.indexes, nil
+}
+
+func (t *PartialMerkleTree) Deserialize(r io.Reader) error {
+	var err error
+	if t.Transactions, err = binarySerializer.Uint32(r, littleEndian); err != nil {
+		return err
+	}
+	count, err := readElementCount(r, "PartialMerkleTree.Hashes")
+	if err != nil {
+		return err
+	}
+	t.Hashes = make([]chainhash.Hash, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		var h chainhash.Hash
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return err
+		}
+		t.Hashes = append(t.Hashes, h)
+	}
+	t.Flags, err = readVarBytes(r, MAX_SIZE, "PartialMerkleTree.Flags")
+	return err
+}
+
+func (t *PartialMerkleTree) Serialize(w io.Writer) error {
+	if err := binarySerializer.PutUint32(w, littleEndian, t.Transactions); err != nil {
+		return err
+	}
+	if err := wire.WriteVarInt(w, 0, uint64(len(t.Hashes))); err != nil {
+		return err
+	}
+	for i := range t.Hashes {
+		if _, err := w.Write(t.Hashes[i][:]); err != nil {
+			return err
+		}
+	}
+	return wire.WriteVarBytes(w, 0, t.Flags)
+}
+
+func (t *PartialMerkleTree) SerializeSize() int {
+	return 4 + wire.VarIntSerializeSize(uint64(len(t.Hashes))) +
+		len(t.Hashes)*chainhash.HashSize + varBytesSerializeSize(t.Flags)
+}
+
+// hashMerkleBranches returns the double SHA-256 of left and right
+// concatenated.
+func hashMerkleBranches(left, right *chainhash.Hash) chainhash.Hash {
+	var buf [2 * chainhash.HashSize]byte
+	copy(buf[:chainhash.HashSize], left[:])
+	copy(buf[chainhash.HashSize:], right[:])
+	return chainhash.DoubleHashH(buf[:])
+}
+
+// CalcMerkleRoot computes a merkle root the way syscoind's ComputeMerkleRoot
+// does, duplicating the last hash of odd levels.  The root of no leaves is
+// the zero hash.
+func CalcMerkleRoot(leaves []chainhash.Hash) chainhash.Hash {
+	if len(leaves) == 0 {
+		return chainhash.Hash{}
+	}
+	level := append([]chainhash.Hash(nil), leaves...)
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		for i := 0; i < len(level)/2; i++ {
+			level[i] = hashMerkleBranches(&level[2*i], &level[2*i+1])
+		}
+		level = level[:len(level)/2]
+	}
+	return level[0]
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Syscoin network magics, the pchMessageStart bytes of syscoind's chain
+// parameters read as a little-endian uint32 the way btcd represents them.
+const (
+	SyscoinMainNet wire.BitcoinNet = 0xffcae2ce
+	SyscoinTestNet wire.BitcoinNet = 0xfecae2ce
+	SyscoinRegTest wire.BitcoinNet = 0xdab5bffa
+)
+
+const (
+	// PROTOCOL_VERSION is the P2P protocol version syscoind advertises.
+	PROTOCOL_VERSION = 70016
+
+	// MIN_PEER_PROTO_VERSION is the oldest protocol version syscoind
+	// connects to.
+	MIN_PEER_PROTO_VERSION = 70015
+)
+
+// Commands of the masternode authentication and LLMQ recovered signature
+// messages.
+const (
+	CmdMNAuth       = "mnauth"
+	CmdQSendRecSigs = "qsendrecsigs"
+	CmdQSigRec      = "qsigrec"
+)
+
+// MessageConstructor returns a new, empty message ready to be decoded into.
+type MessageConstructor func() wire.Message
+
+// messageRegistry maps the Syscoin-specific P2P commands to their message
+// types.  Commands not registered here are decoded by btcd.
+//
+// The NEVM commands (NEVMCommandBlock and the like) are not P2P messages:
+// syscoind exchanges them with its own NEVM client over ZMQ and never
+// relays them to peers.  They are decoded as payloads with
+// NewPayloadForNEVMCommand instead.
+var messageRegistry = struct {
+	sync.RWMutex
+	byCommand map[string]MessageConstructor
+}{byCommand: make(map[string]MessageConstructor)}
+
+func init() {
+	for _, ctor := range []MessageConstructor{
+		func() wire.Message { return &MsgSyscoinVersion{} },
+		func() wire.Message { return &MsgGetMNListDiff{} },
+		func() wire.Message { return &MsgMNListDiff{} },
+		func() wire.Message { return &MsgCLSig{} },
+		func() wire.Message { return &MsgISDLock{} },
+		func() wire.Message { return &MsgMNAuth{} },
+		func() wire.Message { return &MsgQSendRecSigs{} },
+		func() wire.Message { return &MsgQSigRec{} },
+		func() wire.Message { return &MsgSyscoinBlock{} },
+		func() wire.Message { return &MsgSyscoinHeaders{} },
+	} {
+		mustRegister(RegisterMessage(ctor))
+	}
+}
+
+// RegisterMessage makes ReadSyscoinMessage decode messages with the command
+// of the constructed message using ctor.  It returns an error if the command
+// is already registered.
+func RegisterMessage(ctor MessageConstructor) error {
+	command := ctor().Command()
+	if len(command) > wire.CommandSize {
+		return fmt.Errorf("command %q is longer than %d bytes", command, wire.CommandSize)
+	}
+	messageRegistry.Lock()
+	defer messageRegistry.Unlock()
+	if _, ok := messageRegistry.byCommand[command]; ok {
+		return fmt.Errorf("message command %q is already registered", command)
+	}
+	messageRegistry.byCommand[command] = ctor
+	return nil
+}
+
+func makeSyscoinMessage(command string) (wire.Message, bool) {
+	messageRegistry.RLock()
+	ctor, ok := messageRegistry.byCommand[command]
+	messageRegistry.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return ctor(), true
+}
+
+// ReadSyscoinMessage reads, validates and parses the next message from r for
+// the given protocol version and Syscoin network.  Syscoin-specific commands
+// are decoded with the registered message types and every other command by
+// btcd's wire package, which returns wire.ErrUnknownMessage for commands
+// neither knows.  The payload of rejected messages is consumed, so reading
+// can continue with the next message.  It returns the parsed message and
+// its raw payload.
+//
+// Blocks and headers decode to MsgSyscoinBlock and MsgSyscoinHeaders, which
+// understand merge-mined headers.
+func ReadSyscoinMessage(r io.Reader, pver uint32, net wire.BitcoinNet) (wire.Message, []byte, error) {
+	var hdr [wire.MessageHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, nil, err
+	}
+	magic := wire.BitcoinNet(littleEndian.Uint32(hdr[0:4]))
+	command := string(bytes.TrimRight(hdr[4:4+wire.CommandSize], "\x00"))
+	length := littleEndian.Uint32(hdr[16:20])
+
+	if length > wire.MaxMessagePayload {
+		str := fmt.Sprintf("message payload is too large - header "+
+			"indicates %d bytes, but max message payload is %d "+
+			"bytes.", length, wire.MaxMessagePayload)
+		return nil, nil, messageError("ReadSyscoinMessage", str)
+	}
+	payload, err := readBytes(r, int(length))
+	if err != nil {
+		return nil, nil, err
+	}
+	if magic != net {
+		str := fmt.Sprintf("message from other network [%v]", magic)
+		return nil, nil, messageError("ReadSyscoinMessage", str)
+	}
+	if !utf8.ValidString(command) {
+		str := fmt.Sprintf("invalid command %v", []byte(command))
+		return nil, nil, messageError("ReadSyscoinMessage", str)
+	}
+
+	msg, ok := makeSyscoinMessage(command)
+	if !ok {
+		raw := make([]byte, 0, len(hdr)+len(payload))
+		raw = append(append(raw, hdr[:]...), payload...)
+		_, msg, payload, err := wire.ReadMessageWithEncodingN(bytes.NewReader(raw),
+			pver, net, wire.WitnessEncoding)
+		return msg, payload, err
+	}
+
+	if mpl := msg.MaxPayloadLength(pver); length > mpl {
+		str := fmt.Sprintf("payload exceeds max length - header "+
+			"indicates %v bytes, but max payload size for "+
+			"messages of type [%v] is %v.", length, command, mpl)
+		return nil, nil, messageError("ReadSyscoinMessage", str)
+	}
+	if checksum := chainhash.DoubleHashB(payload)[:4]; !bytes.Equal(checksum, hdr[20:24]) {
+		str := fmt.Sprintf("payload checksum failed - header "+
+			"indicates %x, but actual checksum is %x.", hdr[20:24], checksum)
+		return nil, nil, messageError("ReadSyscoinMessage", str)
+	}
+	if err := msg.BtcDecode(bytes.NewBuffer(payload), pver, wire.WitnessEncoding); err != nil {
+		return nil, nil, err
+	}
+	return msg, payload, nil
+}
+
+// WriteSyscoinMessage writes msg to w with the header of the given Syscoin
+// network.  Transactions are written with their witness data.
+func WriteSyscoinMessage(w io.Writer, msg wire.Message, pver uint32, net wire.BitcoinNet) error {
+	_, err := wire.WriteMessageWithEncodingN(w, msg, pver, net, wire.WitnessEncoding)
+	return err
+}
+
+// NegotiateProtocolVersion returns the protocol version to use with a peer
+// that advertised remote, the lower of the two versions.  Peers older than
+// MIN_PEER_PROTO_VERSION are rejected like syscoind does.
+func NegotiateProtocolVersion(local, remote uint32) (uint32, error) {
+	if remote < MIN_PEER_PROTO_VERSION {
+		return 0, fmt.Errorf("peer protocol version %d is older than the minimum %d",
+			remote, MIN_PEER_PROTO_VERSION)
+	}
+	if remote < local {
+		return remote, nil
+	}
+	return local, nil
+}
+
+// MsgSyscoinVersion implements the wire.Message interface and represents
+// syscoind's version message: btcd's version message followed by the
+// masternode authentication challenge and whether the connection is a
+// masternode connection.  The trailing fields are only read if present.
+type MsgSyscoinVersion struct {
+	wire.MsgVersion
+	MNAuthChallenge chainhash.Hash
+	Masternode      bool
+}
+
+// NewMsgSyscoinVersion returns a version message advertising
+// PROTOCOL_VERSION.
+func NewMsgSyscoinVersion(me, you *wire.NetAddress, nonce uint64, lastBlock int32) *MsgSyscoinVersion {
+	msg := &MsgSyscoinVersion{MsgVersion: *wire.NewMsgVersion(me, you, nonce, lastBlock)}
+	msg.ProtocolVersion = PROTOCOL_VERSION
+	return msg
+}
+
+// BtcDecode decodes r into the receiver.  r must be a *bytes.Buffer.  This
+// is part of the wire.Message interface implementation.
+func (msg *MsgSyscoinVersion) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	if err := msg.MsgVersion.BtcDecode(r, pver, enc); err != nil {
+		return err
+	}
+	buf := r.(*bytes.Buffer)
+	if buf.Len() >= chainhash.HashSize {
+		copy(msg.MNAuthChallenge[:], buf.Next(chainhash.HashSize))
+		if buf.Len() > 0 {
+			b, _ := buf.ReadByte()
+			msg.Masternode = b != 0
+		}
+	}
+	return nil
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgSyscoinVersion) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if err := msg.MsgVersion.BtcEncode(w, pver, enc); err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.MNAuthChallenge[:]); err != nil {
+		return err
+	}
+	var masternode uint8
+	if msg.Masternode {
+		masternode = 1
+	}
+	return binarySerializer.PutUint8(w, masternode)
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgSyscoinVersion) MaxPayloadLength(pver uint32) uint32 {
+	return msg.MsgVersion.MaxPayloadLength(pver) + chainhash.HashSize + 1
+}
+
+// MsgMNAuth implements the wire.Message interface and represents an mnauth
+// message, with which a masternode proves its identity to a peer by signing
+// the peer's MNAuthChallenge with its operator key.
+type MsgMNAuth struct {
+	ProRegTxHash chainhash.Hash
+	Sig          [BLS_SIGNATURE_SIZE]byte
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgMNAuth) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := io.ReadFull(r, msg.ProRegTxHash[:]); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(r, msg.Sig[:])
+	return err
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgMNAuth) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := w.Write(msg.ProRegTxHash[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg.Sig[:])
+	return err
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgMNAuth) Command() string {
+	return CmdMNAuth
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgMNAuth) MaxPayloadLength(pver uint32) uint32 {
+	return chainhash.HashSize + BLS_SIGNATURE_SIZE
+}
+
+// MsgQSendRecSigs implements the wire.Message interface and represents a
+// qsendrecsigs message, with which a peer asks to receive recovered LLMQ
+// signatures.
+type MsgQSendRecSigs struct {
+	SendRecoveredSigs bool
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgQSendRecSigs) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	b, err := binarySerializer.Uint8(r)
+	msg.SendRecoveredSigs = b != 0
+	return err
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgQSendRecSigs) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	var b uint8
+	if msg.SendRecoveredSigs {
+		b = 1
+	}
+	return binarySerializer.PutUint8(w, b)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgQSendRecSigs) Command() string {
+	return CmdQSendRecSigs
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgQSendRecSigs) MaxPayloadLength(pver uint32) uint32 {
+	return 1
+}
+
+// MsgQSigRec implements the wire.Message interface and represents a qsigrec
+// message, a recovered LLMQ signature of MsgHash for signing request ID.
+type MsgQSigRec struct {
+	LLMQType   uint8
+	QuorumHash chainhash.Hash
+	ID         chainhash.Hash
+	MsgHash    chainhash.Hash
+	Sig        [BLS_SIGNATURE_SIZE]byte
+}
+
+// SignHash returns the hash the quorum signed.
+func (msg *MsgQSigRec) SignHash() chainhash.Hash {
+	return BuildSignHash(msg.LLMQType, msg.QuorumHash, msg.ID, msg.MsgHash)
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgQSigRec) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	var err error
+	if msg.LLMQType, err = binarySerializer.Uint8(r); err != nil {
+		return err
+	}
+	for _, b := range [][]byte{msg.QuorumHash[:], msg.ID[:], msg.MsgHash[:], msg.Sig[:]} {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgQSigRec) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if err := binarySerializer.PutUint8(w, msg.LLMQType); err != nil {
+		return err
+	}
+	for _, b := range [][]byte{msg.QuorumHash[:], msg.ID[:], msg.MsgHash[:], msg.Sig[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgQSigRec) Command() string {
+	return CmdQSigRec
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgQSigRec) MaxPayloadLength(pver uint32) uint32 {
+	return 1 + 3*chainhash.HashSize + BLS_SIGNATURE_SIZE
+}
@@ -0,0 +1,133 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestSyscoinMessageStream(t *testing.T) {
+	me := wire.NewNetAddressIPPort(net.ParseIP("127.0.0.1"), 18369, wire.SFNodeNetwork)
+	you := wire.NewNetAddressIPPort(net.ParseIP("127.0.0.2"), 18369, wire.SFNodeNetwork)
+	version := NewMsgSyscoinVersion(me, you, 42, 1000)
+	version.MNAuthChallenge = randomHash()
+	version.Masternode = true
+	// Addresses in a version message carry no timestamp.
+	version.AddrYou.Timestamp = time.Time{}
+	version.AddrMe.Timestamp = time.Time{}
+
+	clsig := &MsgCLSig{Height: 1000, BlockHash: randomHash()}
+	mnauth := &MsgMNAuth{ProRegTxHash: randomHash()}
+	inv := wire.NewMsgInv()
+	if err := inv.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &chainhash.Hash{0x01})); err != nil {
+		t.Fatal(err)
+	}
+	msgs := []wire.Message{
+		version,
+		wire.NewMsgVerAck(),
+		&MsgQSendRecSigs{SendRecoveredSigs: true},
+		mnauth,
+		clsig,
+		&MsgGetMNListDiff{BlockHash: randomHash()},
+		wire.NewMsgPing(7),
+		inv,
+		&MsgQSigRec{LLMQType: 1, QuorumHash: randomHash(), ID: randomHash(), MsgHash: randomHash()},
+	}
+
+	var stream bytes.Buffer
+	for _, msg := range msgs {
+		if err := WriteSyscoinMessage(&stream, msg, PROTOCOL_VERSION, SyscoinRegTest); err != nil {
+			t.Fatalf("%s: %v", msg.Command(), err)
+		}
+	}
+	for _, want := range msgs {
+		got, _, err := ReadSyscoinMessage(&stream, PROTOCOL_VERSION, SyscoinRegTest)
+		if err != nil {
+			t.Fatalf("%s: %v", want.Command(), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", want.Command(), got, want)
+		}
+	}
+	if _, _, err := ReadSyscoinMessage(&stream, PROTOCOL_VERSION, SyscoinRegTest); err != io.EOF {
+		t.Errorf("got %v at end of stream, want io.EOF", err)
+	}
+}
+
+func TestReadSyscoinMessageRecovers(t *testing.T) {
+	var stream bytes.Buffer
+	ping := wire.NewMsgPing(1)
+
+	// A message from another network, an unknown command and a message
+	// with a corrupt checksum are each rejected without losing the
+	// message that follows.
+	if err := WriteSyscoinMessage(&stream, ping, PROTOCOL_VERSION, SyscoinMainNet); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSyscoinMessage(&stream, ping, PROTOCOL_VERSION, SyscoinRegTest); err != nil {
+		t.Fatal(err)
+	}
+	unknown := []byte{0xfa, 0xbf, 0xb5, 0xda, 'n', 'o', 'p', 'e', 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0}
+	unknown = append(unknown, chainhash.DoubleHashB([]byte{0x55})[:4]...)
+	stream.Write(append(unknown, 0x55))
+	var corrupt bytes.Buffer
+	if err := WriteSyscoinMessage(&corrupt, &MsgCLSig{}, PROTOCOL_VERSION, SyscoinRegTest); err != nil {
+		t.Fatal(err)
+	}
+	raw := corrupt.Bytes()
+	raw[len(raw)-1] ^= 0xff
+	stream.Write(raw)
+	if err := WriteSyscoinMessage(&stream, ping, PROTOCOL_VERSION, SyscoinRegTest); err != nil {
+		t.Fatal(err)
+	}
+
+	expectErr := []bool{true, false, true, true, false}
+	for i, wantErr := range expectErr {
+		msg, _, err := ReadSyscoinMessage(&stream, PROTOCOL_VERSION, SyscoinRegTest)
+		if (err != nil) != wantErr {
+			t.Fatalf("message %d: got %v, %v", i, msg, err)
+		}
+		if i == 2 && !errors.Is(err, wire.ErrUnknownMessage) {
+			t.Errorf("got %v, want ErrUnknownMessage", err)
+		}
+	}
+}
+
+func TestMsgSyscoinVersionWithoutMasternodeFields(t *testing.T) {
+	me := wire.NewNetAddressIPPort(net.ParseIP("127.0.0.1"), 18369, 0)
+	plain := wire.NewMsgVersion(me, me, 1, 5)
+	var buf bytes.Buffer
+	if err := WriteSyscoinMessage(&buf, plain, PROTOCOL_VERSION, SyscoinRegTest); err != nil {
+		t.Fatal(err)
+	}
+	msg, _, err := ReadSyscoinMessage(&buf, PROTOCOL_VERSION, SyscoinRegTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := msg.(*MsgSyscoinVersion)
+	if v.Masternode || v.MNAuthChallenge != (chainhash.Hash{}) || v.LastBlock != 5 {
+		t.Errorf("unexpected version %+v", v)
+	}
+}
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	if v, err := NegotiateProtocolVersion(PROTOCOL_VERSION, PROTOCOL_VERSION+1); err != nil || v != PROTOCOL_VERSION {
+		t.Errorf("got %d, %v", v, err)
+	}
+	if v, err := NegotiateProtocolVersion(PROTOCOL_VERSION, MIN_PEER_PROTO_VERSION); err != nil || v != MIN_PEER_PROTO_VERSION {
+		t.Errorf("got %d, %v", v, err)
+	}
+	if _, err := NegotiateProtocolVersion(PROTOCOL_VERSION, MIN_PEER_PROTO_VERSION-1); err == nil {
+		t.Error("expected an old peer to be rejected")
+	}
+	if err := RegisterMessage(func() wire.Message { return &MsgCLSig{} }); err == nil {
+		t.Error("expected duplicate command registration to fail")
+	}
+}
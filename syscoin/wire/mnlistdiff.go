@@ -0,0 +1,407 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Commands of the simplified masternode list P2P messages.
+const (
+	CmdGetMNListDiff = "getmnlistd"
+	CmdMNListDiff    = "mnlistdiff"
+)
+
+// SimplifiedMNListEntry is the light client view of a masternode, syscoind's
+// CSimplifiedMNListEntry.
+type SimplifiedMNListEntry struct {
+	ProRegTxHash   chainhash.Hash
+	ConfirmedHash  chainhash.Hash
+	Service        NetService
+	PubKeyOperator [BLS_PUBKEY_SIZE]byte
+	KeyIDVoting    [KEY_ID_SIZE]byte
+	IsValid        bool
+}
+
+func (e *SimplifiedMNListEntry) Deserialize(r io.Reader) error {
+	if _, err := io.ReadFull(r, e.ProRegTxHash[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, e.ConfirmedHash[:]); err != nil {
+		return err
+	}
+	if err := e.Service.Deserialize(r); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, e.PubKeyOperator[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, e.KeyIDVoting[:]); err != nil {
+		return err
+	}
+	valid, err := binarySerializer.Uint8(r)
+	if err != nil {
+		return err
+	}
+	e.IsValid = valid != 0
+	return nil
+}
+
+func (e *SimplifiedMNListEntry) Serialize(w io.Writer) error {
+	if _, err := w.Write(e.ProRegTxHash[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(e.ConfirmedHash[:]); err != nil {
+		return err
+	}
+	if err := e.Service.Serialize(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(e.PubKeyOperator[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(e.KeyIDVoting[:]); err != nil {
+		return err
+	}
+	var valid uint8
+	if e.IsValid {
+		valid = 1
+	}
+	return binarySerializer.PutUint8(w, valid)
+}
+
+func (e *SimplifiedMNListEntry) SerializeSize() int {
+	return 2*chainhash.HashSize + e.Service.SerializeSize() +
+		BLS_PUBKEY_SIZE + KEY_ID_SIZE + 1
+}
+
+// Hash returns the double SHA-256 of the serialized entry, the leaf hash of
+// the masternode list merkle tree.
+func (e *SimplifiedMNListEntry) Hash() chainhash.Hash {
+	var buf bytes.Buffer
+	buf.Grow(e.SerializeSize())
+	_ = e.Serialize(&buf)
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// SimplifiedMNList is the simplified masternode list as of BlockHash, kept
+// sorted by ProRegTxHash.
+type SimplifiedMNList struct {
+	BlockHash chainhash.Hash
+	Entries   []SimplifiedMNListEntry
+}
+
+// NewSimplifiedMNList returns the list of entries at blockHash.  The zero
+// block hash with no entries is the base used to request a full list.
+func NewSimplifiedMNList(blockHash chainhash.Hash, entries []SimplifiedMNListEntry) *SimplifiedMNList {
+	l := &SimplifiedMNList{
+		BlockHash: blockHash,
+		Entries:   append([]SimplifiedMNListEntry(nil), entries...),
+	}
+	sortSMLEntries(l.Entries)
+	return l
+}
+
+func sortSMLEntries(entries []SimplifiedMNListEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].ProRegTxHash[:], entries[j].ProRegTxHash[:]) < 0
+	})
+}
+
+// CalcMerkleRoot returns the merkle root of the list that syscoind commits
+// to in the coinbase CbTx payload.
+func (l *SimplifiedMNList) CalcMerkleRoot() chainhash.Hash {
+	leaves := make([]chainhash.Hash, len(l.Entries))
+	for i := range l.Entries {
+		leaves[i] = l.Entries[i].Hash()
+	}
+	return CalcMerkleRoot(leaves)
+}
+
+// ApplyDiff returns the list that results from applying diff to l.  l is
+// left unchanged.
+func (l *SimplifiedMNList) ApplyDiff(diff *MsgMNListDiff) (*SimplifiedMNList, error) {
+	if diff.BaseBlockHash != l.BlockHash {
+		return nil, fmt.Errorf("mnlistdiff is based on block %v, not %v",
+			diff.BaseBlockHash, l.BlockHash)
+	}
+	byHash := make(map[chainhash.Hash]SimplifiedMNListEntry, len(l.Entries))
+	for _, e := range l.Entries {
+		byHash[e.ProRegTxHash] = e
+	}
+	for _, h := range diff.DeletedMNs {
+		if _, ok := byHash[h]; !ok {
+			return nil, fmt.Errorf("mnlistdiff deletes unknown masternode %v", h)
+		}
+		delete(byHash, h)
+	}
+	for _, e := range diff.MNList {
+		byHash[e.ProRegTxHash] = e
+	}
+	next := &SimplifiedMNList{
+		BlockHash: diff.BlockHash,
+		Entries:   make([]SimplifiedMNListEntry, 0, len(byHash)),
+	}
+	for _, e := range byHash {
+		next.Entries = append(next.Entries, e)
+	}
+	sortSMLEntries(next.Entries)
+	return next, nil
+}
+
+// MsgGetMNListDiff implements the wire.Message interface and represents a
+// getmnlistd message requesting the masternode list changes between two
+// blocks.
+type MsgGetMNListDiff struct {
+	BaseBlockHash chainhash.Hash
+	BlockHash     chainhash.Hash
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgGetMNListDiff) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := io.ReadFull(r, msg.BaseBlockHash[:]); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(r, msg.BlockHash[:])
+	return err
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgGetMNListDiff) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := w.Write(msg.BaseBlockHash[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg.BlockHash[:])
+	return err
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgGetMNListDiff) Command() string {
+	return CmdGetMNListDiff
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgGetMNListDiff) MaxPayloadLength(pver uint32) uint32 {
+	return 2 * chainhash.HashSize
+}
+
+// DeletedQuorum identifies a quorum removed from the active set.
+type DeletedQuorum struct {
+	LLMQType   uint8
+	QuorumHash chainhash.Hash
+}
+
+// MsgMNListDiff implements the wire.Message interface and represents an
+// mnlistdiff message, syscoind's CSimplifiedMNListDiff.  CbTx is the coinbase
+// of BlockHash and CbTxMerkleTree proves it against the block's merkle root.
+type MsgMNListDiff struct {
+	BaseBlockHash  chainhash.Hash
+	BlockHash      chainhash.Hash
+	CbTxMerkleTree PartialMerkleTree
+	CbTx           wire.MsgTx
+	DeletedMNs     []chainhash.Hash
+	MNList         []SimplifiedMNListEntry
+	DeletedQuorums []DeletedQuorum
+	NewQuorums     []FinalCommitment
+}
+
+// BtcDecode decodes r into the receiver.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgMNListDiff) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := io.ReadFull(r, msg.BaseBlockHash[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, msg.BlockHash[:]); err != nil {
+		return err
+	}
+	if err := msg.CbTxMerkleTree.Deserialize(r); err != nil {
+		return err
+	}
+	if err := msg.CbTx.BtcDecode(r, pver, enc); err != nil {
+		return err
+	}
+
+	count, err := readElementCount(r, "MsgMNListDiff.DeletedMNs")
+	if err != nil {
+		return err
+	}
+	msg.DeletedMNs = make([]chainhash.Hash, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		var h chainhash.Hash
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return err
+		}
+		msg.DeletedMNs = append(msg.DeletedMNs, h)
+	}
+
+	if count, err = readElementCount(r, "MsgMNListDiff.MNList"); err != nil {
+		return err
+	}
+	msg.MNList = make([]SimplifiedMNListEntry, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		var e SimplifiedMNListEntry
+		if err := e.Deserialize(r); err != nil {
+			return err
+		}
+		msg.MNList = append(msg.MNList, e)
+	}
+
+	if count, err = readElementCount(r, "MsgMNListDiff.DeletedQuorums"); err != nil {
+		return err
+	}
+	msg.DeletedQuorums = make([]DeletedQuorum, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		var q DeletedQuorum
+		if q.LLMQType, err = binarySerializer.Uint8(r); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, q.QuorumHash[:]); err != nil {
+			return err
+		}
+		msg.DeletedQuorums = append(msg.DeletedQuorums, q)
+	}
+
+	if count, err = readElementCount(r, "MsgMNListDiff.NewQuorums"); err != nil {
+		return err
+	}
+	msg.NewQuorums = make([]FinalCommitment, 0, preallocLen(count))
+	for i := uint64(0); i < count; i++ {
+		var c FinalCommitment
+		if err := c.Deserialize(r); err != nil {
+			return err
+		}
+		msg.NewQuorums = append(msg.NewQuorums, c)
+	}
+	return nil
+}
+
+// BtcEncode encodes the receiver to w.  This is part of the wire.Message
+// interface implementation.
+func (msg *MsgMNListDiff) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if _, err := w.Write(msg.BaseBlockHash[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.BlockHash[:]); err != nil {
+		return err
+	}
+	if err := msg.CbTxMerkleTree.Serialize(w); err != nil {
+		return err
+	}
+	if err := msg.CbTx.BtcEncode(w, pver, enc); err != nil {
+		return err
+	}
+
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.DeletedMNs))); err != nil {
+		return err
+	}
+	for i := range msg.DeletedMNs {
+		if _, err := w.Write(msg.DeletedMNs[i][:]); err != nil {
+			return err
+		}
+	}
+
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.MNList))); err != nil {
+		return err
+	}
+	for i := range msg.MNList {
+		if err := msg.MNList[i].Serialize(w); err != nil {
+			return err
+		}
+	}
+
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.DeletedQuorums))); err != nil {
+		return err
+	}
+	for _, q := range msg.DeletedQuorums {
+		if err := binarySerializer.PutUint8(w, q.LLMQType); err != nil {
+			return err
+		}
+		if _, err := w.Write(q.QuorumHash[:]); err != nil {
+			return err
+		}
+	}
+
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.NewQuorums))); err != nil {
+		return err
+	}
+	for i := range msg.NewQuorums {
+		if err := msg.NewQuorums[i].Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the wire.Message interface implementation.
+func (msg *MsgMNListDiff) Command() string {
+	return CmdMNListDiff
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the wire.Message interface implementation.
+func (msg *MsgMNListDiff) MaxPayloadLength(pver uint32) uint32 {
+	return wire.MaxMessagePayload
+}
+
+// VerifyCoinbase checks that CbTx is the coinbase of the block with the given
+// header and returns its CbTx payload.
+func (msg *MsgMNListDiff) VerifyCoinbase(header *wire.BlockHeader) (*CbTx, error) {
+	if blockHash := header.BlockHash(); blockHash != msg.BlockHash {
+		return nil, fmt.Errorf("mnlistdiff is for block %v, header is %v",
+			msg.BlockHash, blockHash)
+	}
+	root, matches, indexes, err := msg.CbTxMerkleTree.ExtractMatches()
+	if err != nil {
+		return nil, err
+	}
+	if root != header.MerkleRoot {
+		return nil, fmt.Errorf("coinbase merkle proof root %v does not match block merkle root %v",
+			root, header.MerkleRoot)
+	}
+	txHash := msg.CbTx.TxHash()
+	if len(matches) != 1 || matches[0] != txHash || indexes[0] != 0 {
+		return nil, fmt.Errorf("coinbase merkle proof does not prove coinbase %v", txHash)
+	}
+	p, err := DecodeTxPayload(&msg.CbTx)
+	if err != nil {
+		return nil, err
+	}
+	cbTx, ok := p.(*CbTx)
+	if !ok {
+		return nil, fmt.Errorf("coinbase %v carries a %s payload, not %s",
+			txHash, p.Kind(), KindCbTx)
+	}
+	return cbTx, nil
+}
+
+// Verify applies the diff to base after checking the coinbase proof against
+// header, and confirms that the resulting list matches the merkleRootMNList
+// committed in the coinbase.  It returns the list at BlockHash.
+func (msg *MsgMNListDiff) Verify(header *wire.BlockHeader, base *SimplifiedMNList) (*SimplifiedMNList, error) {
+	cbTx, err := msg.VerifyCoinbase(header)
+	if err != nil {
+		return nil, err
+	}
+	next, err := base.ApplyDiff(msg)
+	if err != nil {
+		return nil, err
+	}
+	if root := next.CalcMerkleRoot(); root != cbTx.MerkleRootMNList {
+		return nil, fmt.Errorf("masternode list merkle root %v does not match coinbase commitment %v",
+			root, cbTx.MerkleRootMNList)
+	}
+	return next, nil
+}
@@ -0,0 +1,233 @@
+package wire
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	_ wire.Message = (*MsgGetMNListDiff)(nil)
+	_ wire.Message = (*MsgMNListDiff)(nil)
+)
+
+func randomSMLEntry() SimplifiedMNListEntry {
+	e := SimplifiedMNListEntry{
+		ProRegTxHash:  randomHash(),
+		ConfirmedHash: randomHash(),
+		Service:       NetService{IP: net.ParseIP("198.51.100.4").To16(), Port: 8369},
+		IsValid:       true,
+	}
+	copy(e.PubKeyOperator[:], randomBytes(BLS_PUBKEY_SIZE))
+	copy(e.KeyIDVoting[:], randomBytes(KEY_ID_SIZE))
+	return e
+}
+
+// coinbaseWithCbTx returns a coinbase transaction committing to root.
+func coinbaseWithCbTx(t *testing.T, height int32, root chainhash.Hash) *wire.MsgTx {
+	t.Helper()
+	var buf bytes.Buffer
+	cb := &CbTx{Version: 2, Height: height, MerkleRootMNList: root}
+	if err := cb.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.NullDataScript(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(SYSCOIN_TX_VERSION_MN_COINBASE)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: ^uint32(0)}, []byte{1, 2}, nil))
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	return tx
+}
+
+// buildDiff returns an mnlistdiff from base to a block whose coinbase
+// commits to the list after deleting deleted and adding or updating added,
+// together with that block's header.
+func buildDiff(t *testing.T, base *SimplifiedMNList, deleted []chainhash.Hash, added []SimplifiedMNListEntry) (*MsgMNListDiff, *wire.BlockHeader) {
+	t.Helper()
+	msg := &MsgMNListDiff{
+		BaseBlockHash: base.BlockHash,
+		DeletedMNs:    deleted,
+		MNList:        added,
+	}
+	next, err := base.ApplyDiff(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := coinbaseWithCbTx(t, 100, next.CalcMerkleRoot())
+	txHashes := []chainhash.Hash{cb.TxHash(), randomHash(), randomHash()}
+	header := wire.NewBlockHeader(1, &base.BlockHash, &chainhash.Hash{}, 0, 0)
+	header.MerkleRoot = CalcMerkleRoot(txHashes)
+
+	msg.BlockHash = header.BlockHash()
+	msg.CbTx = *cb
+	msg.CbTxMerkleTree = *NewPartialMerkleTree(txHashes, []bool{true, false, false})
+	return msg, header
+}
+
+func TestPartialMerkleTree(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		hashes := make([]chainhash.Hash, n)
+		matches := make([]bool, n)
+		for i := range hashes {
+			hashes[i] = randomHash()
+			matches[i] = i%3 == 0
+		}
+		tree := NewPartialMerkleTree(hashes, matches)
+
+		var buf bytes.Buffer
+		if err := tree.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != tree.SerializeSize() {
+			t.Errorf("%d txs: SerializeSize %d, wrote %d", n, tree.SerializeSize(), buf.Len())
+		}
+		var decoded PartialMerkleTree
+		if err := decoded.Deserialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		root, got, indexes, err := decoded.ExtractMatches()
+		if err != nil {
+			t.Fatalf("%d txs: %v", n, err)
+		}
+		if root != CalcMerkleRoot(hashes) {
+			t.Errorf("%d txs: root mismatch", n)
+		}
+		for i, idx := range indexes {
+			if idx%3 != 0 || got[i] != hashes[idx] {
+				t.Errorf("%d txs: unexpected match %d at %d", n, i, idx)
+			}
+		}
+		if len(got) != (n+2)/3 {
+			t.Errorf("%d txs: got %d matches", n, len(got))
+		}
+	}
+}
+
+func TestPartialMerkleTreeRejectsMalformed(t *testing.T) {
+	hashes := []chainhash.Hash{randomHash(), randomHash(), randomHash()}
+	good := NewPartialMerkleTree(hashes, []bool{true, false, false})
+
+	extraHash := *good
+	extraHash.Hashes = append(append([]chainhash.Hash(nil), good.Hashes...), randomHash())
+	extraFlags := *good
+	extraFlags.Flags = append(append([]byte(nil), good.Flags...), 0)
+	noTxs := *good
+	noTxs.Transactions = 0
+
+	for name, tree := range map[string]*PartialMerkleTree{
+		"unused hash":       &extraHash,
+		"unused flag byte":  &extraFlags,
+		"no transactions":   &noTxs,
+		"missing flag bits": {Transactions: 3, Hashes: good.Hashes},
+	} {
+		if _, _, _, err := tree.ExtractMatches(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMsgMNListDiffWire(t *testing.T) {
+	base := NewSimplifiedMNList(chainhash.Hash{}, nil)
+	msg, _ := buildDiff(t, base, nil, []SimplifiedMNListEntry{randomSMLEntry(), randomSMLEntry()})
+	msg.DeletedQuorums = []DeletedQuorum{{LLMQType: 1, QuorumHash: randomHash()}}
+	msg.NewQuorums = []FinalCommitment{{
+		Version:      1,
+		LLMQType:     1,
+		QuorumHash:   randomHash(),
+		Signers:      []bool{true, false, true, true, false, false, true, true, true, false},
+		ValidMembers: []bool{true, true, true, true, true, true, true, true, true, false},
+	}}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	var decoded MsgMNListDiff
+	if err := decoded.BtcDecode(bytes.NewReader(buf.Bytes()), wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	var reencoded bytes.Buffer
+	if err := decoded.BtcEncode(&reencoded, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reencoded.Bytes(), buf.Bytes()) {
+		t.Errorf("mnlistdiff round trip mismatch")
+	}
+	if !reflect.DeepEqual(decoded.MNList, msg.MNList) || !reflect.DeepEqual(decoded.NewQuorums, msg.NewQuorums) {
+		t.Errorf("decoded entries mismatch:\n got %+v\nwant %+v", decoded, msg)
+	}
+	if got := decoded.NewQuorums[0].CountSigners(); got != 6 {
+		t.Errorf("CountSigners = %d, want 6", got)
+	}
+
+	get := &MsgGetMNListDiff{BaseBlockHash: randomHash(), BlockHash: randomHash()}
+	buf.Reset()
+	if err := get.BtcEncode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	var decodedGet MsgGetMNListDiff
+	if err := decodedGet.BtcDecode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if decodedGet != *get {
+		t.Errorf("getmnlistd round trip mismatch")
+	}
+}
+
+func TestMsgMNListDiffVerify(t *testing.T) {
+	entries := []SimplifiedMNListEntry{randomSMLEntry(), randomSMLEntry(), randomSMLEntry()}
+	base := NewSimplifiedMNList(chainhash.Hash{}, nil)
+	msg, header := buildDiff(t, base, nil, entries)
+	full, err := msg.Verify(header, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full.Entries) != 3 || full.BlockHash != header.BlockHash() {
+		t.Fatalf("unexpected list %+v", full)
+	}
+
+	updated := entries[1]
+	updated.IsValid = false
+	msg, header = buildDiff(t, full, []chainhash.Hash{entries[0].ProRegTxHash}, []SimplifiedMNListEntry{updated})
+	next, err := msg.Verify(header, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(next.Entries))
+	}
+
+	// A diff that leaves out an update no longer matches the coinbase
+	// commitment.
+	tampered := *msg
+	tampered.MNList = nil
+	if _, err := tampered.Verify(header, full); err == nil {
+		t.Error("expected merkle root mismatch")
+	}
+
+	// The coinbase must be proven against the header.
+	otherHeader := *header
+	otherHeader.Nonce++
+	if _, err := msg.Verify(&otherHeader, full); err == nil {
+		t.Error("expected block hash mismatch")
+	}
+	tampered = *msg
+	tampered.CbTxMerkleTree.Hashes = append([]chainhash.Hash(nil), msg.CbTxMerkleTree.Hashes...)
+	tampered.CbTxMerkleTree.Hashes[len(tampered.CbTxMerkleTree.Hashes)-1] = randomHash()
+	if _, err := tampered.Verify(header, full); err == nil {
+		t.Error("expected merkle proof mismatch")
+	}
+
+	// Applying the diff to the wrong base list fails.
+	if _, err := msg.Verify(header, base); err == nil {
+		t.Error("expected base block mismatch")
+	}
+}
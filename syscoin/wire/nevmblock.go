@@ -53,7 +53,7 @@ type NEVMDisconnectBlockWire struct {
 
 func (a *NEVMAddressEntry) Deserialize(r io.Reader) error {
     var err error
-    a.Address, err = wire.ReadVarBytes(r, 0, HASH_SIZE, "Address")
+    a.Address, err = readVarBytes(r, HASH_SIZE, "Address")
     if err != nil {
         return err
     }
@@ -65,24 +65,16 @@ func (a *NEVMAddressEntry) Deserialize(r io.Reader) error {
 }
 
 func (a *NEVMAddressEntry) Serialize(w io.Writer) error {
-    err := wire.WriteVarBytes(w, 0, a.Address)
-    if err != nil {
-        return err
-    }
-    err = binarySerializer.PutUint32(w, littleEndian, a.CollateralHeight)
-    if err != nil {
-        return err
-    }
-    return nil
+    return writeAppended(w, a)
 }
 
 func (a *NEVMAddressUpdateEntry) Deserialize(r io.Reader) error {
     var err error
-    a.OldAddress, err = wire.ReadVarBytes(r, 0, HASH_SIZE, "OldAddress")
+    a.OldAddress, err = readVarBytes(r, HASH_SIZE, "OldAddress")
     if err != nil {
         return err
     }
-    a.NewAddress, err = wire.ReadVarBytes(r, 0, HASH_SIZE, "NewAddress")
+    a.NewAddress, err = readVarBytes(r, HASH_SIZE, "NewAddress")
     if err != nil {
         return err
     }
@@ -94,24 +86,12 @@ func (a *NEVMAddressUpdateEntry) Deserialize(r io.Reader) error {
 }
 
 func (a *NEVMAddressUpdateEntry) Serialize(w io.Writer) error {
-    err := wire.WriteVarBytes(w, 0, a.OldAddress)
-    if err != nil {
-        return err
-    }
-    err = wire.WriteVarBytes(w, 0, a.NewAddress)
-    if err != nil {
-        return err
-    }
-    err = binarySerializer.PutUint32(w, littleEndian, a.CollateralHeight)
-    if err != nil {
-        return err
-    }
-    return nil
+    return writeAppended(w, a)
 }
 
 func (a *NEVMRemoveEntry) Deserialize(r io.Reader) error {
     var err error
-    a.Address, err = wire.ReadVarBytes(r, 0, HASH_SIZE, "Address")
+    a.Address, err = readVarBytes(r, HASH_SIZE, "Address")
     if err != nil {
         return err
     }
@@ -119,98 +99,62 @@ func (a *NEVMRemoveEntry) Deserialize(r io.Reader) error {
 }
 
 func (a *NEVMRemoveEntry) Serialize(w io.Writer) error {
-    err := wire.WriteVarBytes(w, 0, a.Address)
-    if err != nil {
-        return err
-    }
-    return nil
+    return writeAppended(w, a)
 }
 
 func (d *NEVMAddressDiff) Deserialize(r io.Reader) error {
     var err error
 
     // Deserialize AddedMNNEVM
-    numAdded, err := wire.ReadVarInt(r, 0)
+    numAdded, err := readElementCount(r, "AddedMNNEVM")
     if err != nil {
         return err
     }
-    d.AddedMNNEVM = make([]NEVMAddressEntry, numAdded)
-    for i := range d.AddedMNNEVM {
-        err = d.AddedMNNEVM[i].Deserialize(r)
+    d.AddedMNNEVM = make([]NEVMAddressEntry, 0, preallocLen(numAdded))
+    for i := uint64(0); i < numAdded; i++ {
+        var entry NEVMAddressEntry
+        err = entry.Deserialize(r)
         if err != nil {
             return err
         }
+        d.AddedMNNEVM = append(d.AddedMNNEVM, entry)
     }
 
     // Deserialize UpdatedMNNEVM
-    numUpdated, err := wire.ReadVarInt(r, 0)
+    numUpdated, err := readElementCount(r, "UpdatedMNNEVM")
     if err != nil {
         return err
     }
-    d.UpdatedMNNEVM = make([]NEVMAddressUpdateEntry, numUpdated)
-    for i := range d.UpdatedMNNEVM {
-        err = d.UpdatedMNNEVM[i].Deserialize(r)
+    d.UpdatedMNNEVM = make([]NEVMAddressUpdateEntry, 0, preallocLen(numUpdated))
+    for i := uint64(0); i < numUpdated; i++ {
+        var entry NEVMAddressUpdateEntry
+        err = entry.Deserialize(r)
         if err != nil {
             return err
         }
+        d.UpdatedMNNEVM = append(d.UpdatedMNNEVM, entry)
     }
 
     // Deserialize RemovedMNNEVM
-    numRemoved, err := wire.ReadVarInt(r, 0)
+    numRemoved, err := readElementCount(r, "RemovedMNNEVM")
     if err != nil {
         return err
     }
-    d.RemovedMNNEVM = make([]NEVMRemoveEntry, numRemoved)
-    for i := range d.RemovedMNNEVM {
-        err = d.RemovedMNNEVM[i].Deserialize(r)
+    d.RemovedMNNEVM = make([]NEVMRemoveEntry, 0, preallocLen(numRemoved))
+    for i := uint64(0); i < numRemoved; i++ {
+        var entry NEVMRemoveEntry
+        err = entry.Deserialize(r)
         if err != nil {
             return err
         }
+        d.RemovedMNNEVM = append(d.RemovedMNNEVM, entry)
     }
 
     return nil
 }
 
 func (d *NEVMAddressDiff) Serialize(w io.Writer) error {
-    var err error
-
-    // Serialize AddedMNNEVM
-    err = wire.WriteVarInt(w, 0, uint64(len(d.AddedMNNEVM)))
-    if err != nil {
-        return err
-    }
-    for i := range d.AddedMNNEVM {
-        err = d.AddedMNNEVM[i].Serialize(w)
-        if err != nil {
-            return err
-        }
-    }
-
-    // Serialize UpdatedMNNEVM
-    err = wire.WriteVarInt(w, 0, uint64(len(d.UpdatedMNNEVM)))
-    if err != nil {
-        return err
-    }
-    for i := range d.UpdatedMNNEVM {
-        err = d.UpdatedMNNEVM[i].Serialize(w)
-        if err != nil {
-            return err
-        }
-    }
-
-    // Serialize RemovedMNNEVM
-    err = wire.WriteVarInt(w, 0, uint64(len(d.RemovedMNNEVM)))
-    if err != nil {
-        return err
-    }
-    for i := range d.RemovedMNNEVM {
-        err = d.RemovedMNNEVM[i].Serialize(w)
-        if err != nil {
-            return err
-        }
-    }
-
-    return nil
+    return writeAppended(w, d)
 }
 
 func (a *NEVMBlockWire) Deserialize(r io.Reader) error {
@@ -238,86 +182,27 @@ func (a *NEVMBlockWire) Deserialize(r io.Reader) error {
     }
 
     // Deserialize NEVMBlockData
-    a.NEVMBlockData, err = wire.ReadVarBytes(r, 0, MAX_NEVM_BLOCK_SIZE, "NEVMBlockData")
+    a.NEVMBlockData, err = readVarBytes(r, MAX_NEVM_BLOCK_SIZE, "NEVMBlockData")
     if err != nil {
         return err
     }
 
-    // Deserialize SYSBlockHash
-    a.SYSBlockHash = make([]byte, HASH_SIZE)
-    _, err = io.ReadFull(r, a.SYSBlockHash)
-    if err != nil {
-        return err
-    }
-
-    // Deserialize VersionHashes
-    numVH, err := wire.ReadVarInt(r, 0)
-    if err != nil {
-        return err
-    }
-    a.VersionHashes = make([][]byte, numVH)
-    for i := 0; i < int(numVH); i++ {
-        a.VersionHashes[i], err = wire.ReadVarBytes(r, 0, HASH_SIZE, "VersionHash")
-        if err != nil {
-            return err
-        }
-    }
-
-    // Deserialize Diff
-    a.Diff = NEVMAddressDiff{}
-    err = a.Diff.Deserialize(r)
+    // Deserialize SYSBlockHash, VersionHashes and Diff
+    var t NEVMBlockTrailer
+    err = t.Deserialize(r)
     if err != nil {
         return err
     }
+    a.SYSBlockHash = t.SYSBlockHash
+    a.VersionHashes = t.VersionHashes
+    a.Diff = t.Diff
 
     return nil
 }
 
 
 func (a *NEVMBlockWire) Serialize(w io.Writer) error {
-	var err error
-
-	// NEVMBlockHash
-	if _, err = w.Write(a.NEVMBlockHash[:]); err != nil {
-		return err
-	}
-
-	// TxRoot
-	if _, err = w.Write(a.TxRoot[:]); err != nil {
-		return err
-	}
-
-	// ReceiptRoot
-	if _, err = w.Write(a.ReceiptRoot[:]); err != nil {
-		return err
-	}
-
-	// NEVMBlockData
-	if err = wire.WriteVarBytes(w, 0, a.NEVMBlockData); err != nil {
-		return err
-	}
-
-	// SYSBlockHash
-	if _, err = w.Write(a.SYSBlockHash[:]); err != nil {
-		return err
-	}
-
-	// VersionHashes (slice of byte slices)
-	if err = wire.WriteVarInt(w, 0, uint64(len(a.VersionHashes))); err != nil {
-		return err
-	}
-	for _, vh := range a.VersionHashes {
-		if err = wire.WriteVarBytes(w, 0, vh); err != nil {
-			return err
-		}
-	}
-
-	// Diff
-	if err = a.Diff.Serialize(w); err != nil {
-		return err
-	}
-
-	return nil
+    return writeNEVMBlock(w, a)
 }
 
 func (a *NEVMDisconnectBlockWire) Deserialize(r io.Reader) error {
@@ -340,20 +225,51 @@ func (a *NEVMDisconnectBlockWire) Deserialize(r io.Reader) error {
     return nil
 }
 func (a *NEVMDisconnectBlockWire) Serialize(w io.Writer) error {
-    var err error
+    return writeAppended(w, a)
+}
 
-    // Serialize SYSBlockHash
-    _, err = w.Write(a.SYSBlockHash[:])
-    if err != nil {
-        return err
+
+func (a *NEVMAddressEntry) SerializeSize() int {
+    return varBytesSerializeSize(a.Address) + 4
+}
+
+func (a *NEVMAddressUpdateEntry) SerializeSize() int {
+    return varBytesSerializeSize(a.OldAddress) + varBytesSerializeSize(a.NewAddress) + 4
+}
+
+func (a *NEVMRemoveEntry) SerializeSize() int {
+    return varBytesSerializeSize(a.Address)
+}
+
+func (d *NEVMAddressDiff) SerializeSize() int {
+    n := wire.VarIntSerializeSize(uint64(len(d.AddedMNNEVM)))
+    for i := range d.AddedMNNEVM {
+        n += d.AddedMNNEVM[i].SerializeSize()
+    }
+    n += wire.VarIntSerializeSize(uint64(len(d.UpdatedMNNEVM)))
+    for i := range d.UpdatedMNNEVM {
+        n += d.UpdatedMNNEVM[i].SerializeSize()
+    }
+    n += wire.VarIntSerializeSize(uint64(len(d.RemovedMNNEVM)))
+    for i := range d.RemovedMNNEVM {
+        n += d.RemovedMNNEVM[i].SerializeSize()
     }
+    return n
+}
 
-    // Serialize Diff
-    err = a.Diff.Serialize(w)
-    if err != nil {
-        return err
+func (a *NEVMBlockWire) SerializeSize() int {
+    n := len(a.NEVMBlockHash) + len(a.TxRoot) + len(a.ReceiptRoot) +
+        varBytesSerializeSize(a.NEVMBlockData) + len(a.SYSBlockHash) +
+        wire.VarIntSerializeSize(uint64(len(a.VersionHashes)))
+    for _, vh := range a.VersionHashes {
+        n += varBytesSerializeSize(vh)
     }
+    return n + a.Diff.SerializeSize()
+}
 
-    return nil
+func (a *NEVMDisconnectBlockWire) SerializeSize() int {
+    return len(a.SYSBlockHash) + a.Diff.SerializeSize()
 }
 
+func (a *NEVMBlockWire) Kind() PayloadKind { return KindNEVMBlock }
+func (a *NEVMDisconnectBlockWire) Kind() PayloadKind { return KindNEVMDisconnect }
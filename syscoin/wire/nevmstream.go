@@ -0,0 +1,148 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// NEVMBlockTrailer holds the fields of an NEVMBlockWire that follow its
+// NEVMBlockData.
+type NEVMBlockTrailer struct {
+	SYSBlockHash  []byte
+	VersionHashes [][]byte
+	Diff          NEVMAddressDiff
+}
+
+// NEVMBlockReader decodes an NEVMBlockWire from a stream without holding
+// its NEVMBlockData in memory.  NewNEVMBlockReader reads the fixed header
+// fields and the body length; the body is then available from Body, and
+// Trailer decodes the remaining fields once the body has been consumed or,
+// if it has not, after discarding what is left of it.
+//
+// The reader must not be used concurrently, and the underlying reader must
+// not be read from until Trailer has returned.
+type NEVMBlockReader struct {
+	NEVMBlockHash []byte
+	TxRoot        []byte
+	ReceiptRoot   []byte
+
+	r        io.Reader
+	body     nevmBodyReader
+	bodySize uint64
+	trailer  *NEVMBlockTrailer
+	err      error
+}
+
+// NewNEVMBlockReader reads the NEVMBlockHash, TxRoot and ReceiptRoot of the
+// NEVMBlockWire at the start of r, and the length of its NEVMBlockData,
+// which must not exceed MAX_NEVM_BLOCK_SIZE.
+func NewNEVMBlockReader(r io.Reader) (*NEVMBlockReader, error) {
+	b := &NEVMBlockReader{r: r}
+	for _, field := range []*[]byte{&b.NEVMBlockHash, &b.TxRoot, &b.ReceiptRoot} {
+		*field = make([]byte, HASH_SIZE)
+		if _, err := io.ReadFull(r, *field); err != nil {
+			return nil, err
+		}
+	}
+	size, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if size > MAX_NEVM_BLOCK_SIZE {
+		str := fmt.Sprintf("NEVMBlockData is larger than the max allowed size "+
+			"[count %d, max %d]", size, MAX_NEVM_BLOCK_SIZE)
+		return nil, messageError("NewNEVMBlockReader", str)
+	}
+	b.bodySize = size
+	b.body = nevmBodyReader{r: r, remaining: int64(size)}
+	return b, nil
+}
+
+// BodySize returns the length of the block's NEVMBlockData.
+func (b *NEVMBlockReader) BodySize() uint64 {
+	return b.bodySize
+}
+
+// Body returns a reader yielding the block's NEVMBlockData and then io.EOF.
+// It reports io.ErrUnexpectedEOF if the stream ends before the body does.
+// Once Trailer has been called it yields nothing more.
+func (b *NEVMBlockReader) Body() io.Reader {
+	return &b.body
+}
+
+// Trailer discards whatever part of the NEVMBlockData has not been read
+// from Body and decodes the SYSBlockHash, VersionHashes and Diff following
+// it.  Later calls return the same result.
+func (b *NEVMBlockReader) Trailer() (*NEVMBlockTrailer, error) {
+	if b.trailer != nil || b.err != nil {
+		return b.trailer, b.err
+	}
+	b.trailer, b.err = b.readTrailer()
+	return b.trailer, b.err
+}
+
+func (b *NEVMBlockReader) readTrailer() (*NEVMBlockTrailer, error) {
+	if _, err := io.Copy(io.Discard, &b.body); err != nil {
+		return nil, err
+	}
+
+	t := &NEVMBlockTrailer{}
+	if err := t.Deserialize(b.r); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Deserialize decodes the trailer of an NEVMBlockWire from r.  It is shared
+// by NEVMBlockWire.Deserialize and NEVMBlockReader so both apply the same
+// limits.
+func (t *NEVMBlockTrailer) Deserialize(r io.Reader) error {
+	t.SYSBlockHash = make([]byte, HASH_SIZE)
+	if _, err := io.ReadFull(r, t.SYSBlockHash); err != nil {
+		return err
+	}
+	numVH, err := readElementCount(r, "VersionHashes")
+	if err != nil {
+		return err
+	}
+	t.VersionHashes = make([][]byte, 0, preallocLen(numVH))
+	for i := uint64(0); i < numVH; i++ {
+		vh, err := readVarBytes(r, HASH_SIZE, "VersionHash")
+		if err != nil {
+			return err
+		}
+		t.VersionHashes = append(t.VersionHashes, vh)
+	}
+	t.Diff = NEVMAddressDiff{}
+	return t.Diff.Deserialize(r)
+}
+
+// nevmBodyReader reads the remaining bytes of an NEVMBlockData.
+type nevmBodyReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (br *nevmBodyReader) Read(p []byte) (int, error) {
+	if br.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > br.remaining {
+		p = p[:br.remaining]
+	}
+	n, err := br.r.Read(p)
+	br.remaining -= int64(n)
+	if err == io.EOF {
+		if br.remaining > 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	return n, err
+}
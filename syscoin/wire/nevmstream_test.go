@@ -0,0 +1,152 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func streamTestBlock(bodySize int) *NEVMBlockWire {
+	return &NEVMBlockWire{
+		NEVMBlockHash: randomBytes(HASH_SIZE),
+		TxRoot:        randomBytes(HASH_SIZE),
+		ReceiptRoot:   randomBytes(HASH_SIZE),
+		NEVMBlockData: randomBytes(bodySize),
+		SYSBlockHash:  randomBytes(HASH_SIZE),
+		VersionHashes: [][]byte{randomBytes(HASH_SIZE), randomBytes(HASH_SIZE)},
+		Diff: NEVMAddressDiff{
+			AddedMNNEVM:   []NEVMAddressEntry{{Address: randomBytes(ETH_ADDRESS_SIZE), CollateralHeight: 7}},
+			UpdatedMNNEVM: []NEVMAddressUpdateEntry{},
+			RemovedMNNEVM: []NEVMRemoveEntry{{Address: randomBytes(ETH_ADDRESS_SIZE)}},
+		},
+	}
+}
+
+func serializeBlock(t *testing.T, block *NEVMBlockWire) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := block.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func checkTrailer(t *testing.T, br *NEVMBlockReader, block *NEVMBlockWire) {
+	t.Helper()
+	trailer, err := br.Trailer()
+	if err != nil {
+		t.Fatalf("Trailer: %v", err)
+	}
+	want := &NEVMBlockTrailer{
+		SYSBlockHash:  block.SYSBlockHash,
+		VersionHashes: block.VersionHashes,
+		Diff:          block.Diff,
+	}
+	if !reflect.DeepEqual(trailer, want) {
+		t.Errorf("Trailer = %+v, want %+v", trailer, want)
+	}
+}
+
+func TestNEVMBlockReader(t *testing.T) {
+	block := streamTestBlock(1 << 20)
+	raw := serializeBlock(t, block)
+
+	// Read the body in full, then the trailer.
+	r := bytes.NewReader(raw)
+	br, err := NewNEVMBlockReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(br.NEVMBlockHash, block.NEVMBlockHash) || !bytes.Equal(br.TxRoot, block.TxRoot) ||
+		!bytes.Equal(br.ReceiptRoot, block.ReceiptRoot) {
+		t.Error("header fields mismatch")
+	}
+	if br.BodySize() != uint64(len(block.NEVMBlockData)) {
+		t.Errorf("BodySize = %d, want %d", br.BodySize(), len(block.NEVMBlockData))
+	}
+	body, err := io.ReadAll(br.Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, block.NEVMBlockData) {
+		t.Error("body mismatch")
+	}
+	checkTrailer(t, br, block)
+	if r.Len() != 0 {
+		t.Errorf("%d bytes left unread", r.Len())
+	}
+
+	// Skip the body entirely.
+	br, err = NewNEVMBlockReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTrailer(t, br, block)
+	checkTrailer(t, br, block)
+	if n, err := br.Body().Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Body after Trailer = %d, %v", n, err)
+	}
+
+	// Read part of the body.
+	br, err = NewNEVMBlockReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := make([]byte, 1000)
+	if _, err := io.ReadFull(br.Body(), prefix); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(prefix, block.NEVMBlockData[:1000]) {
+		t.Error("body prefix mismatch")
+	}
+	checkTrailer(t, br, block)
+}
+
+func TestNEVMBlockReaderEmptyBody(t *testing.T) {
+	block := streamTestBlock(0)
+	br, err := NewNEVMBlockReader(bytes.NewReader(serializeBlock(t, block)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := br.Body().Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("empty Body = %d, %v", n, err)
+	}
+	checkTrailer(t, br, block)
+}
+
+func TestNEVMBlockReaderMalformed(t *testing.T) {
+	block := streamTestBlock(10000)
+	raw := serializeBlock(t, block)
+	headerLen := 3*HASH_SIZE + wire.VarIntSerializeSize(uint64(len(block.NEVMBlockData)))
+
+	// A stream ending inside the body.
+	br, err := NewNEVMBlockReader(bytes.NewReader(raw[:headerLen+5000]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(br.Body()); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated Body error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	br, _ = NewNEVMBlockReader(bytes.NewReader(raw[:headerLen+5000]))
+	if _, err := br.Trailer(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated Trailer error = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	// A stream ending inside the trailer.
+	br, _ = NewNEVMBlockReader(bytes.NewReader(raw[:len(raw)-1]))
+	if _, err := br.Trailer(); err == nil {
+		t.Error("Trailer accepted a truncated diff")
+	}
+
+	// A body length over MAX_NEVM_BLOCK_SIZE.
+	var buf bytes.Buffer
+	buf.Write(raw[:3*HASH_SIZE])
+	wire.WriteVarInt(&buf, 0, MAX_NEVM_BLOCK_SIZE+1)
+	if _, err := NewNEVMBlockReader(&buf); err == nil {
+		t.Error("NewNEVMBlockReader accepted an oversized body")
+	}
+}
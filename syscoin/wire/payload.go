@@ -0,0 +1,230 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// PayloadKind names a type of Syscoin payload.  Kinds double as the type
+// names accepted by the syswire command.
+type PayloadKind string
+
+const (
+	KindAllocation     PayloadKind = "allocation"
+	KindMint           PayloadKind = "mint"
+	KindBurnToEthereum PayloadKind = "burn-eth"
+	KindAsset          PayloadKind = "asset"
+	KindNEVMBlock      PayloadKind = "nevmblock"
+	KindNEVMDisconnect PayloadKind = "nevmdisconnect"
+)
+
+// Syscoin transaction versions that carry an asset payload in their
+// OP_RETURN output.
+const (
+	SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_SYSCOIN = 128
+	SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION = 129
+	SYSCOIN_TX_VERSION_ALLOCATION_MINT            = 133
+	SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM    = 134
+	SYSCOIN_TX_VERSION_ALLOCATION_SEND            = 135
+)
+
+// Commands syscoind uses for the NEVM messages it exchanges with the NEVM
+// client.
+const (
+	NEVMCommandBlock      = "nevmblock"
+	NEVMCommandDisconnect = "nevmdisconnect"
+)
+
+// Payload is implemented by every serializable Syscoin payload type.
+type Payload interface {
+	// Serialize writes the payload in syscoind's wire format.
+	Serialize(w io.Writer) error
+
+	// Deserialize decodes the payload from syscoind's wire format.
+	Deserialize(r io.Reader) error
+
+	// SerializeSize returns the number of bytes Serialize will write.
+	SerializeSize() int
+
+	// Kind identifies the payload type.
+	Kind() PayloadKind
+}
+
+// PayloadConstructor returns a new, empty payload ready to be deserialized
+// into.
+type PayloadConstructor func() Payload
+
+// payloadRegistry maps payload kinds, Syscoin transaction versions and NEVM
+// message commands to payload constructors.
+type payloadRegistry struct {
+	sync.RWMutex
+	byKind        map[PayloadKind]PayloadConstructor
+	byTxVersion   map[int32]PayloadConstructor
+	byNEVMCommand map[string]PayloadConstructor
+}
+
+var registry = payloadRegistry{
+	byKind:        make(map[PayloadKind]PayloadConstructor),
+	byTxVersion:   make(map[int32]PayloadConstructor),
+	byNEVMCommand: make(map[string]PayloadConstructor),
+}
+
+func init() {
+	newAllocation := func() Payload { return &AssetAllocationType{} }
+	newMint := func() Payload { return &MintSyscoinType{} }
+	newBurn := func() Payload { return &SyscoinBurnToEthereumType{} }
+	newBlock := func() Payload { return &NEVMBlockWire{} }
+	newDisconnect := func() Payload { return &NEVMDisconnectBlockWire{} }
+
+	mustRegister(RegisterPayloadKind(KindAllocation, newAllocation))
+	mustRegister(RegisterPayloadKind(KindAsset, func() Payload { return &AssetType{} }))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_SYSCOIN, newBurn))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION, newBurn))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_ALLOCATION_MINT, newMint))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM, newBurn))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_ALLOCATION_SEND, newAllocation))
+	mustRegister(RegisterNEVMCommand(NEVMCommandBlock, newBlock))
+	mustRegister(RegisterNEVMCommand(NEVMCommandDisconnect, newDisconnect))
+}
+
+func mustRegister(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// registerKind records the constructor under the kind of the payload it
+// builds.  The caller must hold the registry lock.
+func (reg *payloadRegistry) registerKind(ctor PayloadConstructor) error {
+	kind := ctor().Kind()
+	if existing, ok := reg.byKind[kind]; ok {
+		// Several tx versions may share one payload type; only a
+		// different type claiming the same kind is a conflict.
+		if reflect.TypeOf(existing()) != reflect.TypeOf(ctor()) {
+			return fmt.Errorf("payload kind %q is already registered", kind)
+		}
+		return nil
+	}
+	reg.byKind[kind] = ctor
+	return nil
+}
+
+// RegisterPayloadKind makes a payload type available through NewPayload
+// without tying it to a transaction version or NEVM command.  kind must match
+// the Kind reported by the constructed payload.
+func RegisterPayloadKind(kind PayloadKind, ctor PayloadConstructor) error {
+	if got := ctor().Kind(); got != kind {
+		return fmt.Errorf("constructor builds kind %q, not %q", got, kind)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	return registry.registerKind(ctor)
+}
+
+// RegisterTxVersion associates a Syscoin transaction version with the payload
+// type carried by transactions of that version.  It returns an error if the
+// version is already registered.
+func RegisterTxVersion(version int32, ctor PayloadConstructor) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.byTxVersion[version]; ok {
+		return fmt.Errorf("transaction version %d is already registered", version)
+	}
+	if err := registry.registerKind(ctor); err != nil {
+		return err
+	}
+	registry.byTxVersion[version] = ctor
+	return nil
+}
+
+// RegisterNEVMCommand associates an NEVM message command with its payload
+// type.  It returns an error if the command is already registered.
+func RegisterNEVMCommand(command string, ctor PayloadConstructor) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.byNEVMCommand[command]; ok {
+		return fmt.Errorf("NEVM command %q is already registered", command)
+	}
+	if err := registry.registerKind(ctor); err != nil {
+		return err
+	}
+	registry.byNEVMCommand[command] = ctor
+	return nil
+}
+
+// NewPayload returns an empty payload of the given kind.
+func NewPayload(kind PayloadKind) (Payload, error) {
+	registry.RLock()
+	ctor, ok := registry.byKind[kind]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown payload kind %q", kind)
+	}
+	return ctor(), nil
+}
+
+// NewPayloadForTxVersion returns an empty payload of the type carried by
+// Syscoin transactions with the given version.
+func NewPayloadForTxVersion(version int32) (Payload, error) {
+	registry.RLock()
+	ctor, ok := registry.byTxVersion[version]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no payload registered for transaction version %d", version)
+	}
+	return ctor(), nil
+}
+
+// NewPayloadForNEVMCommand returns an empty payload of the type carried by
+// the given NEVM message command.
+func NewPayloadForNEVMCommand(command string) (Payload, error) {
+	registry.RLock()
+	ctor, ok := registry.byNEVMCommand[command]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no payload registered for NEVM command %q", command)
+	}
+	return ctor(), nil
+}
+
+// IsAssetTxVersion reports whether version is one of the asset transaction
+// versions, whose payloads carry an allocation.
+func IsAssetTxVersion(version int32) bool {
+	switch version {
+	case SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_SYSCOIN,
+		SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION,
+		SYSCOIN_TX_VERSION_ALLOCATION_MINT,
+		SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM,
+		SYSCOIN_TX_VERSION_ALLOCATION_SEND:
+		return true
+	}
+	return false
+}
+
+// IsSyscoinTxVersion reports whether transactions with the given version
+// carry a registered payload.
+func IsSyscoinTxVersion(version int32) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	_, ok := registry.byTxVersion[version]
+	return ok
+}
+
+// PayloadKinds returns every registered payload kind in sorted order.
+func PayloadKinds() []PayloadKind {
+	registry.RLock()
+	kinds := make([]PayloadKind, 0, len(registry.byKind))
+	for kind := range registry.byKind {
+		kinds = append(kinds, kind)
+	}
+	registry.RUnlock()
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
@@ -0,0 +1,137 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestNewPayloadForTxVersion(t *testing.T) {
+	tests := []struct {
+		version int32
+		kind    PayloadKind
+	}{
+		{SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_SYSCOIN, KindBurnToEthereum},
+		{SYSCOIN_TX_VERSION_SYSCOIN_BURN_TO_ALLOCATION, KindBurnToEthereum},
+		{SYSCOIN_TX_VERSION_ALLOCATION_MINT, KindMint},
+		{SYSCOIN_TX_VERSION_ALLOCATION_BURN_TO_NEVM, KindBurnToEthereum},
+		{SYSCOIN_TX_VERSION_ALLOCATION_SEND, KindAllocation},
+	}
+	for _, test := range tests {
+		p, err := NewPayloadForTxVersion(test.version)
+		if err != nil {
+			t.Fatalf("version %d: %v", test.version, err)
+		}
+		if p.Kind() != test.kind {
+			t.Errorf("version %d: got kind %q, want %q", test.version, p.Kind(), test.kind)
+		}
+	}
+	if _, err := NewPayloadForTxVersion(2); err == nil {
+		t.Error("expected an error for a plain bitcoin transaction version")
+	}
+	if IsSyscoinTxVersion(2) || !IsSyscoinTxVersion(SYSCOIN_TX_VERSION_ALLOCATION_SEND) {
+		t.Error("IsSyscoinTxVersion disagrees with the registry")
+	}
+}
+
+func TestNewPayloadForNEVMCommand(t *testing.T) {
+	p, err := NewPayloadForNEVMCommand(NEVMCommandBlock)
+	if err != nil || p.Kind() != KindNEVMBlock {
+		t.Fatalf("nevmblock: got %v, %v", p, err)
+	}
+	p, err = NewPayloadForNEVMCommand(NEVMCommandDisconnect)
+	if err != nil || p.Kind() != KindNEVMDisconnect {
+		t.Fatalf("nevmdisconnect: got %v, %v", p, err)
+	}
+}
+
+// testPayload is a minimal third-party payload used to exercise
+// registration.
+type testPayload struct {
+	Value uint64
+}
+
+func (p *testPayload) Serialize(w io.Writer) error { return PutUint(w, p.Value) }
+func (p *testPayload) SerializeSize() int          { return SizeOfUint(p.Value) }
+func (p *testPayload) Kind() PayloadKind           { return "test-payload" }
+func (p *testPayload) Deserialize(r io.Reader) error {
+	var err error
+	p.Value, err = ReadUint(r)
+	return err
+}
+
+func TestRegisterPayload(t *testing.T) {
+	newTest := func() Payload { return &testPayload{} }
+	if err := RegisterTxVersion(0x7f01, newTest); err != nil {
+		t.Fatalf("RegisterTxVersion failed: %v", err)
+	}
+	if err := RegisterTxVersion(0x7f01, newTest); err == nil {
+		t.Error("expected an error registering a version twice")
+	}
+	if err := RegisterTxVersion(SYSCOIN_TX_VERSION_ALLOCATION_SEND, newTest); err == nil {
+		t.Error("expected an error overriding a built-in version")
+	}
+	if err := RegisterNEVMCommand("testcommand", newTest); err != nil {
+		t.Fatalf("RegisterNEVMCommand failed: %v", err)
+	}
+	if err := RegisterPayloadKind(KindMint, newTest); err == nil {
+		t.Error("expected an error for a kind mismatch")
+	}
+	if err := RegisterPayloadKind("test-payload", func() Payload { return &AssetType{} }); err == nil {
+		t.Error("expected an error for a kind mismatch")
+	}
+
+	p, err := NewPayload("test-payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Deserialize(bytes.NewReader([]byte{0x80, 0x00})); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.(*testPayload).Value; got != 0x80 {
+		t.Errorf("decoded %d, want 128", got)
+	}
+}
+
+func TestSerializeSize(t *testing.T) {
+	payloads := []Payload{
+		&seedAllocation,
+		&AssetAllocationType{},
+		&MintSyscoinType{
+			Allocation:    seedAllocation,
+			TxHash:        randomBytes(HASH_SIZE),
+			BlockHash:     randomBytes(HASH_SIZE),
+			TxParentNodes: randomBytes(MAX_RLP_SIZE),
+			TxRoot:        randomBytes(HASH_SIZE),
+			ReceiptRoot:   randomBytes(HASH_SIZE),
+		},
+		&SyscoinBurnToEthereumType{Allocation: seedAllocation, EthAddress: randomBytes(20)},
+		&AssetType{Symbol: []byte("SYSX"), Precision: 8},
+		&NEVMBlockWire{
+			NEVMBlockHash: randomBytes(HASH_SIZE),
+			TxRoot:        randomBytes(HASH_SIZE),
+			ReceiptRoot:   randomBytes(HASH_SIZE),
+			NEVMBlockData: randomBytes(70000),
+			SYSBlockHash:  randomBytes(HASH_SIZE),
+			VersionHashes: [][]byte{randomBytes(HASH_SIZE), {}},
+			Diff:          seedDiff,
+		},
+		&NEVMDisconnectBlockWire{SYSBlockHash: randomBytes(HASH_SIZE), Diff: seedDiff},
+	}
+	for _, p := range payloads {
+		var buf bytes.Buffer
+		if err := p.Serialize(&buf); err != nil {
+			t.Fatalf("%s: Serialize failed: %v", p.Kind(), err)
+		}
+		if got := p.SerializeSize(); got != buf.Len() {
+			t.Errorf("%s: SerializeSize() = %d, want %d", p.Kind(), got, buf.Len())
+		}
+	}
+	for _, n := range []uint64{0, 0x7f, 0x80, 0x407f, 0x4080, 1 << 40, ^uint64(0)} {
+		var buf bytes.Buffer
+		PutUint(&buf, n)
+		if got := SizeOfUint(n); got != buf.Len() {
+			t.Errorf("SizeOfUint(%#x) = %d, want %d", n, got, buf.Len())
+		}
+	}
+}
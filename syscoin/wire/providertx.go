@@ -0,0 +1,518 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Syscoin transaction versions of the deterministic masternode (DIP3)
+// special transactions.  Syscoin marks special transactions with the
+// transaction version instead of Dash's separate type field.
+const (
+	SYSCOIN_TX_VERSION_MN_REGISTER          = 80
+	SYSCOIN_TX_VERSION_MN_UPDATE_SERVICE    = 81
+	SYSCOIN_TX_VERSION_MN_UPDATE_REGISTRAR  = 82
+	SYSCOIN_TX_VERSION_MN_UPDATE_REVOKE     = 83
+	SYSCOIN_TX_VERSION_MN_COINBASE          = 84
+	SYSCOIN_TX_VERSION_MN_QUORUM_COMMITMENT = 85
+)
+
+const (
+	// LEGACY_BLS_VERSION and BASIC_BLS_VERSION are the provider payload
+	// versions using the legacy and basic BLS serialization schemes.
+	// Provider payloads of any other version are rejected.
+	LEGACY_BLS_VERSION = 1
+	BASIC_BLS_VERSION  = 2
+
+	// CBTX_VERSION_MERKLE_ROOT_MNLIST and CBTX_VERSION_MERKLE_ROOT_QUORUMS
+	// are the CbTx versions committing to the masternode list and, from
+	// version 2, also to the active quorums.
+	CBTX_VERSION_MERKLE_ROOT_MNLIST  = 1
+	CBTX_VERSION_MERKLE_ROOT_QUORUMS = 2
+
+	KEY_ID_SIZE        = 20
+	BLS_PUBKEY_SIZE    = 48
+	BLS_SIGNATURE_SIZE = 96
+	MAX_SCRIPT_SIZE    = 10000
+
+	// MAX_PAYLOAD_SIG_SIZE bounds the compact ECDSA signatures carried in
+	// vchSig.
+	MAX_PAYLOAD_SIG_SIZE = 128
+)
+
+const (
+	KindProRegTx    PayloadKind = "proregtx"
+	KindProUpServTx PayloadKind = "proupservtx"
+	KindProUpRegTx  PayloadKind = "proupregtx"
+	KindProUpRevTx  PayloadKind = "prouprevtx"
+	KindCbTx        PayloadKind = "cbtx"
+)
+
+func init() {
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_MN_REGISTER, func() Payload { return &ProRegTx{} }))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_MN_UPDATE_SERVICE, func() Payload { return &ProUpServTx{} }))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_MN_UPDATE_REGISTRAR, func() Payload { return &ProUpRegTx{} }))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_MN_UPDATE_REVOKE, func() Payload { return &ProUpRevTx{} }))
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_MN_COINBASE, func() Payload { return &CbTx{} }))
+}
+
+// NetService is a masternode's IP address and port, serialized as a
+// 16-byte IPv6 (or IPv4-mapped) address followed by a big-endian port.
+type NetService struct {
+	IP   net.IP
+	Port uint16
+}
+
+// ProRegTx registers a new deterministic masternode.
+type ProRegTx struct {
+	Version            uint16
+	Type               uint16
+	Mode               uint16
+	CollateralOutpoint wire.OutPoint
+	Addr               NetService
+	KeyIDOwner         [KEY_ID_SIZE]byte
+	PubKeyOperator     [BLS_PUBKEY_SIZE]byte
+	KeyIDVoting        [KEY_ID_SIZE]byte
+	OperatorReward     uint16
+	ScriptPayout       []byte
+	InputsHash         chainhash.Hash
+	Sig                []byte
+}
+
+// ProUpServTx updates a masternode's service address, operator payout
+// script and the NEVM address its operator uses for the NEVM masternode
+// list.
+type ProUpServTx struct {
+	Version              uint16
+	ProTxHash            chainhash.Hash
+	Addr                 NetService
+	ScriptOperatorPayout []byte
+	InputsHash           chainhash.Hash
+	NEVMAddress          []byte
+	Sig                  [BLS_SIGNATURE_SIZE]byte
+}
+
+// ProUpRegTx updates a masternode's operator key, voting key and payout
+// script.
+type ProUpRegTx struct {
+	Version        uint16
+	ProTxHash      chainhash.Hash
+	Mode           uint16
+	PubKeyOperator [BLS_PUBKEY_SIZE]byte
+	KeyIDVoting    [KEY_ID_SIZE]byte
+	ScriptPayout   []byte
+	InputsHash     chainhash.Hash
+	Sig            []byte
+}
+
+// ProUpRevTx revokes a masternode's operator key.
+type ProUpRevTx struct {
+	Version    uint16
+	ProTxHash  chainhash.Hash
+	Reason     uint16
+	InputsHash chainhash.Hash
+	Sig        [BLS_SIGNATURE_SIZE]byte
+}
+
+// CbTx is the coinbase special transaction payload committing to the
+// masternode list and, from version 2, the active quorums.
+type CbTx struct {
+	Version           uint16
+	Height            int32
+	MerkleRootMNList  chainhash.Hash
+	MerkleRootQuorums chainhash.Hash
+}
+
+func (a *NetService) Deserialize(r io.Reader) error {
+	ip := make([]byte, net.IPv6len)
+	if _, err := io.ReadFull(r, ip); err != nil {
+		return err
+	}
+	a.IP = ip
+	var err error
+	a.Port, err = binarySerializer.Uint16(r, bigEndian)
+	return err
+}
+
+func (a *NetService) Serialize(w io.Writer) error {
+	ip := a.IP.To16()
+	if ip == nil {
+		ip = make([]byte, net.IPv6len)
+	}
+	if _, err := w.Write(ip); err != nil {
+		return err
+	}
+	return binarySerializer.PutUint16(w, bigEndian, a.Port)
+}
+
+func (a *NetService) SerializeSize() int {
+	return net.IPv6len + 2
+}
+
+// String returns the service in host:port form.
+func (a *NetService) String() string {
+	return net.JoinHostPort(a.IP.String(), strconv.Itoa(int(a.Port)))
+}
+
+// readProviderVersion reads the version of a provider payload and rejects
+// versions other than LEGACY_BLS_VERSION and BASIC_BLS_VERSION, as syscoind
+// does.
+func readProviderVersion(r io.Reader, f string) (uint16, error) {
+	v, err := binarySerializer.Uint16(r, littleEndian)
+	if err != nil {
+		return 0, err
+	}
+	if v != LEGACY_BLS_VERSION && v != BASIC_BLS_VERSION {
+		return 0, messageError(f, fmt.Sprintf("unknown version %d", v))
+	}
+	return v, nil
+}
+
+// IsLegacyBLS reports whether the payload's operator key and signature use
+// the legacy BLS scheme rather than the basic one.
+func (a *ProRegTx) IsLegacyBLS() bool { return a.Version == LEGACY_BLS_VERSION }
+
+// IsLegacyBLS reports whether the payload's signature uses the legacy BLS
+// scheme rather than the basic one.
+func (a *ProUpServTx) IsLegacyBLS() bool { return a.Version == LEGACY_BLS_VERSION }
+
+// IsLegacyBLS reports whether the payload's operator key uses the legacy
+// BLS scheme rather than the basic one.
+func (a *ProUpRegTx) IsLegacyBLS() bool { return a.Version == LEGACY_BLS_VERSION }
+
+// IsLegacyBLS reports whether the payload's signature uses the legacy BLS
+// scheme rather than the basic one.
+func (a *ProUpRevTx) IsLegacyBLS() bool { return a.Version == LEGACY_BLS_VERSION }
+
+func readOutPoint(r io.Reader, op *wire.OutPoint) error {
+	if _, err := io.ReadFull(r, op.Hash[:]); err != nil {
+		return err
+	}
+	var err error
+	op.Index, err = binarySerializer.Uint32(r, littleEndian)
+	return err
+}
+
+func writeOutPoint(w io.Writer, op *wire.OutPoint) error {
+	if _, err := w.Write(op.Hash[:]); err != nil {
+		return err
+	}
+	return binarySerializer.PutUint32(w, littleEndian, op.Index)
+}
+
+func (a *ProRegTx) Deserialize(r io.Reader) error {
+	var err error
+	if a.Version, err = readProviderVersion(r, "ProRegTx.Deserialize"); err != nil {
+		return err
+	}
+	if a.Type, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if a.Mode, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if err = readOutPoint(r, &a.CollateralOutpoint); err != nil {
+		return err
+	}
+	if err = a.Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.KeyIDOwner[:]); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.PubKeyOperator[:]); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.KeyIDVoting[:]); err != nil {
+		return err
+	}
+	if a.OperatorReward, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if a.ScriptPayout, err = readVarBytes(r, MAX_SCRIPT_SIZE, "ScriptPayout"); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.InputsHash[:]); err != nil {
+		return err
+	}
+	a.Sig, err = readVarBytes(r, MAX_PAYLOAD_SIG_SIZE, "Sig")
+	return err
+}
+
+func (a *ProRegTx) Serialize(w io.Writer) error {
+	if err := a.serializeUnsigned(w); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, a.Sig)
+}
+
+// serializeUnsigned writes every field except the signature, which is the
+// form syscoind hashes when signing the payload.
+func (a *ProRegTx) serializeUnsigned(w io.Writer) error {
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Version); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Type); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Mode); err != nil {
+		return err
+	}
+	if err := writeOutPoint(w, &a.CollateralOutpoint); err != nil {
+		return err
+	}
+	if err := a.Addr.Serialize(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.KeyIDOwner[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.PubKeyOperator[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.KeyIDVoting[:]); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint16(w, littleEndian, a.OperatorReward); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(w, 0, a.ScriptPayout); err != nil {
+		return err
+	}
+	_, err := w.Write(a.InputsHash[:])
+	return err
+}
+
+func (a *ProRegTx) SerializeSize() int {
+	return 6 + chainhash.HashSize + 4 + a.Addr.SerializeSize() + KEY_ID_SIZE +
+		BLS_PUBKEY_SIZE + KEY_ID_SIZE + 2 + varBytesSerializeSize(a.ScriptPayout) +
+		chainhash.HashSize + varBytesSerializeSize(a.Sig)
+}
+
+func (a *ProUpServTx) Deserialize(r io.Reader) error {
+	var err error
+	if a.Version, err = readProviderVersion(r, "ProUpServTx.Deserialize"); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.ProTxHash[:]); err != nil {
+		return err
+	}
+	if err = a.Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if a.ScriptOperatorPayout, err = readVarBytes(r, MAX_SCRIPT_SIZE, "ScriptOperatorPayout"); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.InputsHash[:]); err != nil {
+		return err
+	}
+	if a.NEVMAddress, err = readVarBytes(r, HASH_SIZE, "NEVMAddress"); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, a.Sig[:])
+	return err
+}
+
+func (a *ProUpServTx) Serialize(w io.Writer) error {
+	if err := a.serializeUnsigned(w); err != nil {
+		return err
+	}
+	_, err := w.Write(a.Sig[:])
+	return err
+}
+
+func (a *ProUpServTx) serializeUnsigned(w io.Writer) error {
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Version); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.ProTxHash[:]); err != nil {
+		return err
+	}
+	if err := a.Addr.Serialize(w); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(w, 0, a.ScriptOperatorPayout); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.InputsHash[:]); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, a.NEVMAddress)
+}
+
+func (a *ProUpServTx) SerializeSize() int {
+	return 2 + chainhash.HashSize + a.Addr.SerializeSize() +
+		varBytesSerializeSize(a.ScriptOperatorPayout) + chainhash.HashSize +
+		varBytesSerializeSize(a.NEVMAddress) + BLS_SIGNATURE_SIZE
+}
+
+func (a *ProUpRegTx) Deserialize(r io.Reader) error {
+	var err error
+	if a.Version, err = readProviderVersion(r, "ProUpRegTx.Deserialize"); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.ProTxHash[:]); err != nil {
+		return err
+	}
+	if a.Mode, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.PubKeyOperator[:]); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.KeyIDVoting[:]); err != nil {
+		return err
+	}
+	if a.ScriptPayout, err = readVarBytes(r, MAX_SCRIPT_SIZE, "ScriptPayout"); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.InputsHash[:]); err != nil {
+		return err
+	}
+	a.Sig, err = readVarBytes(r, MAX_PAYLOAD_SIG_SIZE, "Sig")
+	return err
+}
+
+func (a *ProUpRegTx) Serialize(w io.Writer) error {
+	if err := a.serializeUnsigned(w); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, a.Sig)
+}
+
+func (a *ProUpRegTx) serializeUnsigned(w io.Writer) error {
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Version); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.ProTxHash[:]); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Mode); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.PubKeyOperator[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.KeyIDVoting[:]); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(w, 0, a.ScriptPayout); err != nil {
+		return err
+	}
+	_, err := w.Write(a.InputsHash[:])
+	return err
+}
+
+func (a *ProUpRegTx) SerializeSize() int {
+	return 2 + chainhash.HashSize + 2 + BLS_PUBKEY_SIZE + KEY_ID_SIZE +
+		varBytesSerializeSize(a.ScriptPayout) + chainhash.HashSize +
+		varBytesSerializeSize(a.Sig)
+}
+
+func (a *ProUpRevTx) Deserialize(r io.Reader) error {
+	var err error
+	if a.Version, err = readProviderVersion(r, "ProUpRevTx.Deserialize"); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.ProTxHash[:]); err != nil {
+		return err
+	}
+	if a.Reason, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, a.InputsHash[:]); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, a.Sig[:])
+	return err
+}
+
+func (a *ProUpRevTx) Serialize(w io.Writer) error {
+	if err := a.serializeUnsigned(w); err != nil {
+		return err
+	}
+	_, err := w.Write(a.Sig[:])
+	return err
+}
+
+func (a *ProUpRevTx) serializeUnsigned(w io.Writer) error {
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Version); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.ProTxHash[:]); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Reason); err != nil {
+		return err
+	}
+	_, err := w.Write(a.InputsHash[:])
+	return err
+}
+
+func (a *ProUpRevTx) SerializeSize() int {
+	return 2 + chainhash.HashSize + 2 + chainhash.HashSize + BLS_SIGNATURE_SIZE
+}
+
+func (a *CbTx) Deserialize(r io.Reader) error {
+	var err error
+	if a.Version, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if a.Version < CBTX_VERSION_MERKLE_ROOT_MNLIST || a.Version > CBTX_VERSION_MERKLE_ROOT_QUORUMS {
+		return messageError("CbTx.Deserialize", fmt.Sprintf("unknown version %d", a.Version))
+	}
+	height, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	a.Height = int32(height)
+	if _, err = io.ReadFull(r, a.MerkleRootMNList[:]); err != nil {
+		return err
+	}
+	if a.Version >= CBTX_VERSION_MERKLE_ROOT_QUORUMS {
+		if _, err = io.ReadFull(r, a.MerkleRootQuorums[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *CbTx) Serialize(w io.Writer) error {
+	if err := binarySerializer.PutUint16(w, littleEndian, a.Version); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(a.Height)); err != nil {
+		return err
+	}
+	if _, err := w.Write(a.MerkleRootMNList[:]); err != nil {
+		return err
+	}
+	if a.Version >= CBTX_VERSION_MERKLE_ROOT_QUORUMS {
+		if _, err := w.Write(a.MerkleRootQuorums[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *CbTx) SerializeSize() int {
+	if a.Version >= CBTX_VERSION_MERKLE_ROOT_QUORUMS {
+		return 6 + 2*chainhash.HashSize
+	}
+	return 6 + chainhash.HashSize
+}
+
+func (a *ProRegTx) Kind() PayloadKind    { return KindProRegTx }
+func (a *ProUpServTx) Kind() PayloadKind { return KindProUpServTx }
+func (a *ProUpRegTx) Kind() PayloadKind  { return KindProUpRegTx }
+func (a *ProUpRevTx) Kind() PayloadKind  { return KindProUpRevTx }
+func (a *CbTx) Kind() PayloadKind        { return KindCbTx }
@@ -0,0 +1,183 @@
+package wire
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func randomHash() chainhash.Hash {
+	var h chainhash.Hash
+	copy(h[:], randomBytes(chainhash.HashSize))
+	return h
+}
+
+func TestProviderTx_SerializeDeserialize(t *testing.T) {
+	var keyIDOwner, keyIDVoting [KEY_ID_SIZE]byte
+	var pubKeyOperator [BLS_PUBKEY_SIZE]byte
+	var sig [BLS_SIGNATURE_SIZE]byte
+	copy(keyIDOwner[:], randomBytes(KEY_ID_SIZE))
+	copy(keyIDVoting[:], randomBytes(KEY_ID_SIZE))
+	copy(pubKeyOperator[:], randomBytes(BLS_PUBKEY_SIZE))
+	copy(sig[:], randomBytes(BLS_SIGNATURE_SIZE))
+	addr := NetService{IP: net.ParseIP("203.0.113.7"), Port: 8369}
+
+	payloads := []Payload{
+		&ProRegTx{
+			Version:            BASIC_BLS_VERSION,
+			CollateralOutpoint: wire.OutPoint{Hash: randomHash(), Index: 1},
+			Addr:               addr,
+			KeyIDOwner:         keyIDOwner,
+			PubKeyOperator:     pubKeyOperator,
+			KeyIDVoting:        keyIDVoting,
+			OperatorReward:     500,
+			ScriptPayout:       randomBytes(22),
+			InputsHash:         randomHash(),
+			Sig:                randomBytes(65),
+		},
+		&ProUpServTx{
+			Version:              LEGACY_BLS_VERSION,
+			ProTxHash:            randomHash(),
+			Addr:                 addr,
+			ScriptOperatorPayout: []byte{},
+			InputsHash:           randomHash(),
+			NEVMAddress:          randomBytes(20),
+			Sig:                  sig,
+		},
+		&ProUpRegTx{
+			Version:        BASIC_BLS_VERSION,
+			ProTxHash:      randomHash(),
+			PubKeyOperator: pubKeyOperator,
+			KeyIDVoting:    keyIDVoting,
+			ScriptPayout:   randomBytes(25),
+			InputsHash:     randomHash(),
+			Sig:            randomBytes(65),
+		},
+		&ProUpRevTx{
+			Version:    LEGACY_BLS_VERSION,
+			ProTxHash:  randomHash(),
+			Reason:     1,
+			InputsHash: randomHash(),
+			Sig:        sig,
+		},
+		&CbTx{Version: 1, Height: 1500000, MerkleRootMNList: randomHash()},
+		&CbTx{Version: 2, Height: 1500001, MerkleRootMNList: randomHash(), MerkleRootQuorums: randomHash()},
+		&FinalCommitmentTxPayload{
+			Version: 1,
+			Height:  1500002,
+			Commitment: FinalCommitment{
+				Version:        1,
+				LLMQType:       1,
+				QuorumHash:     randomHash(),
+				Signers:        []bool{true, true, false, true, true},
+				ValidMembers:   []bool{true, true, true, true, true},
+				QuorumVvecHash: randomHash(),
+				QuorumSig:      sig,
+				MembersSig:     sig,
+			},
+		},
+	}
+	for _, original := range payloads {
+		var buf bytes.Buffer
+		if err := original.Serialize(&buf); err != nil {
+			t.Fatalf("%s: Serialize failed: %v", original.Kind(), err)
+		}
+		if got := original.SerializeSize(); got != buf.Len() {
+			t.Errorf("%s: SerializeSize() = %d, want %d", original.Kind(), got, buf.Len())
+		}
+		deserialized, err := NewPayload(original.Kind())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := deserialized.Deserialize(&buf); err != nil {
+			t.Fatalf("%s: Deserialize failed: %v", original.Kind(), err)
+		}
+		if !reflect.DeepEqual(original, deserialized) {
+			t.Errorf("%s: mismatch after deserialize. Got %+v, want %+v",
+				original.Kind(), deserialized, original)
+		}
+	}
+}
+
+func TestDecodeTxPayload(t *testing.T) {
+	cbTx := CbTx{Version: 2, Height: 42, MerkleRootMNList: randomHash(), MerkleRootQuorums: randomHash()}
+	var buf bytes.Buffer
+	if err := cbTx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.NullDataScript(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := wire.NewMsgTx(SYSCOIN_TX_VERSION_MN_COINBASE)
+	tx.AddTxOut(wire.NewTxOut(5000, []byte{txscript.OP_TRUE}))
+	tx.AddTxOut(wire.NewTxOut(0, script))
+
+	data, nOut, ok := GetSyscoinData(tx)
+	if !ok || nOut != 1 || !bytes.Equal(data, buf.Bytes()) {
+		t.Fatalf("GetSyscoinData = %x, %d, %v", data, nOut, ok)
+	}
+	// Only the first unspendable output is parsed, even when it carries
+	// no data push and a later one does.
+	bare := tx.Copy()
+	bare.TxOut = append([]*wire.TxOut{wire.NewTxOut(0, []byte{txscript.OP_RETURN})}, bare.TxOut...)
+	if _, nOut, ok := GetSyscoinData(bare); ok || nOut != 0 {
+		t.Errorf("GetSyscoinData of a bare OP_RETURN first = %d, %v", nOut, ok)
+	}
+	large := tx.Copy()
+	large.TxOut[0].PkScript = make([]byte, MAX_SCRIPT_SIZE+1)
+	if _, nOut, ok := GetSyscoinData(large); ok || nOut != 0 {
+		t.Errorf("GetSyscoinData after an oversized script = %d, %v", nOut, ok)
+	}
+	p, err := DecodeTxPayload(tx)
+	if err != nil {
+		t.Fatalf("DecodeTxPayload failed: %v", err)
+	}
+	if !reflect.DeepEqual(p, &cbTx) {
+		t.Errorf("got %+v, want %+v", p, cbTx)
+	}
+
+	tx.Version = 2
+	if _, err := DecodeTxPayload(tx); err == nil {
+		t.Error("expected an error for a non-Syscoin transaction version")
+	}
+}
+
+func TestProviderTx_UnknownVersion(t *testing.T) {
+	payloads := []Payload{
+		&ProRegTx{ScriptPayout: []byte{}, Sig: []byte{}},
+		&ProUpServTx{ScriptOperatorPayout: []byte{}, NEVMAddress: []byte{}},
+		&ProUpRegTx{ScriptPayout: []byte{}, Sig: []byte{}},
+		&ProUpRevTx{},
+		&CbTx{},
+	}
+	for _, p := range payloads {
+		for _, version := range []uint16{0, 3} {
+			var buf bytes.Buffer
+			if err := p.Serialize(&buf); err != nil {
+				t.Fatal(err)
+			}
+			b := buf.Bytes()
+			b[0], b[1] = byte(version), byte(version>>8)
+			decoded, err := NewPayload(p.Kind())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := decoded.Deserialize(bytes.NewReader(b)); err == nil {
+				t.Errorf("%s: version %d was accepted", p.Kind(), version)
+			}
+		}
+	}
+
+	legacy := &ProUpServTx{Version: LEGACY_BLS_VERSION}
+	basic := &ProUpServTx{Version: BASIC_BLS_VERSION}
+	if !legacy.IsLegacyBLS() || basic.IsLegacyBLS() {
+		t.Error("IsLegacyBLS does not follow the payload version")
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// KindQuorumCommitment is the kind of the final commitment special
+// transaction payload.
+const KindQuorumCommitment PayloadKind = "qfcommit"
+
+func init() {
+	mustRegister(RegisterTxVersion(SYSCOIN_TX_VERSION_MN_QUORUM_COMMITMENT,
+		func() Payload { return &FinalCommitmentTxPayload{} }))
+}
+
+// FinalCommitment is the result of an LLMQ's distributed key generation,
+// syscoind's llmq::CFinalCommitment.  Signers and ValidMembers hold one
+// entry per quorum member.
+type FinalCommitment struct {
+	Version         uint16
+	LLMQType        uint8
+	QuorumHash      chainhash.Hash
+	Signers         []bool
+	ValidMembers    []bool
+	QuorumPublicKey [BLS_PUBKEY_SIZE]byte
+	QuorumVvecHash  chainhash.Hash
+	QuorumSig       [BLS_SIGNATURE_SIZE]byte
+	MembersSig      [BLS_SIGNATURE_SIZE]byte
+}
+
+// IsNull reports whether the commitment carries no signers or valid members,
+// which syscoind mines when a DKG session failed.
+func (c *FinalCommitment) IsNull() bool {
+	return countBits(c.Signers) == 0 && countBits(c.ValidMembers) == 0
+}
+
+// CountSigners returns the number of members that signed the commitment.
+func (c *FinalCommitment) CountSigners() int {
+	return countBits(c.Signers)
+}
+
+// CountValidMembers returns the number of members marked valid.
+func (c *FinalCommitment) CountValidMembers() int {
+	return countBits(c.ValidMembers)
+}
+
+// CommitmentHash returns the hash the quorum signs in QuorumSig, syscoind's
+// llmq::BuildCommitmentHash.
+func (c *FinalCommitment) CommitmentHash() chainhash.Hash {
+	var buf bytes.Buffer
+	_ = binarySerializer.PutUint8(&buf, c.LLMQType)
+	buf.Write(c.QuorumHash[:])
+	_ = writeDynBitSet(&buf, c.ValidMembers)
+	buf.Write(c.QuorumPublicKey[:])
+	buf.Write(c.QuorumVvecHash[:])
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+func countBits(bits []bool) int {
+	n := 0
+	for _, b := range bits {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *FinalCommitment) Deserialize(r io.Reader) error {
+	var err error
+	if c.Version, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if c.LLMQType, err = binarySerializer.Uint8(r); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, c.QuorumHash[:]); err != nil {
+		return err
+	}
+	if c.Signers, err = readDynBitSet(r, "FinalCommitment.Signers"); err != nil {
+		return err
+	}
+	if c.ValidMembers, err = readDynBitSet(r, "FinalCommitment.ValidMembers"); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, c.QuorumPublicKey[:]); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, c.QuorumVvecHash[:]); err != nil {
+		return err
+	}
+	if _, err = io.ReadFull(r, c.QuorumSig[:]); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, c.MembersSig[:])
+	return err
+}
+
+func (c *FinalCommitment) Serialize(w io.Writer) error {
+	if err := binarySerializer.PutUint16(w, littleEndian, c.Version); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint8(w, c.LLMQType); err != nil {
+		return err
+	}
+	if _, err := w.Write(c.QuorumHash[:]); err != nil {
+		return err
+	}
+	if err := writeDynBitSet(w, c.Signers); err != nil {
+		return err
+	}
+	if err := writeDynBitSet(w, c.ValidMembers); err != nil {
+		return err
+	}
+	for _, b := range [][]byte{c.QuorumPublicKey[:], c.QuorumVvecHash[:], c.QuorumSig[:], c.MembersSig[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *FinalCommitment) SerializeSize() int {
+	return 2 + 1 + chainhash.HashSize + dynBitSetSerializeSize(c.Signers) +
+		dynBitSetSerializeSize(c.ValidMembers) + BLS_PUBKEY_SIZE +
+		chainhash.HashSize + 2*BLS_SIGNATURE_SIZE
+}
+
+// readDynBitSet reads a bit vector serialized like syscoind's DYNBITSET: a
+// compact size bit count followed by the bits packed least significant bit
+// first.  Padding bits past count must be zero so that every bit vector has
+// a single encoding.
+func readDynBitSet(r io.Reader, fieldName string) ([]bool, error) {
+	count, err := readElementCount(r, fieldName)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := readBytes(r, int((count+7)/8))
+	if err != nil {
+		return nil, err
+	}
+	if count%8 != 0 && packed[len(packed)-1]>>(count%8) != 0 {
+		str := fmt.Sprintf("%s has non-zero padding bits", fieldName)
+		return nil, messageError("readDynBitSet", str)
+	}
+	bits := make([]bool, count)
+	for i := range bits {
+		bits[i] = packed[i/8]&(1<<(i%8)) != 0
+	}
+	return bits, nil
+}
+
+func writeDynBitSet(w io.Writer, bits []bool) error {
+	if err := wire.WriteVarInt(w, 0, uint64(len(bits))); err != nil {
+		return err
+	}
+	packed := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	_, err := w.Write(packed)
+	return err
+}
+
+func dynBitSetSerializeSize(bits []bool) int {
+	return wire.VarIntSerializeSize(uint64(len(bits))) + (len(bits)+7)/8
+}
+
+// FinalCommitmentTxPayload is the payload of the special transaction miners
+// use to put a FinalCommitment on chain, syscoind's
+// llmq::CFinalCommitmentTxPayload.  Height is the height of the block that
+// mines it.
+type FinalCommitmentTxPayload struct {
+	Version    uint16
+	Height     uint32
+	Commitment FinalCommitment
+}
+
+func (p *FinalCommitmentTxPayload) Deserialize(r io.Reader) error {
+	var err error
+	if p.Version, err = binarySerializer.Uint16(r, littleEndian); err != nil {
+		return err
+	}
+	if p.Height, err = binarySerializer.Uint32(r, littleEndian); err != nil {
+		return err
+	}
+	return p.Commitment.Deserialize(r)
+}
+
+func (p *FinalCommitmentTxPayload) Serialize(w io.Writer) error {
+	if err := binarySerializer.PutUint16(w, littleEndian, p.Version); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, p.Height); err != nil {
+		return err
+	}
+	return p.Commitment.Serialize(w)
+}
+
+func (p *FinalCommitmentTxPayload) SerializeSize() int {
+	return 2 + 4 + p.Commitment.SerializeSize()
+}
+
+func (p *FinalCommitmentTxPayload) Kind() PayloadKind { return KindQuorumCommitment }
@@ -0,0 +1,108 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errRLPTruncated is returned when an RLP item runs past its input.
+var errRLPTruncated = errors.New("rlp: item runs past the end of its input")
+
+// rlpItem is one RLP item: a byte string or the concatenated encoding of a
+// list's elements.
+type rlpItem struct {
+	list    bool
+	content []byte
+}
+
+// rlpSplit splits the first RLP item off b and returns it with the bytes
+// that follow it.  Non-canonical sizes are rejected, as go-ethereum's
+// decoder rejects them.
+func rlpSplit(b []byte) (rlpItem, []byte, error) {
+	if len(b) == 0 {
+		return rlpItem{}, nil, errRLPTruncated
+	}
+	prefix := b[0]
+	var list bool
+	var offset, size uint64
+	switch {
+	case prefix < 0x80:
+		return rlpItem{content: b[:1]}, b[1:], nil
+	case prefix < 0xb8:
+		offset, size = 1, uint64(prefix-0x80)
+		if size == 1 && len(b) > 1 && b[1] < 0x80 {
+			return rlpItem{}, nil, errors.New("rlp: single byte below 0x80 encoded as a string")
+		}
+	case prefix < 0xc0:
+		n, err := rlpSize(b[1:], int(prefix-0xb7))
+		if err != nil {
+			return rlpItem{}, nil, err
+		}
+		offset, size = 1+uint64(prefix-0xb7), n
+	case prefix < 0xf8:
+		list, offset, size = true, 1, uint64(prefix-0xc0)
+	default:
+		n, err := rlpSize(b[1:], int(prefix-0xf7))
+		if err != nil {
+			return rlpItem{}, nil, err
+		}
+		list, offset, size = true, 1+uint64(prefix-0xf7), n
+	}
+	if size > uint64(len(b))-offset {
+		return rlpItem{}, nil, errRLPTruncated
+	}
+	end := offset + size
+	return rlpItem{list: list, content: b[offset:end]}, b[end:], nil
+}
+
+// rlpSize decodes the big-endian size of a long string or list.
+func rlpSize(b []byte, n int) (uint64, error) {
+	if n > len(b) {
+		return 0, errRLPTruncated
+	}
+	if b[0] == 0 {
+		return 0, errors.New("rlp: size has leading zero bytes")
+	}
+	var size uint64
+	for _, c := range b[:n] {
+		size = size<<8 | uint64(c)
+	}
+	if size < 56 {
+		return 0, errors.New("rlp: long form used for a size below 56")
+	}
+	return size, nil
+}
+
+// rlpList returns the elements of the list item.
+func rlpList(item rlpItem) ([]rlpItem, error) {
+	if !item.list {
+		return nil, errors.New("rlp: expected a list, got a string")
+	}
+	var elems []rlpItem
+	for rest := item.content; len(rest) > 0; {
+		var elem rlpItem
+		var err error
+		elem, rest, err = rlpSplit(rest)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// rlpDecodeList decodes b as a single RLP list with nothing after it.
+func rlpDecodeList(b []byte) ([]rlpItem, error) {
+	item, rest, err := rlpSplit(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("rlp: %d bytes after the value", len(rest))
+	}
+	return rlpList(item)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// MAX_ASSET_DATA_SIZE is the largest payload an asset data-carrier output
+// can hold: the script, an OP_RETURN and a PUSHDATA2 push, must stay within
+// MAX_SCRIPT_SIZE, beyond which syscoind treats scripts as invalid.
+const MAX_ASSET_DATA_SIZE = txscript.MaxScriptSize - 1 - 3
+
+// appendPush appends a push of data using the opcode syscoind's CScript
+// chooses for a byte vector: a direct length opcode below OP_PUSHDATA1,
+// then OP_PUSHDATA1, OP_PUSHDATA2 and OP_PUSHDATA4.  Unlike btcd's
+// ScriptBuilder it never turns one-byte or empty data into OP_1 to OP_16,
+// OP_1NEGATE or OP_0.
+func appendPush(script, data []byte) []byte {
+	switch n := len(data); {
+	case n < txscript.OP_PUSHDATA1:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, txscript.OP_PUSHDATA1, byte(n))
+	case n <= 0xffff:
+		script = append(script, txscript.OP_PUSHDATA2)
+		script = binary.LittleEndian.AppendUint16(script, uint16(n))
+	default:
+		script = append(script, txscript.OP_PUSHDATA4)
+		script = binary.LittleEndian.AppendUint32(script, uint32(n))
+	}
+	return append(script, data...)
+}
+
+// BuildDataScript returns the data-carrier script syscoind builds for data
+// with CScript() << OP_RETURN << data.
+func BuildDataScript(data []byte) ([]byte, error) {
+	if len(data) > MAX_ASSET_DATA_SIZE {
+		return nil, fmt.Errorf("data of %d bytes exceeds the %d-byte data-carrier limit",
+			len(data), MAX_ASSET_DATA_SIZE)
+	}
+	script := make([]byte, 0, 1+5+len(data))
+	return appendPush(append(script, txscript.OP_RETURN), data), nil
+}
+
+// BuildAssetDataScript serializes payload and returns the data-carrier
+// script carrying it, byte-identical to syscoind's.
+func BuildAssetDataScript(payload Payload) ([]byte, error) {
+	data, err := AppendPayload(make([]byte, 0, payload.SerializeSize()), payload)
+	if err != nil {
+		return nil, err
+	}
+	return BuildDataScript(data)
+}
+
+// ParseAssetDataScript returns the data carried by a data-carrier script in
+// the exact form BuildDataScript produces: OP_RETURN followed by a single
+// push using the opcode CScript chooses for its length, and nothing else.
+// GetSyscoinData is more lenient, as syscoind's consensus code is.
+func ParseAssetDataScript(script []byte) ([]byte, error) {
+	data, ok := syscoinDataFromScript(script)
+	if !ok {
+		return nil, fmt.Errorf("script is not OP_RETURN followed by a data push")
+	}
+	if len(data) > MAX_ASSET_DATA_SIZE {
+		return nil, fmt.Errorf("data of %d bytes exceeds the %d-byte data-carrier limit",
+			len(data), MAX_ASSET_DATA_SIZE)
+	}
+	if !bytes.Equal(appendPush([]byte{txscript.OP_RETURN}, data), script) {
+		return nil, fmt.Errorf("data-carrier script is not in canonical form")
+	}
+	return data, nil
+}
+
+// IsAssetDataScript reports whether script is a data-carrier script in the
+// form ParseAssetDataScript accepts.
+func IsAssetDataScript(script []byte) bool {
+	_, err := ParseAssetDataScript(script)
+	return err == nil
+}
+
+// syscoinDataFromScript returns the first push following a leading
+// OP_RETURN in script, as syscoind's GetSyscoinData does.
+func syscoinDataFromScript(script []byte) ([]byte, bool) {
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	if !tokenizer.Next() || tokenizer.Opcode() != txscript.OP_RETURN {
+		return nil, false
+	}
+	if !tokenizer.Next() || tokenizer.Opcode() > txscript.OP_PUSHDATA4 {
+		return nil, false
+	}
+	return tokenizer.Data(), true
+}
@@ -0,0 +1,112 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+func TestBuildDataScriptPushOpcodes(t *testing.T) {
+	tests := []struct {
+		size   int
+		prefix []byte
+	}{
+		{0, []byte{txscript.OP_RETURN, 0x00}},
+		{1, []byte{txscript.OP_RETURN, 0x01}},
+		{75, []byte{txscript.OP_RETURN, 0x4b}},
+		{76, []byte{txscript.OP_RETURN, txscript.OP_PUSHDATA1, 0x4c}},
+		{255, []byte{txscript.OP_RETURN, txscript.OP_PUSHDATA1, 0xff}},
+		{256, []byte{txscript.OP_RETURN, txscript.OP_PUSHDATA2, 0x00, 0x01}},
+		{MAX_ASSET_DATA_SIZE, []byte{txscript.OP_RETURN, txscript.OP_PUSHDATA2, 0x0c, 0x27}},
+	}
+	for _, test := range tests {
+		// A single byte of 1 must not become OP_1, as btcd's
+		// ScriptBuilder would make it.
+		data := bytes.Repeat([]byte{0x01}, test.size)
+		script, err := BuildDataScript(data)
+		if err != nil {
+			t.Fatalf("BuildDataScript(%d bytes): %v", test.size, err)
+		}
+		if !bytes.HasPrefix(script, test.prefix) || len(script) != len(test.prefix)+test.size {
+			t.Errorf("BuildDataScript(%d bytes) = %x..., want prefix %x", test.size,
+				script[:len(test.prefix)], test.prefix)
+		}
+		got, err := ParseAssetDataScript(script)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("ParseAssetDataScript(%d bytes) = %d bytes, %v", test.size, len(got), err)
+		}
+	}
+
+	if _, err := BuildDataScript(make([]byte, MAX_ASSET_DATA_SIZE+1)); err == nil {
+		t.Error("BuildDataScript accepted data over the size limit")
+	}
+}
+
+func TestAssetDataScriptMint(t *testing.T) {
+	mint := &MintSyscoinType{
+		Allocation: AssetAllocationType{
+			VoutAssets: []AssetOutType{{
+				AssetGuid: 999,
+				Values:    []AssetOutValueType{{N: 1, ValueSat: 123456}},
+			}},
+		},
+		TxHash:             randomBytes(HASH_SIZE),
+		BlockHash:          randomBytes(HASH_SIZE),
+		TxParentNodes:      randomBytes(600),
+		TxPath:             randomBytes(3),
+		TxRoot:             randomBytes(HASH_SIZE),
+		ReceiptRoot:        randomBytes(HASH_SIZE),
+		ReceiptParentNodes: randomBytes(700),
+	}
+	script, err := BuildAssetDataScript(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script[1] != txscript.OP_PUSHDATA2 {
+		t.Fatalf("mint payload pushed with opcode %#x, want OP_PUSHDATA2", script[1])
+	}
+	if !IsAssetDataScript(script) {
+		t.Fatal("IsAssetDataScript rejected a built script")
+	}
+	data, err := ParseAssetDataScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got MintSyscoinType
+	if err := got.Deserialize(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.ReceiptParentNodes, mint.ReceiptParentNodes) {
+		t.Error("mint payload did not round trip through its data script")
+	}
+}
+
+func TestParseAssetDataScriptRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		script []byte
+	}{
+		{"empty", nil},
+		{"bare OP_RETURN", []byte{txscript.OP_RETURN}},
+		{"no OP_RETURN", []byte{0x01, 0xaa}},
+		{"opcode after OP_RETURN", []byte{txscript.OP_RETURN, txscript.OP_TRUE}},
+		{"truncated push", []byte{txscript.OP_RETURN, 0x02, 0xaa}},
+		{"trailing data", []byte{txscript.OP_RETURN, 0x01, 0xaa, 0x01, 0xbb}},
+		{"non-minimal PUSHDATA1", []byte{txscript.OP_RETURN, txscript.OP_PUSHDATA1, 0x01, 0xaa}},
+		{"non-minimal PUSHDATA2", []byte{txscript.OP_RETURN, txscript.OP_PUSHDATA2, 0x01, 0x00, 0xaa}},
+	}
+	for _, test := range tests {
+		if _, err := ParseAssetDataScript(test.script); err == nil {
+			t.Errorf("%s: ParseAssetDataScript accepted %x", test.name, test.script)
+		}
+		if IsAssetDataScript(test.script) {
+			t.Errorf("%s: IsAssetDataScript accepted %x", test.name, test.script)
+		}
+	}
+
+	// GetSyscoinData keeps syscoind's leniency towards non-canonical pushes.
+	if data, ok := syscoinDataFromScript([]byte{txscript.OP_RETURN, txscript.OP_PUSHDATA1, 0x01, 0xaa}); !ok || !bytes.Equal(data, []byte{0xaa}) {
+		t.Errorf("syscoinDataFromScript = %x, %v", data, ok)
+	}
+}
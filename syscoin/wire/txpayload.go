@@ -0,0 +1,60 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// GetSyscoinData returns the data pushed by the payload output of tx
+// together with that output's index, mirroring syscoind's GetSyscoinData.
+// The payload output is the first unspendable output, as syscoind's
+// GetSyscoinDataOutput picks it; later outputs are not considered.  ok is
+// false when tx has no unspendable output or that output is not OP_RETURN
+// followed by a data push.
+func GetSyscoinData(tx *wire.MsgTx) (data []byte, nOut int, ok bool) {
+	for i, out := range tx.TxOut {
+		if isUnspendable(out.PkScript) {
+			data, ok := syscoinDataFromScript(out.PkScript)
+			return data, i, ok
+		}
+	}
+	return nil, -1, false
+}
+
+// isUnspendable reports whether script is unspendable by syscoind's
+// CScript::IsUnspendable: it starts with OP_RETURN or exceeds
+// MAX_SCRIPT_SIZE.  Unlike btcd's txscript.IsUnspendable it does not treat
+// scripts that fail to parse as unspendable.
+func isUnspendable(script []byte) bool {
+	return (len(script) > 0 && script[0] == txscript.OP_RETURN) || len(script) > MAX_SCRIPT_SIZE
+}
+
+// DecodeTxPayload decodes the payload carried by a Syscoin transaction,
+// choosing the payload type from the registered transaction versions.
+func DecodeTxPayload(tx *wire.MsgTx) (Payload, error) {
+	p, err := NewPayloadForTxVersion(tx.Version)
+	if err != nil {
+		return nil, err
+	}
+	data, _, ok := GetSyscoinData(tx)
+	if !ok {
+		return nil, fmt.Errorf("transaction %v of version %d has no payload output",
+			tx.TxHash(), tx.Version)
+	}
+	r := bytes.NewReader(data)
+	if err := p.Deserialize(r); err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("transaction %v payload has %d trailing bytes",
+			tx.TxHash(), r.Len())
+	}
+	return p, nil
+}
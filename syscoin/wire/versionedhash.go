@@ -0,0 +1,166 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// VERSIONED_HASH_VERSION_KZG is the version byte of a versioned hash of
+	// a KZG commitment (EIP-4844).
+	VERSIONED_HASH_VERSION_KZG = 0x01
+
+	// KZG_COMMITMENT_SIZE is the length of a compressed KZG commitment.
+	KZG_COMMITMENT_SIZE = 48
+
+	// blobTxType is the EIP-2718 type of an EIP-4844 blob transaction.
+	blobTxType = 0x03
+
+	// blobTxHashesField is the index of blob_versioned_hashes in the RLP
+	// payload of a blob transaction.
+	blobTxHashesField = 10
+)
+
+// VersionedHash identifies a PoDA blob: a version byte followed by the last
+// 31 bytes of the SHA-256 hash of the blob's commitment.
+type VersionedHash [HASH_SIZE]byte
+
+// FromCommitment returns the KZG versioned hash of a 48-byte commitment.
+func FromCommitment(commitment []byte) (VersionedHash, error) {
+	var h VersionedHash
+	if len(commitment) != KZG_COMMITMENT_SIZE {
+		return h, fmt.Errorf("KZG commitment has %d bytes, want %d", len(commitment), KZG_COMMITMENT_SIZE)
+	}
+	h = sha256.Sum256(commitment)
+	h[0] = VERSIONED_HASH_VERSION_KZG
+	return h, nil
+}
+
+// ParseVersionedHash returns b as a VersionedHash.  b must be exactly 32
+// bytes with a known version.
+func ParseVersionedHash(b []byte) (VersionedHash, error) {
+	var h VersionedHash
+	if len(b) != HASH_SIZE {
+		return h, fmt.Errorf("versioned hash has %d bytes, want %d", len(b), HASH_SIZE)
+	}
+	copy(h[:], b)
+	if !h.IsKnownVersion() {
+		return h, fmt.Errorf("versioned hash %v has unknown version %#02x", h, h.Version())
+	}
+	return h, nil
+}
+
+// Version returns the version byte of the hash.
+func (h VersionedHash) Version() byte {
+	return h[0]
+}
+
+// IsKnownVersion reports whether the hash has a version this package
+// understands, which is currently only VERSIONED_HASH_VERSION_KZG.
+func (h VersionedHash) IsKnownVersion() bool {
+	return h[0] == VERSIONED_HASH_VERSION_KZG
+}
+
+// Matches reports whether h is the versioned hash of commitment.
+func (h VersionedHash) Matches(commitment []byte) bool {
+	want, err := FromCommitment(commitment)
+	return err == nil && h.Version() == VERSIONED_HASH_VERSION_KZG && h == want
+}
+
+// String returns the hash as 0x-prefixed hex, as Ethereum tooling shows it.
+func (h VersionedHash) String() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// ParsedVersionHashes returns the block's VersionHashes as VersionedHashes,
+// failing if any is not exactly 32 bytes with a known version.
+func (a *NEVMBlockWire) ParsedVersionHashes() ([]VersionedHash, error) {
+	hashes := make([]VersionedHash, len(a.VersionHashes))
+	for i, b := range a.VersionHashes {
+		h, err := ParseVersionedHash(b)
+		if err != nil {
+			return nil, fmt.Errorf("VersionHashes[%d]: %w", i, err)
+		}
+		hashes[i] = h
+	}
+	return hashes, nil
+}
+
+// BlobVersionedHashes returns the blob_versioned_hashes of every EIP-4844
+// transaction in the RLP-encoded NEVM block, in block order.
+func BlobVersionedHashes(blockData []byte) ([]VersionedHash, error) {
+	block, err := rlpDecodeList(blockData)
+	if err != nil {
+		return nil, fmt.Errorf("NEVM block: %w", err)
+	}
+	if len(block) < 2 {
+		return nil, fmt.Errorf("NEVM block has %d fields, want a header and transactions", len(block))
+	}
+	txs, err := rlpList(block[1])
+	if err != nil {
+		return nil, fmt.Errorf("NEVM block transactions: %w", err)
+	}
+
+	var hashes []VersionedHash
+	for i, tx := range txs {
+		// Legacy transactions are lists; typed ones are strings
+		// holding the type byte and the RLP payload.
+		if tx.list || len(tx.content) == 0 || tx.content[0] != blobTxType {
+			continue
+		}
+		fields, err := rlpDecodeList(tx.content[1:])
+		if err != nil {
+			return nil, fmt.Errorf("NEVM transaction %d: %w", i, err)
+		}
+		if len(fields) <= blobTxHashesField {
+			return nil, fmt.Errorf("NEVM blob transaction %d has %d fields", i, len(fields))
+		}
+		elems, err := rlpList(fields[blobTxHashesField])
+		if err != nil {
+			return nil, fmt.Errorf("NEVM blob transaction %d hashes: %w", i, err)
+		}
+		if len(elems) == 0 {
+			return nil, fmt.Errorf("NEVM blob transaction %d carries no blobs", i)
+		}
+		for j, elem := range elems {
+			if elem.list {
+				return nil, fmt.Errorf("NEVM blob transaction %d hash %d is a list", i, j)
+			}
+			h, err := ParseVersionedHash(elem.content)
+			if err != nil {
+				return nil, fmt.Errorf("NEVM blob transaction %d hash %d: %w", i, j, err)
+			}
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes, nil
+}
+
+// CheckVersionHashes validates the block's VersionHashes and checks that
+// they are exactly the blob versioned hashes of the blob transactions in
+// NEVMBlockData, in block order.
+func (a *NEVMBlockWire) CheckVersionHashes() error {
+	got, err := a.ParsedVersionHashes()
+	if err != nil {
+		return err
+	}
+	want, err := BlobVersionedHashes(a.NEVMBlockData)
+	if err != nil {
+		return err
+	}
+	if len(got) != len(want) {
+		return fmt.Errorf("block lists %d version hashes, its blob transactions carry %d",
+			len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return fmt.Errorf("VersionHashes[%d] is %v, blob transactions carry %v", i, got[i], want[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package wire
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// rlpString and rlpListOf are a minimal RLP encoder for building NEVM
+// blocks in tests.
+func rlpString(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+func rlpListOf(elems ...[]byte) []byte {
+	content := bytes.Join(elems, nil)
+	return append(rlpHeader(0xc0, len(content)), content...)
+}
+
+func rlpHeader(base byte, n int) []byte {
+	if n < 56 {
+		return []byte{base + byte(n)}
+	}
+	var size []byte
+	for ; n > 0; n >>= 8 {
+		size = append([]byte{byte(n)}, size...)
+	}
+	return append([]byte{base + 55 + byte(len(size))}, size...)
+}
+
+// blobTx returns a block-encoded EIP-4844 transaction carrying hashes.
+func blobTx(hashes ...VersionedHash) []byte {
+	var hashList [][]byte
+	for _, h := range hashes {
+		hashList = append(hashList, rlpString(h[:]))
+	}
+	payload := rlpListOf(
+		rlpString([]byte{0x39}), rlpString(nil), rlpString([]byte{1}), rlpString([]byte{2}),
+		rlpString([]byte{0x52, 0x08}), rlpString(bytes.Repeat([]byte{0x11}, 20)), rlpString(nil),
+		rlpString(nil), rlpListOf(), rlpString([]byte{3}), rlpListOf(hashList...),
+		rlpString(nil), rlpString(bytes.Repeat([]byte{0x22}, 32)), rlpString(bytes.Repeat([]byte{0x33}, 32)),
+	)
+	return rlpString(append([]byte{blobTxType}, payload...))
+}
+
+func nevmBlockData(txs ...[]byte) []byte {
+	header := rlpListOf(rlpString(bytes.Repeat([]byte{0x44}, 32)), rlpString(bytes.Repeat([]byte{0x55}, 300)))
+	return rlpListOf(header, rlpListOf(txs...), rlpListOf())
+}
+
+func commitment(b byte) []byte {
+	c := make([]byte, KZG_COMMITMENT_SIZE)
+	c[0], c[47] = 0xa0, b
+	return c
+}
+
+func mustFromCommitment(t *testing.T, c []byte) VersionedHash {
+	t.Helper()
+	h, err := FromCommitment(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestFromCommitment(t *testing.T) {
+	// The commitment of the all-zero blob is the point at infinity.
+	infinity := append([]byte{0xc0}, make([]byte, KZG_COMMITMENT_SIZE-1)...)
+	h := mustFromCommitment(t, infinity)
+	const want = "0x010657f37554c781402a22917dee2f75def7ab966d7b770905398eba3c444014"
+	if h.String() != want {
+		t.Errorf("FromCommitment(infinity) = %v, want %s", h, want)
+	}
+	if !h.IsKnownVersion() || !h.Matches(infinity) || h.Matches(commitment(1)) {
+		t.Error("version or commitment match wrong")
+	}
+	if _, err := FromCommitment(infinity[:47]); err == nil {
+		t.Error("FromCommitment accepted a 47-byte commitment")
+	}
+
+	parsed, err := ParseVersionedHash(h[:])
+	if err != nil || parsed != h {
+		t.Errorf("ParseVersionedHash = %v, %v", parsed, err)
+	}
+	bad := h
+	bad[0] = 0x02
+	if _, err := ParseVersionedHash(bad[:]); err == nil {
+		t.Error("ParseVersionedHash accepted version 2")
+	}
+	if _, err := ParseVersionedHash(h[:31]); err == nil {
+		t.Error("ParseVersionedHash accepted 31 bytes")
+	}
+}
+
+func TestCheckVersionHashes(t *testing.T) {
+	h1 := mustFromCommitment(t, commitment(1))
+	h2 := mustFromCommitment(t, commitment(2))
+	h3 := mustFromCommitment(t, commitment(3))
+	legacyTx := rlpListOf(rlpString(nil), rlpString([]byte{1}), rlpString([]byte{0x52, 0x08}))
+	dynamicFeeTx := rlpString(append([]byte{0x02}, rlpListOf(rlpString([]byte{0x39}))...))
+	data := nevmBlockData(legacyTx, blobTx(h1, h2), dynamicFeeTx, blobTx(h3))
+
+	hashes, err := BlobVersionedHashes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 3 || hashes[0] != h1 || hashes[1] != h2 || hashes[2] != h3 {
+		t.Fatalf("BlobVersionedHashes = %v", hashes)
+	}
+
+	tests := []struct {
+		name   string
+		hashes [][]byte
+		errSub string
+	}{
+		{"match", [][]byte{h1[:], h2[:], h3[:]}, ""},
+		{"missing", [][]byte{h1[:], h2[:]}, "carry 3"},
+		{"reordered", [][]byte{h2[:], h1[:], h3[:]}, "VersionHashes[0]"},
+		{"short hash", [][]byte{h1[:], h2[:], h3[:20]}, "has 20 bytes"},
+		{"unknown version", [][]byte{h1[:], append([]byte{0x00}, h2[1:]...), h3[:]}, "unknown version"},
+	}
+	for _, test := range tests {
+		block := &NEVMBlockWire{NEVMBlockData: data, VersionHashes: test.hashes}
+		err := block.CheckVersionHashes()
+		if test.errSub == "" {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), test.errSub) {
+			t.Errorf("%s: error %v, want it to mention %q", test.name, err, test.errSub)
+		}
+	}
+
+	// A block without blob transactions has no version hashes.
+	empty := &NEVMBlockWire{NEVMBlockData: nevmBlockData(legacyTx)}
+	if err := empty.CheckVersionHashes(); err != nil {
+		t.Errorf("blobless block: %v", err)
+	}
+}
+
+func TestBlobVersionedHashesMalformed(t *testing.T) {
+	h := VersionedHash{VERSIONED_HASH_VERSION_KZG}
+	good := nevmBlockData(blobTx(h))
+	tests := map[string][]byte{
+		"empty":               nil,
+		"truncated":           good[:len(good)-1],
+		"trailing bytes":      append(append([]byte(nil), good...), 0x80),
+		"not a list":          rlpString([]byte("block")),
+		"no transactions":     rlpListOf(rlpListOf()),
+		"blob without hashes": nevmBlockData(blobTx()),
+		"non-canonical byte":  nevmBlockData(append([]byte{0x81}, 0x05)),
+	}
+	for name, data := range tests {
+		if _, err := BlobVersionedHashes(data); err == nil {
+			t.Errorf("%s: BlobVersionedHashes accepted %x", name, data)
+		}
+	}
+}
+
+func TestRLPSplitLongForms(t *testing.T) {
+	long := bytes.Repeat([]byte{0xee}, 1000)
+	item, rest, err := rlpSplit(rlpString(long))
+	if err != nil || item.list || !bytes.Equal(item.content, long) || len(rest) != 0 {
+		t.Errorf("long string: %v, %x, %v", item.list, rest, err)
+	}
+	// Long form for a 3-byte string.
+	if _, _, err := rlpSplit(append([]byte{0xb8, 0x03}, 1, 2, 3)); err == nil {
+		t.Error("rlpSplit accepted a non-canonical long string size")
+	}
+	if _, _, err := rlpSplit([]byte{0xf9, 0x00, 0x40}); err == nil {
+		t.Error("rlpSplit accepted a size with a leading zero")
+	}
+	if _, _, err := rlpSplit(mustHex("f9ffff")); err == nil {
+		t.Error("rlpSplit accepted a list running past its input")
+	}
+}